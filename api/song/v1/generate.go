@@ -0,0 +1,4 @@
+// Package songv1 сгенерированный код gRPC API песен
+package songv1
+
+//go:generate protoc -I ../.. --go_out=../.. --go_opt=paths=source_relative --go-grpc_out=../.. --go-grpc_opt=paths=source_relative song/v1/song.proto
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"RestApi_v1/internal/config/internal/lib/auth"
+	"RestApi_v1/internal/config/internal/models"
+	"RestApi_v1/internal/config/internal/storage/postgres"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+func apiKeyCmd(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("apikey: subcommand is required (create, revoke, list)")
+	}
+
+	storage, err := postgres.New(ctx)
+	if err != nil {
+		return err
+	}
+	defer storage.Close()
+
+	switch args[0] {
+	case "create":
+		return apiKeyCreate(ctx, storage, args[1:])
+	case "revoke":
+		return apiKeyRevoke(ctx, storage, args[1:])
+	case "list":
+		return apiKeyList(ctx, storage, args[1:])
+	default:
+		return fmt.Errorf("apikey: unknown subcommand %q", args[0])
+	}
+}
+
+func apiKeyCreate(ctx context.Context, storage *postgres.Storage, args []string) error {
+	fs := flag.NewFlagSet("apikey create", flag.ContinueOnError)
+	name := fs.String("name", "", "key owner, e.g. service name")
+	roleName := fs.String("role", string(auth.RoleReader), "reader, editor or admin")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *name == "" {
+		return fmt.Errorf("apikey create: -name is required")
+	}
+
+	role, err := auth.ParseRole(*roleName)
+	if err != nil {
+		return fmt.Errorf("apikey create: %w", err)
+	}
+
+	key, hash, err := auth.GenerateAPIKey()
+	if err != nil {
+		return err
+	}
+
+	id, err := storage.CreateAPIKey(ctx, *name, hash, string(role))
+	if err != nil {
+		return err
+	}
+
+	// ключ не хранится в базе, показываем его один раз
+	fmt.Printf("id:   %d\nrole: %s\nkey:  %s\n", id, role, key)
+	return nil
+}
+
+func apiKeyRevoke(ctx context.Context, storage *postgres.Storage, args []string) error {
+	fs := flag.NewFlagSet("apikey revoke", flag.ContinueOnError)
+	id := fs.Int64("id", 0, "key id")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *id <= 0 {
+		return fmt.Errorf("apikey revoke: -id is required")
+	}
+
+	if err := storage.RevokeAPIKey(ctx, *id); err != nil {
+		return fmt.Errorf("apikey revoke: %w", err)
+	}
+
+	fmt.Printf("key %d revoked\n", *id)
+	return nil
+}
+
+func apiKeyList(ctx context.Context, storage *postgres.Storage, args []string) error {
+	fs := flag.NewFlagSet("apikey list", flag.ContinueOnError)
+	output := outputFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := checkOutput(*output); err != nil {
+		return fmt.Errorf("apikey list: %w", err)
+	}
+
+	keys, err := storage.ListAPIKeys(ctx)
+	if err != nil {
+		return err
+	}
+	return printOutput(os.Stdout, *output, apiKeys(keys))
+}
+
+// apiKeys список ключей для вывода, хеши ключей в модели не выбираются
+type apiKeys []models.APIKey
+
+func (k apiKeys) MarshalCSV() ([]string, [][]string) {
+	records := make([][]string, 0, len(k))
+	for _, key := range k {
+		revoked := ""
+		if key.RevokedAt.Valid {
+			revoked = key.RevokedAt.Time.Format(time.RFC3339)
+		}
+		records = append(records, []string{strconv.FormatInt(key.ID, 10), key.Name, key.Role, key.CreatedAt.Format(time.RFC3339), revoked})
+	}
+	return []string{"id", "name", "role", "created", "revoked"}, records
+}
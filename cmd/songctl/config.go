@@ -0,0 +1,94 @@
+package main
+
+import (
+	"RestApi_v1/internal/config/internal/lib/auth"
+	"RestApi_v1/internal/config/internal/lib/enrich"
+	"RestApi_v1/internal/config/internal/lib/tlsconfig"
+	"RestApi_v1/internal/config/internal/storage/postgres"
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+)
+
+func configCmd(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("config: subcommand is required (check)")
+	}
+
+	switch args[0] {
+	case "check":
+		return configCheck(ctx, args[1:])
+	default:
+		return fmt.Errorf("config: unknown subcommand %q", args[0])
+	}
+}
+
+// configCheck загружает конфиг со всеми слоями, собирает из него то же, что сервер при старте,
+// и сообщает обо всех ошибках сразу
+func configCheck(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("config check", flag.ContinueOnError)
+	db := fs.Bool("db", false, "also connect to the database and show the schema version")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	failed := 0
+	check := func(name string, err error) {
+		if err != nil {
+			fmt.Printf("%-12s FAIL  %s\n", name, err)
+			failed++
+			return
+		}
+		fmt.Printf("%-12s ok\n", name)
+	}
+
+	// значения проверяет сам Load, дальше - то, что собирается из них при старте сервера
+	cfg, err := loadConfig()
+	check("config", err)
+	if err != nil {
+		return fmt.Errorf("config check: config is not loaded")
+	}
+
+	_, err = auth.NewJWTVerifier(cfg.Auth.JWT)
+	check("auth", err)
+
+	if tlsCfg := cfg.HTTPServer.TLS; tlsCfg.Enabled {
+		certs, err := tlsconfig.NewCertReloader(slog.Default(), tlsCfg.CertFile, tlsCfg.KeyFile)
+		if err == nil {
+			_, err = tlsconfig.New(tlsCfg, certs)
+		}
+		check("tls", err)
+	}
+
+	if cfg.Enrichment.Enabled {
+		_, err := enrich.NewProvider(cfg.Enrichment.Provider)
+		check("enrichment", err)
+	}
+
+	if *db {
+		check("database", checkDatabase(ctx))
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("config check: %d of the checks failed", failed)
+	}
+	return nil
+}
+
+func checkDatabase(ctx context.Context) error {
+	storage, err := postgres.Connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer storage.Close()
+
+	status, err := storage.MigrationStatus(ctx)
+	if err != nil {
+		return err
+	}
+	if !status.UpToDate() {
+		return fmt.Errorf("schema version %d, latest %d: run songctl migrate up", status.Current, status.Latest)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"RestApi_v1/internal/config/internal/lib/enrich"
+	"RestApi_v1/internal/config/internal/lib/ratelimit"
+	"RestApi_v1/internal/config/internal/storage/postgres"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+)
+
+func enrichCmd(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("enrich: subcommand is required (run, fake-provider)")
+	}
+
+	switch args[0] {
+	case "run":
+		return enrichRun(ctx, args[1:])
+	case "fake-provider":
+		return enrichFakeProvider(ctx, args[1:])
+	default:
+		return fmt.Errorf("enrich: unknown subcommand %q", args[0])
+	}
+}
+
+// enrichRun разовый проход, настройки сервиса и лимита берутся из конфига
+func enrichRun(ctx context.Context, args []string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	fs := flag.NewFlagSet("enrich run", flag.ContinueOnError)
+	batch := fs.Int("batch", cfg.Enrichment.BatchSize, "songs per pass")
+	all := fs.Bool("all", false, "repeat passes until no incomplete songs are left")
+	providerURL := fs.String("provider-url", "", "use http provider at this URL instead of the configured one")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg.Enrichment.BatchSize = *batch
+	if *providerURL != "" {
+		cfg.Enrichment.Provider.Kind = enrich.ProviderHTTP
+		cfg.Enrichment.Provider.URL = *providerURL
+	}
+
+	provider, err := enrich.NewProvider(cfg.Enrichment.Provider)
+	if err != nil {
+		return err
+	}
+
+	storage, err := postgres.New(ctx)
+	if err != nil {
+		return err
+	}
+	defer storage.Close()
+
+	// с postgres-лимитом утилита делит квоту сервиса с работающими серверами
+	var limiter ratelimit.Store = ratelimit.NewMemoryStore()
+	if cfg.RateLimit.Store == "postgres" {
+		limiter = ratelimit.NewSharedStore(storage)
+	}
+
+	log := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	enricher := enrich.New(log, storage, provider, limiter, cfg.Enrichment)
+
+	var total enrich.Summary
+	for {
+		summary, err := enricher.RunOnce(ctx)
+		total.Checked += summary.Checked
+		total.Enriched += summary.Enriched
+		total.NoData += summary.NoData
+		total.NotFound += summary.NotFound
+		total.Failed += summary.Failed
+		if err != nil {
+			return err
+		}
+		if !*all || summary.Checked < *batch {
+			break
+		}
+	}
+
+	fmt.Printf("checked %d: enriched %d, no data %d, not found %d, failed %d\n",
+		total.Checked, total.Enriched, total.NoData, total.NotFound, total.Failed)
+	return nil
+}
+
+// enrichFakeProvider поднимает локальный сервис /info для проверки http-провайдера
+func enrichFakeProvider(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("enrich fake-provider", flag.ContinueOnError)
+	addr := fs.String("addr", "localhost:8090", "listen address")
+	fixtures := fs.String("fixtures", "", "JSON array of {group, song, releaseDate, text, link}; without it every song is found")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var provider enrich.Provider = enrich.NewFakeProvider(nil)
+	if *fixtures != "" {
+		p, err := enrich.LoadFakeProvider(*fixtures)
+		if err != nil {
+			return err
+		}
+		provider = p
+	}
+
+	server := &http.Server{
+		Addr:              *addr,
+		Handler:           enrich.FakeHandler(provider),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	fmt.Printf("fake provider listening on http://%s/info?group=...&song=...\n", *addr)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
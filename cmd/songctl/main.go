@@ -0,0 +1,104 @@
+package main
+
+import (
+	"RestApi_v1/internal/config/internal/config"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// songctl - административная утилита, работает напрямую с хранилищем по тому же конфигу, что и сервер
+
+const usage = `usage: songctl [-config <path>] <command> [arguments]
+
+  -config  config file, default $CONFIG_PATH or ./config/local.yaml
+
+commands:
+  serve
+  migrate status [-o table|json|csv]
+  migrate up
+  song add -song <title> [-group <name>] [-text <text>] [-release-date <date>] [-link <url>] [-o ...]
+  song get -id <id> [-o ...]
+  song list [-group <name>] [-song <title>] [-q <text>] [-from <date>] [-to <date>] [-after <id>] [-limit <n>] [-o ...]
+  song update -id <id> [-song ...] [-group ...] [-text ...] [-release-date ...] [-link ...] [-o ...]
+  song delete -song <title>
+  import [-file <path>] [-format json|ndjson|csv] [-mode atomic|best_effort] [-o ...]
+  export [-file <path>] [-format json|ndjson|csv] [-group ...] [-song ...] [-from ...] [-to ...]
+  apikey create -name <name> -role <reader|editor|admin>
+  apikey revoke -id <id>
+  apikey list [-o table|json|csv]
+  config check [-db]
+  enrich run [-batch <n>] [-all] [-provider-url <url>]
+  enrich fake-provider [-addr <host:port>] [-fixtures <file.json>]
+`
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := run(ctx, os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		code := 1
+		var exit *exitError
+		if errors.As(err, &exit) {
+			code = exit.code
+		}
+		stop()
+		os.Exit(code)
+	}
+}
+
+// configPath значение флага -config, общего для всех команд
+var configPath string
+
+func run(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("songctl", flag.ContinueOnError)
+	fs.StringVar(&configPath, "config", "", "config file")
+	fs.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	args = fs.Args()
+
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, usage)
+		return fmt.Errorf("command is required")
+	}
+
+	switch args[0] {
+	case "serve":
+		return serveCmd(ctx, args[1:])
+	case "migrate":
+		return migrateCmd(ctx, args[1:])
+	case "song":
+		return songCmd(ctx, args[1:])
+	case "import":
+		return importCmd(ctx, args[1:])
+	case "export":
+		return exportCmd(ctx, args[1:])
+	case "config":
+		return configCmd(ctx, args[1:])
+	case "apikey":
+		return apiKeyCmd(ctx, args[1:])
+	case "enrich":
+		return enrichCmd(ctx, args[1:])
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+		return nil
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
+
+// loadConfig конфиг по флагу -config, CONFIG_PATH или пути по умолчанию
+func loadConfig() (*config.Config, error) {
+	return config.Load(config.Path(configPath))
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"RestApi_v1/internal/config/internal/storage"
+	"RestApi_v1/internal/config/internal/storage/postgres"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// migrationStatus версия схемы для вывода командой migrate status
+type migrationStatus storage.MigrationStatus
+
+func (m migrationStatus) MarshalCSV() ([]string, [][]string) {
+	state := "pending"
+	if storage.MigrationStatus(m).UpToDate() {
+		state = "up to date"
+	}
+	return []string{"current", "latest", "state"}, [][]string{{strconv.Itoa(m.Current), strconv.Itoa(m.Latest), state}}
+}
+
+func migrateCmd(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("migrate: subcommand is required (status, up)")
+	}
+
+	// без миграций при подключении, иначе status всегда показывал бы актуальную схему
+	storage, err := postgres.Connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer storage.Close()
+
+	switch args[0] {
+	case "status":
+		return migrateStatus(ctx, storage, args[1:])
+	case "up":
+		return migrateUp(ctx, storage)
+	default:
+		return fmt.Errorf("migrate: unknown subcommand %q", args[0])
+	}
+}
+
+func migrateStatus(ctx context.Context, storage *postgres.Storage, args []string) error {
+	fs := flag.NewFlagSet("migrate status", flag.ContinueOnError)
+	output := outputFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := checkOutput(*output); err != nil {
+		return fmt.Errorf("migrate status: %w", err)
+	}
+
+	status, err := storage.MigrationStatus(ctx)
+	if err != nil {
+		return err
+	}
+	return printOutput(os.Stdout, *output, migrationStatus(status))
+}
+
+func migrateUp(ctx context.Context, storage *postgres.Storage) error {
+	before, err := storage.MigrationStatus(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := storage.Migrate(ctx); err != nil {
+		return err
+	}
+
+	if before.UpToDate() {
+		fmt.Printf("schema is up to date, version %d\n", before.Current)
+		return nil
+	}
+	fmt.Printf("schema migrated from version %d to %d\n", before.Current, before.Latest)
+	return nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"RestApi_v1/internal/config/internal/lib/api/format"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// Форматы вывода, значения флага -o
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputCSV   = "csv"
+)
+
+// maxCellWidth длиннее в таблице не показываем, полный текст есть в json и csv
+const maxCellWidth = 40
+
+// outputFlag добавляет к команде флаг -o
+func outputFlag(fs *flag.FlagSet) *string {
+	return fs.String("o", outputTable, "output format: table, json or csv")
+}
+
+func checkOutput(output string) error {
+	switch output {
+	case outputTable, outputJSON, outputCSV:
+		return nil
+	}
+	return fmt.Errorf("unknown output format %q", output)
+}
+
+// printOutput печатает значение таблицей, CSV с тем же заголовком или JSON как в ответах API
+func printOutput(w io.Writer, output string, v format.CSVMarshaler) error {
+	switch output {
+	case outputJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case outputCSV:
+		header, records := v.MarshalCSV()
+		cw := csv.NewWriter(w)
+		if err := cw.Write(header); err != nil {
+			return err
+		}
+		return cw.WriteAll(records)
+	default:
+		header, records := v.MarshalCSV()
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, strings.ToUpper(strings.Join(header, "\t")))
+		for _, record := range records {
+			cells := make([]string, len(record))
+			for i, cell := range record {
+				cells[i] = tableCell(cell)
+			}
+			fmt.Fprintln(tw, strings.Join(cells, "\t"))
+		}
+		return tw.Flush()
+	}
+}
+
+// tableCell ячейка таблицы в одну строку, пустая показывается прочерком
+func tableCell(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if s == "" {
+		return "-"
+	}
+	if r := []rune(s); len(r) > maxCellWidth {
+		return string(r[:maxCellWidth-1]) + "…"
+	}
+	return s
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"RestApi_v1/internal/config/internal/app"
+	"RestApi_v1/internal/config/internal/lib/logger/sl"
+	"context"
+	"fmt"
+)
+
+// exitError ошибка с кодом выхода процесса, как у сервера в cmd/restApi_v1
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+// serveCmd запускает сервер так же, как cmd/restApi_v1
+func serveCmd(ctx context.Context, args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("serve: unexpected arguments %q", args)
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return &exitError{code: app.ExitInit, err: err}
+	}
+
+	application, err := app.New(ctx, cfg)
+	if err != nil {
+		return &exitError{code: app.ExitCode(err), err: err}
+	}
+
+	err = application.Run(ctx)
+	if err != nil {
+		application.Log().Error("application stopped with errors", sl.Err(err))
+		return &exitError{code: app.ExitCode(err), err: err}
+	}
+	return nil
+}
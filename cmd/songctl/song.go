@@ -0,0 +1,274 @@
+package main
+
+import (
+	"RestApi_v1/internal/config/internal/http-server/handlers/song/save"
+	"RestApi_v1/internal/config/internal/lib/i18n"
+	"RestApi_v1/internal/config/internal/lib/jobs"
+	"RestApi_v1/internal/config/internal/lib/validate"
+	"RestApi_v1/internal/config/internal/models"
+	"RestApi_v1/internal/config/internal/storage"
+	"RestApi_v1/internal/config/internal/storage/postgres"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"os"
+	"strings"
+)
+
+// maxListLimit больше за один вызов song list не выдаем, дальше - через -after или export
+const maxListLimit = 1000
+
+func songCmd(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("song: subcommand is required (add, get, list, update, delete)")
+	}
+
+	storage, err := postgres.New(ctx)
+	if err != nil {
+		return err
+	}
+	defer storage.Close()
+
+	switch args[0] {
+	case "add":
+		return songAdd(ctx, storage, args[1:])
+	case "get":
+		return songGet(ctx, storage, args[1:])
+	case "list":
+		return songList(ctx, storage, args[1:])
+	case "update":
+		return songUpdate(ctx, storage, args[1:])
+	case "delete":
+		return songDelete(ctx, storage, args[1:])
+	default:
+		return fmt.Errorf("song: unknown subcommand %q", args[0])
+	}
+}
+
+// songFlags флаги полей песни для add и update
+func songFlags(fs *flag.FlagSet, req *save.Request) {
+	fs.StringVar(&req.Song, "song", "", "song title")
+	fs.StringVar(&req.Group, "group", "", "group name")
+	fs.StringVar(&req.TextSong, "text", "", "song text")
+	fs.StringVar(&req.DateSong, "release-date", "", "release date, 2006-01-02 or 02.01.2006")
+	fs.StringVar(&req.LinkSong, "link", "", "link to the song")
+}
+
+func songAdd(ctx context.Context, storage *postgres.Storage, args []string) error {
+	fs := flag.NewFlagSet("song add", flag.ContinueOnError)
+	var req save.Request
+	songFlags(fs, &req)
+	output := outputFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := checkOutput(*output); err != nil {
+		return fmt.Errorf("song add: %w", err)
+	}
+
+	if err := validateSong(&req); err != nil {
+		return fmt.Errorf("song add: %w", err)
+	}
+
+	id, err := storage.SaveSong(ctx, req.Song, req.Group, req.TextSong, req.DateSong, req.LinkSong)
+	if err != nil {
+		return fmt.Errorf("song add: %w", songError(err, req.Song))
+	}
+
+	song, err := songByID(ctx, storage, id)
+	if err != nil {
+		return fmt.Errorf("song add: %w", err)
+	}
+	return printOutput(os.Stdout, *output, models.Songs{song})
+}
+
+func songGet(ctx context.Context, storage *postgres.Storage, args []string) error {
+	fs := flag.NewFlagSet("song get", flag.ContinueOnError)
+	id := fs.Int64("id", 0, "song id")
+	output := outputFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *id <= 0 {
+		return fmt.Errorf("song get: -id is required")
+	}
+	if err := checkOutput(*output); err != nil {
+		return fmt.Errorf("song get: %w", err)
+	}
+
+	song, err := songByID(ctx, storage, *id)
+	if err != nil {
+		return fmt.Errorf("song get: %w", err)
+	}
+	return printOutput(os.Stdout, *output, models.Songs{song})
+}
+
+func songList(ctx context.Context, storage *postgres.Storage, args []string) error {
+	fs := flag.NewFlagSet("song list", flag.ContinueOnError)
+	var params jobs.ExportParams
+	fs.StringVar(&params.Group, "group", "", "only songs of this group")
+	fs.StringVar(&params.Song, "song", "", "title contains")
+	fs.StringVar(&params.From, "from", "", "released on or after this date")
+	fs.StringVar(&params.To, "to", "", "released on or before this date")
+	query := fs.String("q", "", "title, group or text contains")
+	after := fs.Int64("after", 0, "only songs with id greater than this")
+	limit := fs.Int("limit", 50, fmt.Sprintf("max songs to show, up to %d", maxListLimit))
+	output := outputFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *limit < 1 || *limit > maxListLimit {
+		return fmt.Errorf("song list: -limit must be between 1 and %d", maxListLimit)
+	}
+	if err := checkOutput(*output); err != nil {
+		return fmt.Errorf("song list: %w", err)
+	}
+
+	filter, err := params.Filter()
+	if err != nil {
+		return fmt.Errorf("song list: %w", err)
+	}
+	filter.Query = *query
+	filter.AfterID = *after
+
+	songs, err := storage.ListSongs(ctx, filter, *limit)
+	if err != nil {
+		return err
+	}
+	return printOutput(os.Stdout, *output, models.Songs(songs))
+}
+
+// songUpdate меняет только поля, переданные флагами, остальные берутся из текущей версии песни
+func songUpdate(ctx context.Context, storage *postgres.Storage, args []string) error {
+	fs := flag.NewFlagSet("song update", flag.ContinueOnError)
+	id := fs.Int64("id", 0, "song id")
+	var changes save.Request
+	songFlags(fs, &changes)
+	output := outputFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *id <= 0 {
+		return fmt.Errorf("song update: -id is required")
+	}
+	if err := checkOutput(*output); err != nil {
+		return fmt.Errorf("song update: %w", err)
+	}
+
+	current, err := songByID(ctx, storage, *id)
+	if err != nil {
+		return fmt.Errorf("song update: %w", err)
+	}
+
+	req := requestOf(current)
+	set := 0
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "song":
+			req.Song = changes.Song
+		case "group":
+			req.Group = changes.Group
+		case "text":
+			req.TextSong = changes.TextSong
+		case "release-date":
+			req.DateSong = changes.DateSong
+		case "link":
+			req.LinkSong = changes.LinkSong
+		default:
+			return
+		}
+		set++
+	})
+	if set == 0 {
+		return fmt.Errorf("song update: nothing to change, set at least one of -song, -group, -text, -release-date, -link")
+	}
+
+	if err := validateSong(&req); err != nil {
+		return fmt.Errorf("song update: %w", err)
+	}
+
+	if _, err := storage.UpdateSong(ctx, int(*id), req.Song, req.Group, req.TextSong, req.DateSong, req.LinkSong); err != nil {
+		return fmt.Errorf("song update: %w", songError(err, req.Song))
+	}
+
+	updated, err := songByID(ctx, storage, *id)
+	if err != nil {
+		return fmt.Errorf("song update: %w", err)
+	}
+	return printOutput(os.Stdout, *output, models.Songs{updated})
+}
+
+// songDelete удаляет по названию, как DELETE /song
+func songDelete(ctx context.Context, storage *postgres.Storage, args []string) error {
+	fs := flag.NewFlagSet("song delete", flag.ContinueOnError)
+	name := fs.String("song", "", "song title")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *name == "" {
+		return fmt.Errorf("song delete: -song is required")
+	}
+
+	if _, err := storage.DeleteSong(ctx, *name); err != nil {
+		return fmt.Errorf("song delete: %w", err)
+	}
+
+	fmt.Printf("song %q deleted\n", *name)
+	return nil
+}
+
+func songByID(ctx context.Context, s *postgres.Storage, id int64) (models.Song, error) {
+	songs, err := s.SongsByIDs(ctx, []int64{id})
+	if err != nil {
+		return models.Song{}, err
+	}
+	if len(songs) == 0 {
+		return models.Song{}, fmt.Errorf("song %d: %w", id, storage.ErrSongNotFound)
+	}
+	return songs[0], nil
+}
+
+// requestOf текущие поля песни в виде запроса на сохранение
+func requestOf(song models.Song) save.Request {
+	req := save.Request{
+		Song:     song.Song,
+		Group:    song.Group,
+		TextSong: song.Text,
+		LinkSong: song.Link,
+	}
+	if song.ReleaseDate.Valid {
+		req.DateSong = song.ReleaseDate.Time.Format(validate.DateLayoutISO)
+	}
+	return req
+}
+
+// validateSong проверяет поля по правилам POST /song и приводит дату к формату базы
+func validateSong(req *save.Request) error {
+	err := validate.Struct(req)
+	if err == nil {
+		req.DateSong, _ = validate.NormalizeDate(req.DateSong)
+		return nil
+	}
+
+	t := i18n.Get(i18n.DefaultLang)
+
+	var validateErrs validator.ValidationErrors
+	if !errors.As(err, &validateErrs) {
+		return err
+	}
+
+	msgs := make([]string, 0, len(validateErrs))
+	for _, fe := range validateErrs {
+		msgs = append(msgs, fe.Translate(t.Validation()))
+	}
+	return fmt.Errorf("%s: %s", t.T(i18n.MsgValidationError), strings.Join(msgs, ", "))
+}
+
+func songError(err error, song string) error {
+	if errors.Is(err, storage.ErrSongExist) {
+		return fmt.Errorf("song %q already exists", song)
+	}
+	return err
+}
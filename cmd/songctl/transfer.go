@@ -0,0 +1,259 @@
+package main
+
+import (
+	"RestApi_v1/internal/config/internal/lib/i18n"
+	"RestApi_v1/internal/config/internal/lib/jobs"
+	"RestApi_v1/internal/config/internal/lib/songimport"
+	"RestApi_v1/internal/config/internal/models"
+	"RestApi_v1/internal/config/internal/storage"
+	"RestApi_v1/internal/config/internal/storage/postgres"
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// exportBatch сколько строк читается из курсора за раз
+const exportBatch = 500
+
+// Форматы файлов import и export
+const (
+	fileJSON   = "json"
+	fileNDJSON = "ndjson"
+	fileCSV    = "csv"
+)
+
+var fileContentTypes = map[string]string{
+	fileJSON:   songimport.ContentTypeJSON,
+	fileNDJSON: songimport.ContentTypeNDJSON,
+	fileCSV:    songimport.ContentTypeCSV,
+}
+
+// importReport отчет загрузки, таблицей выводятся строки
+type importReport songimport.Report
+
+func (r importReport) MarshalCSV() ([]string, [][]string) {
+	records := make([][]string, 0, len(r.Rows))
+	for _, row := range r.Rows {
+		id := ""
+		if row.ID > 0 {
+			id = strconv.FormatInt(row.ID, 10)
+		}
+		records = append(records, []string{strconv.Itoa(row.Row), row.Status, id, row.Song, row.Error})
+	}
+	return []string{"row", "status", "id", "song", "error"}, records
+}
+
+// importCmd загружает песни из файла по тем же правилам, что POST /songs/import
+func importCmd(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	file := fs.String("file", "-", "file to import, - for stdin")
+	fileFormat := fs.String("format", "", "json, ndjson or csv, by default taken from the file extension")
+	modeName := fs.String("mode", string(songimport.ModeBestEffort), "atomic or best_effort")
+	output := outputFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := checkOutput(*output); err != nil {
+		return fmt.Errorf("import: %w", err)
+	}
+
+	mode, err := songimport.ParseMode(*modeName)
+	if err != nil {
+		return fmt.Errorf("import: %w", err)
+	}
+
+	contentType, err := fileContentType(*fileFormat, *file)
+	if err != nil {
+		return fmt.Errorf("import: %w", err)
+	}
+
+	in := io.Reader(os.Stdin)
+	if *file != "-" {
+		f, err := os.Open(*file)
+		if err != nil {
+			return fmt.Errorf("import: %w", err)
+		}
+		defer f.Close()
+		in = f
+	}
+
+	records, err := songimport.Decode(in, contentType)
+	if err != nil {
+		return fmt.Errorf("import: %w", err)
+	}
+
+	storage, err := postgres.New(ctx)
+	if err != nil {
+		return err
+	}
+	defer storage.Close()
+
+	report, err := songimport.Run(ctx, storage, records, mode, i18n.Get(i18n.DefaultLang))
+	if err != nil {
+		return fmt.Errorf("import: %w", err)
+	}
+
+	if err := printOutput(os.Stdout, *output, importReport(report)); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "total %d, created %d, duplicates %d, invalid %d\n",
+		report.Total, report.Created, report.Duplicates, report.Invalid)
+
+	if mode == songimport.ModeAtomic && !report.Committed {
+		return fmt.Errorf("import: %s", report.Message)
+	}
+	return nil
+}
+
+// exportCmd выгружает песни по фильтру, как GET /songs/export, но еще и в JSON-массив или CSV
+func exportCmd(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	var params jobs.ExportParams
+	fs.StringVar(&params.Group, "group", "", "only songs of this group")
+	fs.StringVar(&params.Song, "song", "", "title contains")
+	fs.StringVar(&params.From, "from", "", "released on or after this date")
+	fs.StringVar(&params.To, "to", "", "released on or before this date")
+	file := fs.String("file", "-", "file to write, - for stdout")
+	fileFormat := fs.String("format", "", "json, ndjson or csv, by default taken from the file extension")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if _, err := fileContentType(*fileFormat, *file); err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+	format := *fileFormat
+	if format == "" {
+		format = formatOf(*file)
+	}
+
+	filter, err := params.Filter()
+	if err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+
+	storage, err := postgres.New(ctx)
+	if err != nil {
+		return err
+	}
+	defer storage.Close()
+
+	out := os.Stdout
+	if *file != "-" {
+		if out, err = os.Create(*file); err != nil {
+			return fmt.Errorf("export: %w", err)
+		}
+	}
+
+	w := bufio.NewWriter(out)
+	count, err := writeSongs(ctx, w, storage, filter, format)
+	if err == nil {
+		err = w.Flush()
+	}
+	if out != os.Stdout {
+		err = errors.Join(err, out.Close())
+	}
+	if err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "exported %d songs\n", count)
+	return nil
+}
+
+// writeSongs пишет песни потоком, не собирая всю выгрузку в памяти
+func writeSongs(ctx context.Context, w io.Writer, exporter *postgres.Storage, filter storage.SongFilter, format string) (int, error) {
+	var (
+		count int
+		write func(models.Song) error
+		end   func() error
+	)
+
+	switch format {
+	case fileCSV:
+		cw := csv.NewWriter(w)
+		header, _ := models.Songs(nil).MarshalCSV()
+		if err := cw.Write(header); err != nil {
+			return 0, err
+		}
+		write = func(song models.Song) error {
+			_, records := models.Songs{song}.MarshalCSV()
+			return cw.Write(records[0])
+		}
+		end = func() error {
+			cw.Flush()
+			return cw.Error()
+		}
+	case fileJSON:
+		if _, err := io.WriteString(w, "["); err != nil {
+			return 0, err
+		}
+		write = func(song models.Song) error {
+			if count > 0 {
+				if _, err := io.WriteString(w, ","); err != nil {
+					return err
+				}
+			}
+			raw, err := json.Marshal(song)
+			if err != nil {
+				return err
+			}
+			_, err = w.Write(raw)
+			return err
+		}
+		end = func() error {
+			_, err := io.WriteString(w, "]\n")
+			return err
+		}
+	default:
+		enc := json.NewEncoder(w)
+		write = func(song models.Song) error {
+			return enc.Encode(song)
+		}
+		end = func() error { return nil }
+	}
+
+	err := exporter.ExportSongs(ctx, filter, exportBatch, func(song models.Song) error {
+		if err := write(song); err != nil {
+			return err
+		}
+		count++
+		return nil
+	})
+	if err != nil {
+		return count, err
+	}
+	return count, end()
+}
+
+// fileContentType тип содержимого по флагу -format или расширению файла
+func fileContentType(format string, file string) (string, error) {
+	if format == "" {
+		format = formatOf(file)
+	}
+	contentType, ok := fileContentTypes[format]
+	if !ok {
+		return "", fmt.Errorf("unknown file format %q, use -format json, ndjson or csv", format)
+	}
+	return contentType, nil
+}
+
+// formatOf формат по расширению, для stdin и неизвестных расширений - NDJSON
+func formatOf(file string) string {
+	switch filepath.Ext(file) {
+	case ".json":
+		return fileJSON
+	case ".csv":
+		return fileCSV
+	default:
+		return fileNDJSON
+	}
+}
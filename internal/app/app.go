@@ -0,0 +1,477 @@
+package app
+
+import (
+	"RestApi_v1/internal/config/internal/config"
+	"RestApi_v1/internal/config/internal/http-server/handlers/health"
+	"RestApi_v1/internal/config/internal/http-server/middleware/idempotency"
+	"RestApi_v1/internal/config/internal/lib/auth"
+	"RestApi_v1/internal/config/internal/lib/cache"
+	"RestApi_v1/internal/config/internal/lib/enrich"
+	"RestApi_v1/internal/config/internal/lib/events"
+	"RestApi_v1/internal/config/internal/lib/jobs"
+	"RestApi_v1/internal/config/internal/lib/logger/handlers/slogctx"
+	"RestApi_v1/internal/config/internal/lib/logger/sl"
+	"RestApi_v1/internal/config/internal/lib/ratelimit"
+	"RestApi_v1/internal/config/internal/lib/songcache"
+	"RestApi_v1/internal/config/internal/lib/tracing"
+	"RestApi_v1/internal/config/internal/lib/webhooks"
+	"RestApi_v1/internal/config/internal/storage/postgres"
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc"
+	grpchealth "google.golang.org/grpc/health"
+)
+
+const shutdownTimeout = 10 * time.Second
+
+// Коды завершения процесса
+const (
+	ExitOK       = 0
+	ExitRuntime  = 1 // сервер или воркер упал во время работы
+	ExitInit     = 2 // не удалось собрать приложение
+	ExitShutdown = 3 // ошибки при остановке
+)
+
+var (
+	ErrInit     = errors.New("failed to init application")
+	ErrShutdown = errors.New("failed to stop application")
+)
+
+// Worker фоновая задача, работающая до отмены контекста
+type Worker interface {
+	Name() string
+	Run(ctx context.Context) error
+}
+
+type closer struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// App владеет всеми компонентами приложения и их порядком запуска и остановки
+type App struct {
+	// cfg конфиг на момент старта, по нему собраны компоненты
+	cfg *config.Config
+	// current действующий конфиг: при перезагрузке в нем меняются только части из applyReloadable
+	current  atomic.Pointer[config.Config]
+	log      *slog.Logger
+	logLevel *slog.LevelVar
+	storage  *postgres.Storage
+	songs    songcache.Store // storage или кеш перед ним
+	server   *http.Server
+	// redirect nil, если http_server.redirect_address не задан
+	redirect *http.Server
+	// grpc nil, если gRPC выключен в конфиге
+	grpc       *grpc.Server
+	grpcHealth *grpchealth.Server
+	readiness  *health.Readiness
+
+	authenticator  *auth.Authenticator
+	rateLimitStore ratelimit.Store
+	events         *events.Broker
+	workers        []Worker
+
+	// закрываются в обратном порядке
+	closers []closer
+}
+
+// New создает логгер и по очереди поднимает хранилище и HTTP-сервер по загруженному конфигу.
+// Если какой-то компонент не поднялся, уже созданные закрываются.
+func New(ctx context.Context, cfg *config.Config) (*App, error) {
+	a := &App{
+		cfg:      cfg,
+		logLevel: new(slog.LevelVar),
+	}
+	a.current.Store(cfg)
+	a.logLevel.Set(logLevel(cfg))
+	a.log = setupLogger(cfg.Env, a.logLevel)
+
+	a.log.Info("starting service", slog.String("env", cfg.Env))
+	a.log.Debug("debug msg are enable")
+
+	if err := a.init(ctx); err != nil {
+		a.log.Error("failed to init application", sl.Err(err))
+		closeErr := a.close()
+		return nil, errors.Join(fmt.Errorf("%w: %w", ErrInit, err), closeErr)
+	}
+
+	return a, nil
+}
+
+func (a *App) init(ctx context.Context) error {
+	// трейсинг: OTLP или stdout в зависимости от конфига
+	shutdownTracing, err := tracing.Setup(ctx, a.cfg.Tracing)
+	if err != nil {
+		return fmt.Errorf("tracing: %w", err)
+	}
+	a.addCloser("tracing", shutdownTracing)
+
+	// старт базы данных, создаем таблицы если их нет
+	storage, err := postgres.New(ctx)
+	if err != nil {
+		return fmt.Errorf("storage: %w", err)
+	}
+	a.storage = storage
+	a.addCloser("storage", func(context.Context) error {
+		storage.Close()
+		return nil
+	})
+
+	a.readiness = health.NewReadiness(a.log, storage)
+
+	jwtVerifier, err := auth.NewJWTVerifier(a.cfg.Auth.JWT)
+	if err != nil {
+		return fmt.Errorf("auth: %w", err)
+	}
+	// одна проверка учетных данных для REST и gRPC
+	a.authenticator = auth.NewAuthenticator(storage, jwtVerifier)
+
+	switch a.cfg.RateLimit.Store {
+	case "postgres":
+		a.rateLimitStore = ratelimit.NewSharedStore(storage)
+		a.workers = append(a.workers, ratelimit.NewCleaner(a.log, storage, a.cfg.RateLimit.CleanupInterval, a.longestRateLimitWindow))
+	case "memory", "":
+		a.rateLimitStore = ratelimit.NewMemoryStore()
+	default:
+		return fmt.Errorf("rate limit: unknown store %q", a.cfg.RateLimit.Store)
+	}
+
+	// воркеры очереди задач; при остановке доводят задачу до контрольной точки
+	if a.cfg.Jobs.Workers > 0 {
+		a.workers = append(a.workers, jobs.NewRunner(a.log, storage, a.cfg.Jobs, map[string]jobs.Handler{
+			jobs.KindImport: jobs.NewImportHandler(storage, storage),
+			jobs.KindExport: jobs.NewExportHandler(storage),
+		}))
+	}
+
+	// дозаполнение песен из внешнего сервиса; лимит запросов к нему общий через rateLimitStore
+	if a.cfg.Enrichment.Enabled {
+		provider, err := enrich.NewProvider(a.cfg.Enrichment.Provider)
+		if err != nil {
+			return fmt.Errorf("enrichment: %w", err)
+		}
+		a.workers = append(a.workers, enrich.New(a.log, storage, provider, a.rateLimitStore, a.cfg.Enrichment))
+	}
+
+	if err := a.initSongCache(ctx); err != nil {
+		return fmt.Errorf("cache: %w", err)
+	}
+
+	a.workers = append(a.workers, idempotency.NewCleaner(a.log, storage, a.cfg.Idempotency.CleanupInterval))
+
+	// лента изменений песен для /events из журнала, который пишет триггер
+	a.events = events.NewBroker(a.log, storage, a.cfg.Events)
+	a.workers = append(a.workers, a.events, events.NewCleaner(a.log, storage, a.cfg.Events))
+
+	// исходящие вебхуки из outbox, который пишут изменения песен
+	if a.cfg.Webhooks.Workers > 0 {
+		a.workers = append(a.workers, webhooks.NewDispatcher(a.log, storage, a.cfg.Webhooks))
+	}
+
+	a.workers = append(a.workers, &configReloader{app: a})
+
+	router, err := a.router()
+	if err != nil {
+		return fmt.Errorf("router: %w", err)
+	}
+
+	if err := a.httpServer(router); err != nil {
+		return err
+	}
+
+	if a.cfg.GRPC.Enabled {
+		a.grpc = a.grpcServer()
+	}
+
+	return nil
+}
+
+// initSongCache ставит кеш перед чтением песен по id. Без кеша хендлеры работают со storage напрямую.
+func (a *App) initSongCache(ctx context.Context) error {
+	a.songs = a.storage
+	if !a.cfg.Cache.Enabled {
+		return nil
+	}
+
+	var shared cache.Backend
+	if a.cfg.Cache.Redis.Addr != "" {
+		redis := cache.NewRedisBackend(a.cfg.Cache.Redis.Addr, a.cfg.Cache.Redis.Password, a.cfg.Cache.Redis.DB)
+		a.addCloser("cache redis", func(context.Context) error {
+			return redis.Close()
+		})
+		if err := redis.Ping(ctx); err != nil {
+			return fmt.Errorf("redis: %w", err)
+		}
+		shared = redis
+	}
+
+	songs := songcache.New(a.log, a.storage, shared, a.cfg.Cache)
+	a.songs = songs
+	a.workers = append(a.workers, songcache.NewInvalidator(a.log, songs, a.storage))
+
+	// счетчики попаданий и промахов в /debug/vars
+	publishSongCacheStats(songs)
+
+	return nil
+}
+
+var songCacheStats struct {
+	once  sync.Once
+	cache atomic.Pointer[songcache.Cache]
+}
+
+// publishSongCacheStats регистрирует переменную expvar один раз на процесс:
+// повторный expvar.Publish с тем же именем паникует
+func publishSongCacheStats(c *songcache.Cache) {
+	songCacheStats.cache.Store(c)
+	songCacheStats.once.Do(func() {
+		expvar.Publish("song_cache", expvar.Func(func() any {
+			return songCacheStats.cache.Load().Stats()
+		}))
+	})
+}
+
+func (a *App) addCloser(name string, fn func(ctx context.Context) error) {
+	a.closers = append(a.closers, closer{name: name, fn: fn})
+}
+
+// Run запускает HTTP-сервер и воркеры и блокируется до отмены ctx или падения компонента.
+// Затем останавливает всё в обратном порядке и возвращает собранные ошибки.
+func (a *App) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// первая ошибка компонента останавливает всё приложение
+	runErrs := make(chan error, len(a.workers)+3)
+
+	var wg sync.WaitGroup
+
+	a.log.Info("starting server", slog.String("address", a.cfg.Address), slog.Bool("tls", a.cfg.HTTPServer.TLS.Enabled))
+	go func() {
+		if err := a.serveHTTP(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			runErrs <- fmt.Errorf("http server: %w", err)
+		}
+	}()
+	a.log.Info("server started")
+
+	if a.redirect != nil {
+		a.log.Info("starting https redirect", slog.String("address", a.redirect.Addr))
+		go func() {
+			if err := a.redirect.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				runErrs <- fmt.Errorf("redirect server: %w", err)
+			}
+		}()
+	}
+
+	if a.grpc != nil {
+		lis, err := net.Listen("tcp", a.cfg.GRPC.Address)
+		if err != nil {
+			runErrs <- fmt.Errorf("grpc server: %w", err)
+		} else {
+			a.log.Info("starting grpc server", slog.String("address", a.cfg.GRPC.Address))
+			go func() {
+				if err := a.grpc.Serve(lis); err != nil {
+					runErrs <- fmt.Errorf("grpc server: %w", err)
+				}
+			}()
+		}
+	}
+
+	for _, w := range a.workers {
+		wg.Add(1)
+		go func(w Worker) {
+			defer wg.Done()
+
+			a.log.Info("starting worker", slog.String("worker", w.Name()))
+			if err := w.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+				runErrs <- fmt.Errorf("worker %s: %w", w.Name(), err)
+			}
+		}(w)
+	}
+
+	var runErr error
+	select {
+	case <-ctx.Done():
+		a.log.Info("stopping application")
+	case runErr = <-runErrs:
+		a.log.Error("component failed, stopping application", sl.Err(runErr))
+	}
+
+	shutdownErr := a.shutdown(cancel, &wg)
+
+	if shutdownErr != nil {
+		shutdownErr = fmt.Errorf("%w: %w", ErrShutdown, shutdownErr)
+	}
+	return errors.Join(runErr, shutdownErr)
+}
+
+func (a *App) shutdown(cancelWorkers context.CancelFunc, workers *sync.WaitGroup) error {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	var errs []error
+
+	// балансировщик должен увидеть not ready до закрытия соединений
+	a.readiness.SetNotReady()
+
+	a.log.Info("stopping server")
+	if err := a.server.Shutdown(ctx); err != nil {
+		a.log.Error("failed to stop server", sl.Err(err))
+		errs = append(errs, fmt.Errorf("http server: %w", err))
+	} else {
+		a.log.Info("server stopped")
+	}
+
+	if a.redirect != nil {
+		if err := a.redirect.Shutdown(ctx); err != nil {
+			a.log.Error("failed to stop redirect server", sl.Err(err))
+			errs = append(errs, fmt.Errorf("redirect server: %w", err))
+		}
+	}
+
+	if a.grpc != nil {
+		if err := a.stopGRPC(ctx); err != nil {
+			a.log.Error("failed to stop grpc server", sl.Err(err))
+			errs = append(errs, fmt.Errorf("grpc server: %w", err))
+		} else {
+			a.log.Info("grpc server stopped")
+		}
+	}
+
+	cancelWorkers()
+
+	done := make(chan struct{})
+	go func() {
+		workers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		errs = append(errs, fmt.Errorf("workers: %w", ctx.Err()))
+	}
+
+	if err := a.closeWithContext(ctx); err != nil {
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
+
+// stopGRPC дожидается текущих вызовов, а по истечении ctx обрывает их
+func (a *App) stopGRPC(ctx context.Context) error {
+	// клиенты с health-check перестают слать новые вызовы
+	a.grpcHealth.Shutdown()
+
+	stopped := make(chan struct{})
+	go func() {
+		a.grpc.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		a.grpc.Stop()
+		return ctx.Err()
+	}
+}
+
+func (a *App) close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	return a.closeWithContext(ctx)
+}
+
+func (a *App) closeWithContext(ctx context.Context) error {
+	var errs []error
+
+	for i := len(a.closers) - 1; i >= 0; i-- {
+		c := a.closers[i]
+		if err := c.fn(ctx); err != nil {
+			a.log.Error("failed to close component", slog.String("component", c.name), sl.Err(err))
+			errs = append(errs, fmt.Errorf("%s: %w", c.name, err))
+			continue
+		}
+		a.log.Info("component closed", slog.String("component", c.name))
+	}
+	a.closers = nil
+
+	return errors.Join(errs...)
+}
+
+// Log возвращает логгер приложения
+func (a *App) Log() *slog.Logger {
+	return a.log
+}
+
+// ExitCode переводит ошибку Run или New в код завершения процесса
+func ExitCode(err error) int {
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.Is(err, ErrInit):
+		return ExitInit
+	case errors.Is(err, ErrShutdown) && !isRuntime(err):
+		return ExitShutdown
+	default:
+		return ExitRuntime
+	}
+}
+
+// isRuntime сообщает, есть ли среди собранных ошибок что-то кроме ошибок остановки
+func isRuntime(err error) bool {
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		return !errors.Is(err, ErrShutdown)
+	}
+	for _, e := range joined.Unwrap() {
+		if e != nil && !errors.Is(e, ErrShutdown) {
+			return true
+		}
+	}
+	return false
+}
+
+// setupLogger формат логов зависит от окружения, уровень задает level и меняется на ходу.
+// trace_id и request_id берутся из контекста записи, поэтому логируем через *Context.
+func setupLogger(env string, level *slog.LevelVar) *slog.Logger {
+	var log *slog.Logger
+
+	switch env {
+	case config.EnvLocal:
+		log = slog.New(slogctx.NewHandler(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: level}),
+		))
+	case config.EnvDev, config.EnvProd:
+		log = slog.New(slogctx.NewHandler(
+			slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: level}),
+		))
+	}
+	return log
+}
+
+// logLevel уровень из log.level, без него - debug для local и dev, info для prod
+func logLevel(cfg *config.Config) slog.Level {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(cfg.Log.Level)); err == nil {
+		return level
+	}
+	if cfg.Env == config.EnvProd {
+		return slog.LevelInfo
+	}
+	return slog.LevelDebug
+}
@@ -0,0 +1,60 @@
+package app
+
+import (
+	songv1 "RestApi_v1/internal/config/api/song/v1"
+	"RestApi_v1/internal/config/internal/grpc-server/interceptor"
+	"RestApi_v1/internal/config/internal/grpc-server/song"
+	"RestApi_v1/internal/config/internal/lib/auth"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+// grpcRoles минимальная роль по методу, те же, что у соответствующих REST-маршрутов.
+// Get, как и GET /{id}/{page}/{pageSize}, доступен без учетных данных.
+var grpcRoles = map[string]auth.Role{
+	songv1.SongService_Create_FullMethodName: auth.RoleEditor,
+	songv1.SongService_List_FullMethodName:   auth.RoleReader,
+	songv1.SongService_Search_FullMethodName: auth.RoleReader,
+	songv1.SongService_Update_FullMethodName: auth.RoleEditor,
+	songv1.SongService_Delete_FullMethodName: auth.RoleAdmin,
+}
+
+// grpcLimits группа лимита по методу, те же, что у соответствующих REST-маршрутов
+var grpcLimits = map[string]string{
+	songv1.SongService_Create_FullMethodName: groupSongsWrite,
+	songv1.SongService_Get_FullMethodName:    groupSongsRead,
+	songv1.SongService_List_FullMethodName:   groupSongsRead,
+	songv1.SongService_Search_FullMethodName: groupSongsRead,
+	songv1.SongService_Update_FullMethodName: groupSongsWrite,
+	songv1.SongService_Delete_FullMethodName: groupSongsWrite,
+}
+
+// grpcServer собирает gRPC-сервер с SongService, health и, если включено, reflection
+func (a *App) grpcServer() *grpc.Server {
+	server := grpc.NewServer(
+		// порядок как у middleware REST: трейс, лог, восстановление после паники, аутентификация, лимит
+		grpc.ChainUnaryInterceptor(
+			interceptor.Tracing(),
+			interceptor.Logger(a.log),
+			interceptor.Recovery(a.log),
+			interceptor.Auth(a.log, a.cfg.Auth.APIKeyHeader, a.authenticator, grpcRoles),
+			interceptor.RateLimit(a.log, a.rateLimitStore, grpcLimits, a.rateLimitRule),
+		),
+	)
+
+	song.Register(server, a.log, a.songs, a.storage)
+
+	// общий статус "" и статус сервиса - SERVING до остановки приложения
+	a.grpcHealth = health.NewServer()
+	a.grpcHealth.SetServingStatus(songv1.SongService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(server, a.grpcHealth)
+
+	if a.cfg.GRPC.Reflection {
+		reflection.Register(server)
+	}
+
+	return server
+}
@@ -0,0 +1,94 @@
+package app
+
+import (
+	"RestApi_v1/internal/config/internal/lib/tlsconfig"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// httpServer собирает основной HTTP-сервер, а при redirect_address - и листенер с редиректом на https
+func (a *App) httpServer(router http.Handler) error {
+	cfg := a.cfg.HTTPServer
+
+	handler := router
+	if cfg.H2C {
+		handler = h2c.NewHandler(router, &http2.Server{IdleTimeout: cfg.IdleTimeout})
+	}
+
+	a.server = &http.Server{
+		Addr:         cfg.Address,
+		Handler:      handler,
+		ReadTimeout:  cfg.Timeout,
+		WriteTimeout: cfg.Timeout,
+		IdleTimeout:  cfg.IdleTimeout,
+	}
+	// открытые потоки событий иначе не дадут серверу остановиться
+	a.server.RegisterOnShutdown(a.events.Close)
+
+	if !cfg.HTTP2 {
+		// пустая, но не nil карта выключает согласование h2 по ALPN
+		a.server.TLSNextProto = map[string]func(*http.Server, *tls.Conn, http.Handler){}
+	}
+
+	if cfg.TLS.Enabled {
+		certs, err := tlsconfig.NewCertReloader(a.log, cfg.TLS.CertFile, cfg.TLS.KeyFile)
+		if err != nil {
+			return fmt.Errorf("tls: %w", err)
+		}
+		if a.server.TLSConfig, err = tlsconfig.New(cfg.TLS, certs); err != nil {
+			return fmt.Errorf("tls: %w", err)
+		}
+		a.workers = append(a.workers, certs)
+	}
+
+	if cfg.RedirectAddress != "" {
+		a.redirect = &http.Server{
+			Addr:         cfg.RedirectAddress,
+			Handler:      httpsRedirect(cfg.Address),
+			ReadTimeout:  cfg.Timeout,
+			WriteTimeout: cfg.Timeout,
+			IdleTimeout:  cfg.IdleTimeout,
+		}
+	}
+
+	return nil
+}
+
+// serveHTTP блокируется, пока сервер не остановлен; с TLS сертификат берется из TLSConfig
+func (a *App) serveHTTP() error {
+	if a.server.TLSConfig != nil {
+		return a.server.ListenAndServeTLS("", "")
+	}
+	return a.server.ListenAndServe()
+}
+
+// httpsRedirect отправляет запрос на тот же хост и путь по https, на порт основного листенера
+func httpsRedirect(address string) http.Handler {
+	_, port, _ := net.SplitHostPort(address)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if port != "443" {
+			host = net.JoinHostPort(host, port)
+		}
+
+		target := url.URL{
+			Scheme:   "https",
+			Host:     host,
+			Path:     r.URL.Path,
+			RawPath:  r.URL.RawPath,
+			RawQuery: r.URL.RawQuery,
+		}
+		// 308 сохраняет метод и тело, в отличие от 301
+		http.Redirect(w, r, target.String(), http.StatusPermanentRedirect)
+	})
+}
@@ -0,0 +1,146 @@
+package app
+
+import (
+	"RestApi_v1/internal/config/internal/config"
+	"RestApi_v1/internal/config/internal/lib/logger/sl"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// reloadDebounce редактор сохраняет файл несколькими операциями, перечитываем после последней
+const reloadDebounce = 300 * time.Millisecond
+
+// reloadable поля, которые применяются без перезапуска: само поле или все поля раздела с точкой на конце
+var reloadable = []string{"log.", "rate_limit.enabled", "rate_limit.groups.", "cors.", "graphql."}
+
+func isReloadable(field string) bool {
+	for _, prefix := range reloadable {
+		if field == prefix || (strings.HasSuffix(prefix, ".") && strings.HasPrefix(field, prefix)) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyReloadable переносит из next в cfg разделы, перечисленные в reloadable
+func applyReloadable(cfg *config.Config, next *config.Config) {
+	cfg.Log = next.Log
+	cfg.RateLimit.Enabled = next.RateLimit.Enabled
+	cfg.RateLimit.Groups = next.RateLimit.Groups
+	cfg.CORS = next.CORS
+	cfg.GraphQL = next.GraphQL
+}
+
+// configReloader перечитывает конфиг по SIGHUP и, если включено reload.watch, по изменению файлов конфига
+type configReloader struct {
+	app *App
+}
+
+func (r *configReloader) Name() string {
+	return "config-reload"
+}
+
+func (r *configReloader) Run(ctx context.Context) error {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	var (
+		events <-chan fsnotify.Event
+		errs   <-chan error
+	)
+	if r.app.cfg.Reload.Watch {
+		watcher, err := fsnotify.NewWatcher()
+		if err != nil {
+			return fmt.Errorf("failed to watch config: %w", err)
+		}
+		defer watcher.Close()
+
+		// следим за каталогом: редакторы и ConfigMap заменяют файл, а не пишут в него
+		if err := watcher.Add(filepath.Dir(r.app.cfg.Path)); err != nil {
+			return fmt.Errorf("failed to watch config: %w", err)
+		}
+		events, errs = watcher.Events, watcher.Errors
+	}
+
+	debounce := time.NewTimer(time.Hour)
+	debounce.Stop()
+	defer debounce.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-hup:
+			r.app.log.Info("reloading config", slog.String("reason", "SIGHUP"))
+			r.app.reloadConfig()
+		case event := <-events:
+			if r.isConfigFile(event.Name) && event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) != 0 {
+				debounce.Reset(reloadDebounce)
+			}
+		case <-debounce.C:
+			r.app.log.Info("reloading config", slog.String("reason", "file changed"))
+			r.app.reloadConfig()
+		case err := <-errs:
+			r.app.log.Error("config watcher failed", sl.Err(err))
+		}
+	}
+}
+
+// isConfigFile базовый файл или файл окружения рядом с ним
+func (r *configReloader) isConfigFile(name string) bool {
+	cfg := r.app.cfg
+	name = filepath.Clean(name)
+	if name == filepath.Clean(cfg.Path) {
+		return true
+	}
+	envPath := config.EnvPath(cfg.Path, cfg.Env)
+	return envPath != "" && name == filepath.Clean(envPath)
+}
+
+// reloadConfig загружает конфиг заново и применяет то, что можно поменять на ходу.
+// Невалидный конфиг отклоняется целиком, действующий остается прежним.
+func (a *App) reloadConfig() {
+	next, err := config.Load(a.cfg.Path)
+	if err != nil {
+		a.log.Error("config reload rejected", sl.Err(err))
+		return
+	}
+
+	prev := a.current.Load()
+	changes := config.Diff(prev, next)
+	if len(changes) == 0 {
+		a.log.Info("config reloaded, nothing changed")
+		return
+	}
+
+	applied := 0
+	for _, c := range changes {
+		attrs := []any{slog.String("field", c.Field), slog.String("old", c.Old), slog.String("new", c.New)}
+		if isReloadable(c.Field) {
+			applied++
+			a.log.Info("config changed", attrs...)
+			continue
+		}
+		a.log.Warn("config change requires restart", attrs...)
+	}
+	if applied == 0 {
+		return
+	}
+
+	cfg := *prev
+	applyReloadable(&cfg, next)
+	a.current.Store(&cfg)
+	a.logLevel.Set(logLevel(&cfg))
+
+	a.log.Info("config reload applied", slog.Int("changes", applied), slog.Int("pending_restart", len(changes)-applied))
+}
@@ -0,0 +1,193 @@
+package app
+
+import (
+	"RestApi_v1/internal/config/cmd/restApi_v1/docs"
+	"RestApi_v1/internal/config/internal/config"
+	"RestApi_v1/internal/config/internal/graph"
+	"RestApi_v1/internal/config/internal/http-server/handlers/events/socket"
+	"RestApi_v1/internal/config/internal/http-server/handlers/events/stream"
+	"RestApi_v1/internal/config/internal/http-server/handlers/health"
+	"RestApi_v1/internal/config/internal/http-server/handlers/job/create"
+	"RestApi_v1/internal/config/internal/http-server/handlers/job/result"
+	"RestApi_v1/internal/config/internal/http-server/handlers/job/status"
+	del "RestApi_v1/internal/config/internal/http-server/handlers/song/delete"
+	"RestApi_v1/internal/config/internal/http-server/handlers/song/export"
+	"RestApi_v1/internal/config/internal/http-server/handlers/song/get"
+	"RestApi_v1/internal/config/internal/http-server/handlers/song/importSongs"
+	"RestApi_v1/internal/config/internal/http-server/handlers/song/save"
+	updateSong "RestApi_v1/internal/config/internal/http-server/handlers/song/updateSong"
+	webhookCreate "RestApi_v1/internal/config/internal/http-server/handlers/webhook/create"
+	"RestApi_v1/internal/config/internal/http-server/handlers/webhook/deliveries"
+	webhookList "RestApi_v1/internal/config/internal/http-server/handlers/webhook/list"
+	"RestApi_v1/internal/config/internal/http-server/handlers/webhook/redeliver"
+	webhookRemove "RestApi_v1/internal/config/internal/http-server/handlers/webhook/remove"
+	mwAuth "RestApi_v1/internal/config/internal/http-server/middleware/auth"
+	mwCORS "RestApi_v1/internal/config/internal/http-server/middleware/cors"
+	mwDeadline "RestApi_v1/internal/config/internal/http-server/middleware/deadline"
+	mwHTTPCache "RestApi_v1/internal/config/internal/http-server/middleware/httpcache"
+	mwIdempotency "RestApi_v1/internal/config/internal/http-server/middleware/idempotency"
+	mwLogger "RestApi_v1/internal/config/internal/http-server/middleware/logger"
+	mwOpenAPI "RestApi_v1/internal/config/internal/http-server/middleware/openapi"
+	mwRateLimit "RestApi_v1/internal/config/internal/http-server/middleware/ratelimit"
+	mwTracing "RestApi_v1/internal/config/internal/http-server/middleware/tracing"
+	"RestApi_v1/internal/config/internal/lib/api/format"
+	"RestApi_v1/internal/config/internal/lib/auth"
+	"RestApi_v1/internal/config/internal/lib/ratelimit"
+	"expvar"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	httpSwagger "github.com/swaggo/http-swagger"
+	"net/http"
+	"time"
+)
+
+// группы маршрутов для лимитов из config.RateLimit.Groups
+const (
+	groupSongsRead   = "songs_read"
+	groupSongsWrite  = "songs_write"
+	groupSongsExport = "songs_export"
+	groupJobs        = "jobs"
+	groupWebhooks    = "webhooks"
+	groupGraphQL     = "graphql"
+)
+
+// маршруты с Cache-Control из config.HTTPCache.CacheControl
+const (
+	cacheSong      = "song"
+	cacheJobStatus = "job_status"
+)
+
+func (a *App) router() (http.Handler, error) {
+	// host и basePath спецификации берутся из конфига, остальное - из аннотаций
+	docs.SwaggerInfo.Host = a.cfg.Swagger.Host
+	if docs.SwaggerInfo.Host == "" {
+		docs.SwaggerInfo.Host = a.cfg.Address
+	}
+	docs.SwaggerInfo.BasePath = a.cfg.Swagger.BasePath
+
+	// в dev дополнительно сверяем ответы со спецификацией
+	validator, err := mwOpenAPI.NewValidator(a.log, []byte(docs.SwaggerInfo.ReadDoc()), a.cfg.Env != config.EnvProd)
+	if err != nil {
+		return nil, err
+	}
+
+	// все хендлеры отвечают через render.Respond с выбором формата по Accept
+	render.Respond = format.Respond
+
+	router := chi.NewRouter()
+
+	router.Use(middleware.RequestID)
+
+	router.Use(mwTracing.New())
+
+	// preflight отвечается до аутентификации и проверки по спецификации
+	router.Use(mwCORS.New(func() []string { return a.current.Load().CORS.AllowedOrigins }, a.cfg.Auth.APIKeyHeader, func() time.Duration { return a.current.Load().CORS.MaxAge }))
+
+	// логгер и восстановление после паники до аутентификации: отказы и сбои в ней тоже попадают в лог
+	// и не роняют соединение. Пользователя логгер узнает от аутентификации после ответа.
+	router.Use(mwLogger.New(a.log))
+
+	router.Use(middleware.Recoverer)
+
+	router.Use(mwAuth.New(a.log, a.cfg.Auth.APIKeyHeader, a.authenticator))
+
+	router.Use(validator.Middleware)
+
+	// повтор изменяющего запроса с тем же Idempotency-Key получает сохраненный ответ
+	idempotent := mwIdempotency.New(a.log, a.storage, a.cfg.Idempotency.TTL, a.cfg.Idempotency.MaxBodyBytes)
+	// загрузка файла идет дольше общего таймаута сервера
+	upload := mwDeadline.New(a.cfg.HTTPServer.UploadTimeout)
+
+	// пробы оркестратора: liveness и readiness
+	router.Get("/healthz", health.Liveness())
+	router.Get("/readyz", a.readiness.Handler())
+
+	// Определение маршрутов
+	// Метод Post - добавляем в базу данных песню
+	//{
+	//	"group": "Название группы2",
+	//	"song": "1234",
+	//	"textSong": "Текст песни223",
+	//	"dateSong": "2023-01-01",
+	//	"linkSong": "http://ссылка-на-песня"
+	//}
+	router.With(mwAuth.Require(auth.RoleEditor), a.limit(groupSongsWrite), idempotent).Post("/song", save.New(a.log, a.songs)) // add song to db
+
+	// метод Get - получаем из базы данных песню по id, нужно указать номер страницы и размер страницы для пагинации
+	router.With(a.limit(groupSongsRead), a.httpCache(cacheSong)).Get("/{id}/{page}/{pageSize}", get.New(a.log, a.songs)) // get song from db
+	// выгрузка всего каталога в NDJSON потоком из курсора
+	router.With(mwAuth.Require(auth.RoleReader), a.limit(groupSongsExport)).Get("/songs/export", export.New(a.log, a.storage))
+	// пакетная загрузка из NDJSON, JSON-массива или CSV с отчетом по каждой строке
+	router.With(mwAuth.Require(auth.RoleEditor), a.limit(groupSongsWrite), upload, idempotent).Post("/songs/import", importSongs.New(a.log, a.storage))
+	// фоновые загрузки и выгрузки: постановка в очередь, статус и результат
+	router.With(mwAuth.Require(auth.RoleReader), a.limit(groupJobs), upload, idempotent).Post("/jobs", create.New(a.log, a.storage, a.cfg.Jobs.MaxAttempts))
+	router.With(mwAuth.Require(auth.RoleReader), a.limit(groupSongsRead), a.httpCache(cacheJobStatus)).Get("/jobs/{id}", status.New(a.log, a.storage))
+	router.With(mwAuth.Require(auth.RoleReader), a.limit(groupSongsExport)).Get("/jobs/{id}/result", result.New(a.log, a.storage))
+	// лента изменений песен: SSE и WebSocket
+	router.With(mwAuth.Require(auth.RoleReader), a.limit(groupSongsRead)).Get("/events", stream.New(a.log, a.events))
+	router.With(mwAuth.Require(auth.RoleReader), a.limit(groupSongsRead)).Get("/events/ws", socket.New(a.log, a.events))
+	// GraphQL поверх тех же хранилищ; роли проверяются по полям схемы
+	router.With(a.limit(groupGraphQL)).Handle("/graphql", graph.NewHandler(a.log, a.songs, a.storage, func() config.GraphQL { return a.current.Load().GraphQL }))
+	// подписки на вебхуки и журнал доставок
+	router.Route("/webhooks", func(r chi.Router) {
+		r.Use(mwAuth.Require(auth.RoleAdmin), a.limit(groupWebhooks))
+		r.With(idempotent).Post("/", webhookCreate.New(a.log, a.storage))
+		r.Get("/", webhookList.New(a.log, a.storage))
+		r.With(idempotent).Delete("/{id}", webhookRemove.New(a.log, a.storage))
+		r.Get("/{id}/deliveries", deliveries.New(a.log, a.storage))
+		r.With(idempotent).Post("/{id}/deliveries/{deliveryId}/retry", redeliver.New(a.log, a.storage))
+	})
+	// метод Delete  - удаляем из базы данных песню имени
+	router.With(mwAuth.Require(auth.RoleAdmin), a.limit(groupSongsWrite), idempotent).Delete("/{song}", del.New(a.log, a.songs)) // delete song from db
+	// метод Put  - изменяем песню в базе данных, нужно в запросе передать айди - по айди идет поиск в базе
+	router.With(mwAuth.Require(auth.RoleEditor), a.limit(groupSongsWrite), idempotent).Put("/edit", updateSong.New(a.log, a.songs)) // edit song in db
+
+	// счетчики expvar, в том числе кеша песен
+	router.With(mwAuth.Require(auth.RoleAdmin)).Get("/debug/vars", expvar.Handler().ServeHTTP)
+
+	// Подключение Swagger UI, спецификация отдается по /swagger/doc.json
+	router.Get("/swagger/*", httpSwagger.Handler(httpSwagger.URL("doc.json")))
+
+	return router, nil
+}
+
+// httpCache возвращает middleware ETag и условных запросов с Cache-Control маршрута
+func (a *App) httpCache(route string) func(next http.Handler) http.Handler {
+	return mwHTTPCache.New(a.cfg.HTTPCache.CacheControl[route])
+}
+
+// limit возвращает middleware лимита для группы маршрутов, правило берется из действующего конфига
+func (a *App) limit(group string) func(next http.Handler) http.Handler {
+	return mwRateLimit.New(a.log, a.rateLimitStore, group, func() ratelimit.Rule {
+		return a.rateLimitRule(group)
+	})
+}
+
+// rateLimitRule правило группы из текущего конфига, общее для REST и gRPC
+func (a *App) rateLimitRule(group string) ratelimit.Rule {
+	cfg := a.current.Load()
+	if !cfg.RateLimit.Enabled {
+		return ratelimit.Rule{}
+	}
+
+	rule := cfg.RateLimit.Groups[group]
+	return ratelimit.Rule{
+		Requests: rule.Requests,
+		Window:   rule.Window,
+	}
+}
+
+// longestRateLimitWindow самое длинное окно среди бакетов общего хранилища: групп и запросов дозаполнения
+func (a *App) longestRateLimitWindow() time.Duration {
+	cfg := a.current.Load()
+
+	var longest time.Duration
+	for _, rule := range cfg.RateLimit.Groups {
+		longest = max(longest, rule.Window)
+	}
+	if cfg.Enrichment.Enabled {
+		longest = max(longest, cfg.Enrichment.RateLimit.Window)
+	}
+	return longest
+}
@@ -1,43 +1,232 @@
 package config
 
 import (
-	"github.com/ilyakaznacheev/cleanenv"
-	"log"
-	"os"
 	"time"
 )
 
+// Окружения, от них зависят формат логов и строгость проверок
+const (
+	EnvLocal = "local"
+	EnvDev   = "dev"
+	EnvProd  = "prod"
+)
+
 type Config struct {
-	Env string `yaml:"env" env-default:"local"`
+	// Path файл, из которого загружен конфиг; по нему же перечитывается при перезагрузке
+	Path string `yaml:"-"`
+
+	Env string `yaml:"env" env:"ENV" env-default:"local"`
 	//StoragePath string `yaml:"storage_path" env-required:"true"`
-	HTTPServer `yaml:"http_server"`
+	HTTPServer  `yaml:"http_server"`
+	Log         Log         `yaml:"log"`
+	Reload      Reload      `yaml:"reload"`
+	CORS        CORS        `yaml:"cors"`
+	Tracing     Tracing     `yaml:"tracing"`
+	Auth        Auth        `yaml:"auth"`
+	RateLimit   RateLimit   `yaml:"rate_limit"`
+	Swagger     Swagger     `yaml:"swagger"`
+	Jobs        Jobs        `yaml:"jobs"`
+	Enrichment  Enrichment  `yaml:"enrichment"`
+	Idempotency Idempotency `yaml:"idempotency"`
+	Cache       Cache       `yaml:"cache"`
+	HTTPCache   HTTPCache   `yaml:"http_cache"`
+	Events      Events      `yaml:"events"`
+	Webhooks    Webhooks    `yaml:"webhooks"`
+	GRPC        GRPC        `yaml:"grpc"`
+	GraphQL     GraphQL     `yaml:"graphql"`
 }
 
+// HTTPServer основной листенер API. С TLS HTTP/2 согласуется через ALPN, без TLS - только при H2C.
+// RedirectAddress - дополнительный листенер без TLS, который отправляет клиентов на https.
 type HTTPServer struct {
-	Address     string        `yaml:"address" env-default:"localhost:8080"`
-	Timeout     time.Duration `yaml:"timeout" env-default:"4s"`
-	IdleTimeout time.Duration `yaml:"idle_timeout" env-default:"60s"`
+	Address         string        `yaml:"address" env-default:"localhost:8080"`
+	Timeout         time.Duration `yaml:"timeout" env-default:"4s"`
+	IdleTimeout     time.Duration `yaml:"idle_timeout" env-default:"60s"`
+	UploadTimeout   time.Duration `yaml:"upload_timeout" env-default:"10m"` // на загрузку файлов вместо timeout
+	HTTP2           bool          `yaml:"http2" env-default:"true"`
+	H2C             bool          `yaml:"h2c" env-default:"false"` // HTTP/2 без TLS, для трафика внутри кластера
+	RedirectAddress string        `yaml:"redirect_address" env:"HTTP_REDIRECT_ADDRESS"`
+	TLS             TLS           `yaml:"tls"`
 	//User        string        `yaml:"user" env-required:"true"`
 	//Password    string        `yaml:"password" env-required:"true" env:"HTTP_SERVER_PASSWORD"`
 }
 
-func MustLoad() *Config {
+// TLS сертификат и ключ перечитываются при изменении файлов без перезапуска.
+// С ClientCAFile сервер проверяет сертификаты клиентов (mTLS); RequireClientCert отклоняет соединения без них.
+type TLS struct {
+	Enabled           bool   `yaml:"enabled" env:"TLS_ENABLED" env-default:"false"`
+	CertFile          string `yaml:"cert_file" env:"TLS_CERT_FILE"`
+	KeyFile           string `yaml:"key_file" env:"TLS_KEY_FILE"`
+	MinVersion        string `yaml:"min_version" env-default:"1.2"` // 1.2 или 1.3
+	ClientCAFile      string `yaml:"client_ca_file" env:"TLS_CLIENT_CA_FILE"`
+	RequireClientCert bool   `yaml:"require_client_cert" env-default:"false"`
+}
+
+// Log уровень логов: debug, info, warn или error. Пусто - по окружению: debug для local и dev, info для prod.
+type Log struct {
+	Level string `yaml:"level" env:"LOG_LEVEL"`
+}
+
+// Reload перечитывание конфига без перезапуска: по SIGHUP всегда, по изменению файла - если Watch.
+// На ходу применяются log, rate_limit.enabled, rate_limit.groups, cors и graphql, остальное - после перезапуска.
+type Reload struct {
+	Watch bool `yaml:"watch" env-default:"true"`
+}
+
+// CORS источники, которым браузер разрешит запросы к API. Пустой список - CORS выключен, "*" - любой источник.
+type CORS struct {
+	AllowedOrigins []string      `yaml:"allowed_origins" env:"CORS_ALLOWED_ORIGINS" env-separator:","`
+	MaxAge         time.Duration `yaml:"max_age" env-default:"10m"` // сколько браузер кеширует ответ на preflight
+}
+
+// Tracing настройки экспорта трейсов: otlp, stdout или none
+type Tracing struct {
+	Exporter    string  `yaml:"exporter" env:"TRACING_EXPORTER" env-default:"none"`
+	Endpoint    string  `yaml:"endpoint" env:"OTEL_EXPORTER_OTLP_ENDPOINT" env-default:"localhost:4317"`
+	Insecure    bool    `yaml:"insecure" env-default:"true"`
+	ServiceName string  `yaml:"service_name" env-default:"restApi_v1"`
+	SampleRatio float64 `yaml:"sample_ratio" env-default:"1"`
+}
+
+// Auth настройки аутентификации: API-ключи хранятся в базе, JWT проверяются по секрету или ключу
+type Auth struct {
+	APIKeyHeader string `yaml:"api_key_header" env-default:"X-API-Key"`
+	JWT          JWT    `yaml:"jwt"`
+}
+
+type JWT struct {
+	HMACSecret       string `yaml:"hmac_secret" env:"JWT_HMAC_SECRET"`
+	RSAPublicKeyPath string `yaml:"rsa_public_key_path" env:"JWT_RSA_PUBLIC_KEY_PATH"`
+	Issuer           string `yaml:"issuer"`
+	Audience         string `yaml:"audience"`
+}
+
+// RateLimit лимиты запросов по группам маршрутов, ключ - имя группы (songs_read, songs_write)
+type RateLimit struct {
+	Enabled bool                     `yaml:"enabled" env-default:"true"`
+	Store   string                   `yaml:"store" env-default:"memory"` // memory или postgres
+	Groups  map[string]RateLimitRule `yaml:"groups"`
+	// CleanupInterval как часто удалять из postgres бакеты, простоявшие дольше самого длинного окна
+	CleanupInterval time.Duration `yaml:"cleanup_interval" env-default:"10m"`
+}
+
+type RateLimitRule struct {
+	Requests int           `yaml:"requests"`
+	Window   time.Duration `yaml:"window"`
+}
+
+// Swagger адрес, по которому клиенты видят API. Host по умолчанию - адрес сервера,
+// BasePath меняется, если API отдается через прокси с префиксом.
+type Swagger struct {
+	Host     string `yaml:"host" env:"SWAGGER_HOST"`
+	BasePath string `yaml:"base_path" env:"SWAGGER_BASE_PATH" env-default:"/"`
+}
+
+// Jobs очередь фоновых задач. Workers: 0 - сервис только ставит задачи, выполняют их другие экземпляры.
+// Неудачная попытка повторяется через RetryBackoff, 2*RetryBackoff, ... но не реже MaxBackoff.
+type Jobs struct {
+	Workers      int           `yaml:"workers" env:"JOBS_WORKERS" env-default:"2"`
+	PollInterval time.Duration `yaml:"poll_interval" env-default:"1s"`
+	Lease        time.Duration `yaml:"lease" env-default:"1m"` // через сколько задачу молчащего воркера заберет другой
+	MaxAttempts  int           `yaml:"max_attempts" env-default:"5"`
+	RetryBackoff time.Duration `yaml:"retry_backoff" env-default:"5s"`
+	MaxBackoff   time.Duration `yaml:"max_backoff" env-default:"5m"`
+	StopTimeout  time.Duration `yaml:"stop_timeout" env-default:"5s"` // сколько ждать текущий шаг задачи при остановке
+}
 
-	configPath := "./config/local.yaml"
-	if configPath == "" {
-		log.Fatal("CONFIG_PATH is not set")
-	}
+// Enrichment дозаполнение пустых текста, даты и ссылки у песен из внешнего сервиса.
+// Песню, про которую сервис ничего не знает, снова спрашиваем через RecheckAfter, после ошибки - через RetryAfter.
+type Enrichment struct {
+	Enabled      bool               `yaml:"enabled" env:"ENRICHMENT_ENABLED" env-default:"false"`
+	Interval     time.Duration      `yaml:"interval" env-default:"1h"`
+	BatchSize    int                `yaml:"batch_size" env-default:"100"`
+	RecheckAfter time.Duration      `yaml:"recheck_after" env-default:"168h"`
+	RetryAfter   time.Duration      `yaml:"retry_after" env-default:"1h"`
+	RateLimit    RateLimitRule      `yaml:"rate_limit"` // запросы к сервису, общий бакет rate_limit.store
+	Provider     EnrichmentProvider `yaml:"provider"`
+}
 
-	// check if file exists
-	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		log.Fatalf("config file does not exist: %s", configPath)
-	}
+// EnrichmentProvider сервис с данными о песнях: http (GET {url}/info?group=&song=) или fake
+type EnrichmentProvider struct {
+	Kind    string        `yaml:"kind" env-default:"fake"`
+	URL     string        `yaml:"url" env:"ENRICHMENT_PROVIDER_URL"`
+	APIKey  string        `yaml:"api_key" env:"ENRICHMENT_PROVIDER_API_KEY"`
+	Timeout time.Duration `yaml:"timeout" env-default:"5s"`
+	// FixturesPath для fake: JSON-массив ответов. Без него fake придумывает данные для любой песни.
+	FixturesPath string `yaml:"fixtures_path"`
+}
 
-	var cfg Config
+// Idempotency ответы на запросы с заголовком Idempotency-Key хранятся TTL и отдаются повторно.
+// Тело запроса для сверки читается целиком до вызова хендлера, поэтому больше MaxBodyBytes с ключом не принимается.
+// Значение не должно быть меньше лимита на файл фоновой загрузки (128 MiB), иначе POST /jobs с ключом получит 413.
+type Idempotency struct {
+	TTL             time.Duration `yaml:"ttl" env-default:"24h"`
+	MaxBodyBytes    int64         `yaml:"max_body_bytes" env-default:"134217728"`
+	CleanupInterval time.Duration `yaml:"cleanup_interval" env-default:"10m"`
+}
+
+// Cache кеш песен для GET по id: LRU в памяти процесса и, если задан Redis.Addr, общий кеш реплик.
+// Изменения с других реплик приходят через LISTEN/NOTIFY, TTL ограничивает устаревание при потере уведомлений.
+type Cache struct {
+	Enabled bool          `yaml:"enabled" env:"CACHE_ENABLED" env-default:"true"`
+	Size    int           `yaml:"size" env-default:"10000"`
+	TTL     time.Duration `yaml:"ttl" env-default:"5m"`
+	Redis   CacheRedis    `yaml:"redis"`
+}
 
-	if err := cleanenv.ReadConfig(configPath, &cfg); err != nil {
-		log.Fatalf("cannot read config: %s", err)
-	}
+type CacheRedis struct {
+	Addr     string        `yaml:"addr" env:"CACHE_REDIS_ADDR"`
+	Password string        `yaml:"password" env:"CACHE_REDIS_PASSWORD"`
+	DB       int           `yaml:"db"`
+	TTL      time.Duration `yaml:"ttl" env-default:"1m"`
+}
+
+// HTTPCache заголовок Cache-Control по маршрутам (song, job_status). ETag и 304 работают и без него.
+type HTTPCache struct {
+	CacheControl map[string]string `yaml:"cache_control"`
+}
+
+// Events журнал изменений песен для /events. В журнале LogSize последних событий не старше Retention,
+// клиент с Last-Event-ID раньше начала журнала получает событие reset.
+type Events struct {
+	LogSize      int           `yaml:"log_size" env-default:"10000"`
+	Retention    time.Duration `yaml:"retention" env-default:"24h"`
+	TrimInterval time.Duration `yaml:"trim_interval" env-default:"1m"`
+	PollInterval time.Duration `yaml:"poll_interval" env-default:"5s"` // на случай потерянного уведомления
+	Heartbeat    time.Duration `yaml:"heartbeat" env-default:"15s"`
+	Buffer       int           `yaml:"buffer" env-default:"256"` // очередь подписчика, отставший отключается
+	// GapTimeout сколько ждать событие, которое получило id раньше уже видимых, но еще не закоммичено.
+	// Дольше - считаем транзакцию откаченной; должно быть больше самой долгой транзакции записи песен.
+	GapTimeout time.Duration `yaml:"gap_timeout" env-default:"30s"`
+}
+
+// Webhooks отправка событий песен подписчикам. Workers: 0 - сервис только пишет outbox,
+// отправляют другие экземпляры. Неудачная доставка повторяется через RetryBackoff, 2*RetryBackoff, ...
+// но не реже MaxBackoff; после MaxAttempts попыток доставка переходит в dead.
+type Webhooks struct {
+	Workers      int           `yaml:"workers" env:"WEBHOOKS_WORKERS" env-default:"2"`
+	PollInterval time.Duration `yaml:"poll_interval" env-default:"1s"`
+	BatchSize    int           `yaml:"batch_size" env-default:"100"` // событий outbox за один разбор
+	Timeout      time.Duration `yaml:"timeout" env-default:"10s"`
+	Lease        time.Duration `yaml:"lease" env-default:"1m"`
+	MaxAttempts  int           `yaml:"max_attempts" env-default:"8"`
+	RetryBackoff time.Duration `yaml:"retry_backoff" env-default:"10s"`
+	MaxBackoff   time.Duration `yaml:"max_backoff" env-default:"1h"`
+	Retention    time.Duration `yaml:"retention" env-default:"168h"` // сколько хранить журнал доставок
+}
+
+// GRPC SongService на отдельном адресе рядом с REST, с теми же ключами и ролями.
+// Reflection нужен grpcurl и подобным клиентам, чтобы обходиться без .proto.
+type GRPC struct {
+	Enabled    bool   `yaml:"enabled" env:"GRPC_ENABLED" env-default:"false"`
+	Address    string `yaml:"address" env:"GRPC_ADDRESS" env-default:"localhost:9090"`
+	Reflection bool   `yaml:"reflection" env-default:"true"`
+}
 
-	return &cfg
+// GraphQL лимиты /graphql. Сложность: каждое поле - 1, списки умножают сложность элемента на first.
+// Поля интроспекции (__schema, __type) в глубину не входят.
+type GraphQL struct {
+	MaxDepth      int  `yaml:"max_depth" env-default:"8"`
+	MaxComplexity int  `yaml:"max_complexity" env-default:"1000"`
+	Introspection bool `yaml:"introspection" env-default:"true"`
 }
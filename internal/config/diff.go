@@ -0,0 +1,87 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// secretFields значения этих полей в Diff не показываются
+var secretFields = map[string]bool{
+	"hmac_secret": true,
+	"password":    true,
+	"api_key":     true,
+}
+
+// Change поле, которое отличается в двух версиях конфига. Field - путь как в YAML (rate_limit.groups.jobs.requests).
+type Change struct {
+	Field string
+	Old   string
+	New   string
+}
+
+// Diff поля, которые отличаются в old и new, в порядке объявления. Ключи карт сравниваются по отдельности.
+func Diff(old *Config, new *Config) []Change {
+	var changes []Change
+	diffValue("", reflect.ValueOf(*old), reflect.ValueOf(*new), false, &changes)
+	return changes
+}
+
+func diffValue(field string, a reflect.Value, b reflect.Value, secret bool, changes *[]Change) {
+	switch a.Kind() {
+	case reflect.Struct:
+		t := a.Type()
+		for i := 0; i < t.NumField(); i++ {
+			name := strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0]
+			if name == "" || name == "-" {
+				continue
+			}
+			diffValue(join(field, name), a.Field(i), b.Field(i), secretFields[name], changes)
+		}
+	case reflect.Map:
+		keys := map[string]reflect.Value{}
+		for _, k := range append(a.MapKeys(), b.MapKeys()...) {
+			keys[fmt.Sprint(k.Interface())] = k
+		}
+		names := make([]string, 0, len(keys))
+		for name := range keys {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		zero := reflect.Zero(a.Type().Elem())
+		for _, name := range names {
+			av, bv := a.MapIndex(keys[name]), b.MapIndex(keys[name])
+			if !av.IsValid() {
+				av = zero
+			}
+			if !bv.IsValid() {
+				bv = zero
+			}
+			diffValue(join(field, name), av, bv, secret, changes)
+		}
+	default:
+		if reflect.DeepEqual(a.Interface(), b.Interface()) {
+			return
+		}
+		*changes = append(*changes, Change{Field: field, Old: show(a, secret), New: show(b, secret)})
+	}
+}
+
+func join(prefix string, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
+
+func show(v reflect.Value, secret bool) string {
+	if secret {
+		if v.IsZero() {
+			return ""
+		}
+		return "***"
+	}
+	return fmt.Sprint(v.Interface())
+}
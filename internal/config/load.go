@@ -0,0 +1,133 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ilyakaznacheev/cleanenv"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// DefaultPath конфиг, если путь не передан ни флагом, ни переменной окружения
+const DefaultPath = "./config/local.yaml"
+
+// Path выбирает путь к конфигу: флаг --config, затем CONFIG_PATH, затем DefaultPath
+func Path(flagValue string) string {
+	if flagValue != "" {
+		return flagValue
+	}
+	if path := os.Getenv("CONFIG_PATH"); path != "" {
+		return path
+	}
+	return DefaultPath
+}
+
+// Load собирает конфиг по слоям, каждый следующий важнее предыдущего:
+// значения по умолчанию из тегов env-default, базовый файл path, файл окружения <env>.yaml
+// рядом с ним, если он есть, и переменные окружения. Итог проверяется Validate.
+func Load(path string) (*Config, error) {
+	const op = "config.Load"
+
+	var cfg Config
+
+	// значения по умолчанию ставятся до файлов, иначе явный false или 0 из файла затирался бы ими
+	if err := cleanenv.ReadEnv(&cfg); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := readFile(path, &cfg, true); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	env := cfg.Env
+	if v, ok := os.LookupEnv("ENV"); ok {
+		env = v
+	}
+	if envPath := EnvPath(path, env); envPath != "" {
+		if err := readFile(envPath, &cfg, false); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := overrideFromEnv(&cfg); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %s: %w", op, path, err)
+	}
+
+	cfg.Path = path
+	return &cfg, nil
+}
+
+// EnvPath файл настроек окружения рядом с базовым: для config/local.yaml и env=prod это config/prod.yaml.
+// Пустая строка, если окружение не задано или файл совпадает с базовым.
+func EnvPath(path string, env string) string {
+	if env == "" {
+		return ""
+	}
+
+	envPath := filepath.Join(filepath.Dir(path), env+filepath.Ext(path))
+	if filepath.Clean(envPath) == filepath.Clean(path) {
+		return ""
+	}
+	return envPath
+}
+
+// readFile накладывает YAML-файл на уже заполненный конфиг. Неизвестные ключи - ошибка,
+// чтобы опечатка в имени настройки не оставляла значение по умолчанию молча.
+func readFile(path string, cfg *Config, required bool) error {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) && !required {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open config file: %w", err)
+	}
+	defer f.Close()
+
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// overrideFromEnv переносит в конфиг поля, для которых задана переменная окружения.
+// Разбор значений остается за cleanenv, поэтому формат переменных тот же, что и раньше.
+func overrideFromEnv(cfg *Config) error {
+	var fromEnv Config
+	if err := cleanenv.ReadEnv(&fromEnv); err != nil {
+		return err
+	}
+
+	copyEnvFields(reflect.ValueOf(cfg).Elem(), reflect.ValueOf(fromEnv))
+	return nil
+}
+
+func copyEnvFields(dst reflect.Value, src reflect.Value) {
+	t := dst.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		if names, ok := field.Tag.Lookup("env"); ok {
+			for _, name := range strings.Split(names, ",") {
+				if _, set := os.LookupEnv(name); set {
+					dst.Field(i).Set(src.Field(i))
+					break
+				}
+			}
+			continue
+		}
+
+		if field.Type.Kind() == reflect.Struct {
+			copyEnvFields(dst.Field(i), src.Field(i))
+		}
+	}
+}
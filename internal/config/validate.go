@@ -0,0 +1,198 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Validate проверяет значения, которые иначе всплыли бы только при первом обращении к ним.
+// Возвращает все найденные ошибки сразу, имена полей - как в YAML.
+func (c *Config) Validate() error {
+	var p problems
+
+	p.oneOf("env", c.Env, EnvLocal, EnvDev, EnvProd)
+
+	p.address("http_server.address", c.HTTPServer.Address)
+	p.positive("http_server.timeout", c.HTTPServer.Timeout)
+	p.positive("http_server.idle_timeout", c.HTTPServer.IdleTimeout)
+	p.positive("http_server.upload_timeout", c.HTTPServer.UploadTimeout)
+	if c.HTTPServer.H2C && (!c.HTTPServer.HTTP2 || c.HTTPServer.TLS.Enabled) {
+		p.add("http_server.h2c", "needs http2 on and tls off")
+	}
+	if tls := c.HTTPServer.TLS; tls.Enabled {
+		if tls.CertFile == "" || tls.KeyFile == "" {
+			p.add("http_server.tls", "cert_file and key_file are required")
+		}
+		p.oneOf("http_server.tls.min_version", tls.MinVersion, "1.2", "1.3")
+		if tls.RequireClientCert && tls.ClientCAFile == "" {
+			p.add("http_server.tls.require_client_cert", "needs client_ca_file")
+		}
+	}
+	if c.HTTPServer.RedirectAddress != "" {
+		p.address("http_server.redirect_address", c.HTTPServer.RedirectAddress)
+		if !c.HTTPServer.TLS.Enabled {
+			p.add("http_server.redirect_address", "redirects to https, so tls must be on")
+		}
+	}
+
+	if c.Log.Level != "" {
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(c.Log.Level)); err != nil {
+			p.add("log.level", "must be debug, info, warn or error, got %q", c.Log.Level)
+		}
+	}
+
+	for _, origin := range c.CORS.AllowedOrigins {
+		if origin == "*" {
+			continue
+		}
+		u, err := url.Parse(origin)
+		if err != nil || u.Scheme == "" || u.Host == "" || u.Path != "" {
+			p.add("cors.allowed_origins", "must be \"*\" or scheme://host[:port], got %q", origin)
+		}
+	}
+	if len(c.CORS.AllowedOrigins) > 0 {
+		p.positive("cors.max_age", c.CORS.MaxAge)
+	}
+
+	p.oneOf("tracing.exporter", c.Tracing.Exporter, "otlp", "stdout", "none")
+	if c.Tracing.Exporter == "otlp" {
+		p.address("tracing.endpoint", c.Tracing.Endpoint)
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		p.add("tracing.sample_ratio", "must be between 0 and 1, got %v", c.Tracing.SampleRatio)
+	}
+
+	if c.Auth.APIKeyHeader == "" {
+		p.add("auth.api_key_header", "must not be empty")
+	}
+
+	p.oneOf("rate_limit.store", c.RateLimit.Store, "memory", "postgres")
+	if c.RateLimit.Store == "postgres" {
+		p.positive("rate_limit.cleanup_interval", c.RateLimit.CleanupInterval)
+	}
+	groups := make([]string, 0, len(c.RateLimit.Groups))
+	for name := range c.RateLimit.Groups {
+		groups = append(groups, name)
+	}
+	sort.Strings(groups)
+	for _, name := range groups {
+		p.rule("rate_limit.groups."+name, c.RateLimit.Groups[name])
+	}
+
+	p.atLeast("jobs.workers", c.Jobs.Workers, 0)
+	p.positive("jobs.poll_interval", c.Jobs.PollInterval)
+	p.positive("jobs.lease", c.Jobs.Lease)
+	p.atLeast("jobs.max_attempts", c.Jobs.MaxAttempts, 1)
+	p.positive("jobs.retry_backoff", c.Jobs.RetryBackoff)
+	p.positive("jobs.max_backoff", c.Jobs.MaxBackoff)
+	p.positive("jobs.stop_timeout", c.Jobs.StopTimeout)
+
+	if c.Enrichment.Enabled {
+		p.positive("enrichment.interval", c.Enrichment.Interval)
+		p.atLeast("enrichment.batch_size", c.Enrichment.BatchSize, 1)
+		p.positive("enrichment.recheck_after", c.Enrichment.RecheckAfter)
+		p.positive("enrichment.retry_after", c.Enrichment.RetryAfter)
+		p.rule("enrichment.rate_limit", c.Enrichment.RateLimit)
+		p.oneOf("enrichment.provider.kind", c.Enrichment.Provider.Kind, "http", "fake")
+		if c.Enrichment.Provider.Kind == "http" && c.Enrichment.Provider.URL == "" {
+			p.add("enrichment.provider.url", "is required for the http provider")
+		}
+		p.positive("enrichment.provider.timeout", c.Enrichment.Provider.Timeout)
+	}
+
+	p.positive("idempotency.ttl", c.Idempotency.TTL)
+	if c.Idempotency.MaxBodyBytes <= 0 {
+		p.add("idempotency.max_body_bytes", "must be positive, got %d", c.Idempotency.MaxBodyBytes)
+	}
+	p.positive("idempotency.cleanup_interval", c.Idempotency.CleanupInterval)
+
+	if c.Cache.Enabled {
+		p.atLeast("cache.size", c.Cache.Size, 1)
+		p.positive("cache.ttl", c.Cache.TTL)
+		if c.Cache.Redis.Addr != "" {
+			p.address("cache.redis.addr", c.Cache.Redis.Addr)
+			p.positive("cache.redis.ttl", c.Cache.Redis.TTL)
+		}
+	}
+
+	p.atLeast("events.log_size", c.Events.LogSize, 1)
+	p.positive("events.retention", c.Events.Retention)
+	p.positive("events.trim_interval", c.Events.TrimInterval)
+	p.positive("events.poll_interval", c.Events.PollInterval)
+	p.positive("events.gap_timeout", c.Events.GapTimeout)
+	p.positive("events.heartbeat", c.Events.Heartbeat)
+	p.atLeast("events.buffer", c.Events.Buffer, 1)
+
+	p.atLeast("webhooks.workers", c.Webhooks.Workers, 0)
+	p.positive("webhooks.poll_interval", c.Webhooks.PollInterval)
+	p.atLeast("webhooks.batch_size", c.Webhooks.BatchSize, 1)
+	p.positive("webhooks.timeout", c.Webhooks.Timeout)
+	p.positive("webhooks.lease", c.Webhooks.Lease)
+	p.atLeast("webhooks.max_attempts", c.Webhooks.MaxAttempts, 1)
+	p.positive("webhooks.retry_backoff", c.Webhooks.RetryBackoff)
+	p.positive("webhooks.max_backoff", c.Webhooks.MaxBackoff)
+	p.positive("webhooks.retention", c.Webhooks.Retention)
+
+	if c.GRPC.Enabled {
+		p.address("grpc.address", c.GRPC.Address)
+	}
+
+	p.atLeast("graphql.max_depth", c.GraphQL.MaxDepth, 1)
+	p.atLeast("graphql.max_complexity", c.GraphQL.MaxComplexity, 1)
+
+	return errors.Join(p...)
+}
+
+type problems []error
+
+func (p *problems) add(field string, format string, args ...any) {
+	*p = append(*p, fmt.Errorf("%s: %s", field, fmt.Sprintf(format, args...)))
+}
+
+func (p *problems) positive(field string, d time.Duration) {
+	if d <= 0 {
+		p.add(field, "must be a positive duration, got %s", d)
+	}
+}
+
+func (p *problems) atLeast(field string, n int, min int) {
+	if n < min {
+		p.add(field, "must be at least %d, got %d", min, n)
+	}
+}
+
+func (p *problems) oneOf(field string, value string, allowed ...string) {
+	if !slices.Contains(allowed, value) {
+		p.add(field, "must be one of %s, got %q", strings.Join(allowed, ", "), value)
+	}
+}
+
+// rule лимит без запросов и окна означает "без ограничения", остальное должно быть задано полностью
+func (p *problems) rule(field string, rule RateLimitRule) {
+	if rule == (RateLimitRule{}) {
+		return
+	}
+	p.atLeast(field+".requests", rule.Requests, 1)
+	p.positive(field+".window", rule.Window)
+}
+
+// address адрес вида host:port, хост может быть пустым (:8080), порт - число
+func (p *problems) address(field string, addr string) {
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		p.add(field, "must be host:port, got %q", addr)
+		return
+	}
+	if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+		p.add(field, "port must be a number from 0 to 65535, got %q", port)
+	}
+}
@@ -0,0 +1,65 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// depthLimit отклоняет операции, в которых поля вложены глубже max. Лимит читается на каждую операцию.
+type depthLimit struct {
+	max func() int
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.OperationContextMutator
+} = depthLimit{}
+
+func (depthLimit) ExtensionName() string {
+	return "DepthLimit"
+}
+
+func (d depthLimit) Validate(graphql.ExecutableSchema) error {
+	if d.max == nil {
+		return fmt.Errorf("depth limit func can not be nil")
+	}
+	return nil
+}
+
+func (d depthLimit) MutateOperationContext(ctx context.Context, rc *graphql.OperationContext) *gqlerror.Error {
+	limit := d.max()
+	if depth := selectionDepth(rc.Operation.SelectionSet); depth > limit {
+		err := gqlerror.Errorf("operation has depth %d, which exceeds the limit of %d", depth, limit)
+		err.Extensions = map[string]any{"code": codeDepthLimit}
+		return err
+	}
+	return nil
+}
+
+// selectionDepth глубина вложенности полей с учетом фрагментов. Циклы во фрагментах
+// сюда не доходят: их отклоняет валидация запроса.
+func selectionDepth(set ast.SelectionSet) int {
+	depth := 0
+	for _, sel := range set {
+		switch sel := sel.(type) {
+		case *ast.Field:
+			// интроспекция глубокая сама по себе и данных не читает
+			if strings.HasPrefix(sel.Name, "__") {
+				continue
+			}
+			depth = max(depth, 1+selectionDepth(sel.SelectionSet))
+		case *ast.InlineFragment:
+			depth = max(depth, selectionDepth(sel.SelectionSet))
+		case *ast.FragmentSpread:
+			if sel.Definition != nil {
+				depth = max(depth, selectionDepth(sel.Definition.SelectionSet))
+			}
+		}
+	}
+	return depth
+}
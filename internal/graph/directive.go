@@ -0,0 +1,24 @@
+package graph
+
+import (
+	"RestApi_v1/internal/config/internal/graph/model"
+	"RestApi_v1/internal/config/internal/lib/auth"
+	"RestApi_v1/internal/config/internal/lib/i18n"
+	"context"
+	"strings"
+
+	"github.com/99designs/gqlgen/graphql"
+)
+
+// hasRole пускает к полю только пользователей с ролью не ниже role, как mwAuth.Require в REST
+func hasRole(ctx context.Context, _ any, next graphql.Resolver, role model.Role) (any, error) {
+	principal, ok := auth.PrincipalFromContext(ctx)
+	if !ok {
+		return nil, newError(ctx, codeUnauthenticated, i18n.MsgUnauthorized)
+	}
+	if !principal.Role.Allows(auth.Role(strings.ToLower(role.String()))) {
+		return nil, newError(ctx, codeForbidden, i18n.MsgForbidden)
+	}
+
+	return next(ctx)
+}
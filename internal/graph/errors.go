@@ -0,0 +1,86 @@
+package graph
+
+import (
+	"RestApi_v1/internal/config/internal/lib/i18n"
+	"RestApi_v1/internal/config/internal/lib/logger/sl"
+	"RestApi_v1/internal/config/internal/storage"
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/go-playground/validator/v10"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// Коды ошибок в extensions.code
+const (
+	codeBadUserInput    = "BAD_USER_INPUT"
+	codeUnauthenticated = "UNAUTHENTICATED"
+	codeForbidden       = "FORBIDDEN"
+	codeNotFound        = "NOT_FOUND"
+	codeAlreadyExists   = "ALREADY_EXISTS"
+	codeDepthLimit      = "DEPTH_LIMIT_EXCEEDED"
+	codeInternal        = "INTERNAL_SERVER_ERROR"
+)
+
+type translatorKey struct{}
+
+func withTranslator(ctx context.Context, t *i18n.Translator) context.Context {
+	return context.WithValue(ctx, translatorKey{}, t)
+}
+
+// translator язык клиента из Accept-Language запроса
+func translator(ctx context.Context) *i18n.Translator {
+	if t, ok := ctx.Value(translatorKey{}).(*i18n.Translator); ok {
+		return t
+	}
+	return i18n.Get(i18n.DefaultLang)
+}
+
+func newError(ctx context.Context, code string, msg string, params ...string) *gqlerror.Error {
+	return &gqlerror.Error{
+		Path:       graphql.GetPath(ctx),
+		Message:    translator(ctx).T(msg, params...),
+		Extensions: map[string]any{"code": code},
+	}
+}
+
+// validationError ошибки валидации с переводом, по полю входных данных на каждую
+func validationError(ctx context.Context, err error) *gqlerror.Error {
+	t := translator(ctx)
+
+	var validateErrs validator.ValidationErrors
+	if !errors.As(err, &validateErrs) {
+		return newError(ctx, codeInternal, i18n.MsgInternalError)
+	}
+
+	fields := make(map[string]string, len(validateErrs))
+	for _, fe := range validateErrs {
+		fields[fe.Field()] = fe.Translate(t.Validation())
+	}
+
+	gqlErr := newError(ctx, codeBadUserInput, i18n.MsgValidationError)
+	gqlErr.Extensions["fields"] = fields
+	return gqlErr
+}
+
+// badInput ошибка одного аргумента, который не разобрался
+func badInput(ctx context.Context, field string, err error) *gqlerror.Error {
+	gqlErr := newError(ctx, codeBadUserInput, i18n.MsgInvalidRequest)
+	gqlErr.Extensions["fields"] = map[string]string{field: err.Error()}
+	return gqlErr
+}
+
+// storageError переводит ошибки хранилища в коды, как REST в HTTP-статусы и gRPC в свои коды
+func storageError(ctx context.Context, log *slog.Logger, msg string, err error) *gqlerror.Error {
+	switch {
+	case errors.Is(err, storage.ErrSongNotFound):
+		return newError(ctx, codeNotFound, i18n.MsgNotFound)
+	case errors.Is(err, storage.ErrSongExist):
+		return newError(ctx, codeAlreadyExists, i18n.MsgSongExists)
+	default:
+		log.ErrorContext(ctx, msg, sl.Err(err))
+		return newError(ctx, codeInternal, i18n.MsgInternalError)
+	}
+}
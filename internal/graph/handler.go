@@ -0,0 +1,88 @@
+package graph
+
+import (
+	"RestApi_v1/internal/config/internal/config"
+	"RestApi_v1/internal/config/internal/graph/model"
+	"RestApi_v1/internal/config/internal/lib/i18n"
+	"RestApi_v1/internal/config/internal/lib/songcache"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/99designs/gqlgen/graphql/handler/lru"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+)
+
+// queryCacheSize сколько разобранных запросов держать в памяти
+const queryCacheSize = 1000
+
+// NewHandler обработчик /graphql: запросы GET и POST, лимиты глубины и сложности из cfg.
+// cfg читается на каждую операцию, поэтому лимиты и интроспекция меняются при перезагрузке конфига.
+// Пользователя в контекст кладет общий middleware аутентификации, роли проверяет директива @hasRole.
+func NewHandler(log *slog.Logger, songs songcache.Store, store Store, cfg func() config.GraphQL) http.Handler {
+	log = log.With(slog.String("component", "graph"))
+
+	schema := NewExecutableSchema(Config{
+		Resolvers:  &Resolver{log: log, songs: songs, store: store},
+		Directives: DirectiveRoot{HasRole: hasRole},
+		Complexity: complexity(),
+	})
+
+	srv := handler.New(schema)
+	srv.AddTransport(transport.Options{})
+	srv.AddTransport(transport.GET{})
+	srv.AddTransport(transport.POST{})
+	srv.SetQueryCache(lru.New(queryCacheSize))
+
+	srv.Use(introspectionSwitch{enabled: func() bool { return cfg().Introspection }})
+	srv.Use(depthLimit{max: func() int { return cfg().MaxDepth }})
+	// превышение отдается с extensions.code COMPLEXITY_LIMIT_EXCEEDED
+	srv.Use(&extension.ComplexityLimit{Func: func(context.Context, *graphql.OperationContext) int {
+		return cfg().MaxComplexity
+	}})
+
+	srv.SetRecoverFunc(func(ctx context.Context, rec any) error {
+		log.ErrorContext(ctx, "panic in resolver",
+			slog.String("panic", fmt.Sprint(rec)),
+			slog.String("stack", string(debug.Stack())),
+		)
+		return newError(ctx, codeInternal, i18n.MsgInternalError)
+	})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := withLoaders(r.Context(), newLoaders(store))
+		ctx = withTranslator(ctx, i18n.FromRequest(r))
+
+		srv.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// complexity списки стоят как first элементов, остальные поля - по умолчанию, 1 плюс вложенные
+func complexity() ComplexityRoot {
+	var c ComplexityRoot
+
+	list := func(childComplexity int, first *int, def int) int {
+		n := def
+		if first != nil && *first > 0 {
+			n = *first
+		}
+		return 1 + n*childComplexity
+	}
+
+	c.Query.Songs = func(childComplexity int, _ *model.SongFilter, first *int, _ *string) int {
+		return list(childComplexity, first, defaultPageSize)
+	}
+	c.Query.Search = func(childComplexity int, _ string, first *int, _ *string) int {
+		return list(childComplexity, first, defaultPageSize)
+	}
+	c.Group.Songs = func(childComplexity int, first *int) int {
+		return list(childComplexity, first, 10)
+	}
+
+	return c
+}
@@ -0,0 +1,31 @@
+package graph
+
+import (
+	"context"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// introspectionSwitch как extension.Introspection, но включается и выключается без пересборки обработчика
+type introspectionSwitch struct {
+	enabled func() bool
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.OperationContextMutator
+} = introspectionSwitch{}
+
+func (introspectionSwitch) ExtensionName() string {
+	return "Introspection"
+}
+
+func (introspectionSwitch) Validate(graphql.ExecutableSchema) error {
+	return nil
+}
+
+func (i introspectionSwitch) MutateOperationContext(_ context.Context, rc *graphql.OperationContext) *gqlerror.Error {
+	rc.DisableIntrospection = !i.enabled()
+	return nil
+}
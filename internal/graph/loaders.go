@@ -0,0 +1,98 @@
+package graph
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"RestApi_v1/internal/config/internal/models"
+
+	"github.com/vikstrous/dataloadgen"
+	"go.opentelemetry.io/otel"
+)
+
+const tracerName = "RestApi_v1/graph"
+
+// loaderWait сколько загрузчик копит ключи от параллельных резолверов перед запросом в базу
+const loaderWait = 2 * time.Millisecond
+
+// groupKey песни группы: name в нижнем регистре, first - сколько песен нужно
+type groupKey struct {
+	name  string
+	first int
+}
+
+// loaders собирают обращения резолверов за один запрос в пачки, чтобы список песен
+// с группами не превращался в N+1 запросов. Живут один HTTP-запрос.
+type loaders struct {
+	songByID   *dataloadgen.Loader[int64, *models.Song]
+	groupSongs *dataloadgen.Loader[groupKey, []models.Song]
+}
+
+func newLoaders(store Store) *loaders {
+	tracer := otel.Tracer(tracerName)
+
+	return &loaders{
+		songByID: dataloadgen.NewLoader(func(ctx context.Context, ids []int64) ([]*models.Song, []error) {
+			songs, err := store.SongsByIDs(ctx, ids)
+			if err != nil {
+				return nil, []error{err}
+			}
+
+			byID := make(map[int64]*models.Song, len(songs))
+			for i := range songs {
+				byID[songs[i].ID] = &songs[i]
+			}
+
+			res := make([]*models.Song, len(ids))
+			for i, id := range ids {
+				res[i] = byID[id]
+			}
+			return res, nil
+		}, dataloadgen.WithWait(loaderWait), dataloadgen.WithTracer(tracer)),
+
+		groupSongs: dataloadgen.NewLoader(func(ctx context.Context, keys []groupKey) ([][]models.Song, []error) {
+			// одна выборка на все группы с наибольшим first, лишнее отрезаем по ключам
+			var (
+				names    []string
+				perGroup int
+				seen     = make(map[string]bool, len(keys))
+			)
+			for _, k := range keys {
+				if !seen[k.name] {
+					seen[k.name] = true
+					names = append(names, k.name)
+				}
+				perGroup = max(perGroup, k.first)
+			}
+
+			songs, err := store.SongsByGroups(ctx, names, perGroup)
+			if err != nil {
+				return nil, []error{err}
+			}
+
+			byGroup := make(map[string][]models.Song, len(names))
+			for _, song := range songs {
+				name := strings.ToLower(song.Group)
+				byGroup[name] = append(byGroup[name], song)
+			}
+
+			res := make([][]models.Song, len(keys))
+			for i, k := range keys {
+				group := byGroup[k.name]
+				res[i] = group[:min(len(group), k.first)]
+			}
+			return res, nil
+		}, dataloadgen.WithWait(loaderWait), dataloadgen.WithTracer(tracer)),
+	}
+}
+
+type loadersKey struct{}
+
+func withLoaders(ctx context.Context, l *loaders) context.Context {
+	return context.WithValue(ctx, loadersKey{}, l)
+}
+
+func loadersFrom(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
@@ -0,0 +1,6 @@
+package model
+
+// Group группа песен; в базе это колонка nameGroup, отдельной таблицы нет
+type Group struct {
+	Name string `json:"name"`
+}
@@ -0,0 +1,84 @@
+// Code generated by github.com/99designs/gqlgen, DO NOT EDIT.
+
+package model
+
+import (
+	"RestApi_v1/internal/config/internal/models"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+type Mutation struct {
+}
+
+type PageInfo struct {
+	EndCursor   *string `json:"endCursor,omitempty"`
+	HasNextPage bool    `json:"hasNextPage"`
+}
+
+type Query struct {
+}
+
+type SongConnection struct {
+	Nodes    []models.Song `json:"nodes"`
+	PageInfo *PageInfo     `json:"pageInfo"`
+}
+
+type SongFilter struct {
+	Group        *string `json:"group,omitempty"`
+	Title        *string `json:"title,omitempty"`
+	ReleasedFrom *string `json:"releasedFrom,omitempty"`
+	ReleasedTo   *string `json:"releasedTo,omitempty"`
+}
+
+type SongInput struct {
+	Title       string  `json:"title"`
+	Group       *string `json:"group,omitempty"`
+	Text        *string `json:"text,omitempty"`
+	ReleaseDate *string `json:"releaseDate,omitempty"`
+	Link        *string `json:"link,omitempty"`
+}
+
+type Role string
+
+const (
+	RoleReader Role = "READER"
+	RoleEditor Role = "EDITOR"
+	RoleAdmin  Role = "ADMIN"
+)
+
+var AllRole = []Role{
+	RoleReader,
+	RoleEditor,
+	RoleAdmin,
+}
+
+func (e Role) IsValid() bool {
+	switch e {
+	case RoleReader, RoleEditor, RoleAdmin:
+		return true
+	}
+	return false
+}
+
+func (e Role) String() string {
+	return string(e)
+}
+
+func (e *Role) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = Role(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid Role", str)
+	}
+	return nil
+}
+
+func (e Role) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
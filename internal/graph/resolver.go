@@ -0,0 +1,146 @@
+package graph
+
+import (
+	"RestApi_v1/internal/config/internal/graph/model"
+	"RestApi_v1/internal/config/internal/lib/songcache"
+	"RestApi_v1/internal/config/internal/lib/validate"
+	"RestApi_v1/internal/config/internal/models"
+	"RestApi_v1/internal/config/internal/storage"
+	"context"
+	"errors"
+	"log/slog"
+	"strconv"
+	"time"
+)
+
+// Store чтение песен пачками для загрузчиков и постраничные выборки
+type Store interface {
+	SongsByIDs(ctx context.Context, ids []int64) ([]models.Song, error)
+	SongsByGroups(ctx context.Context, groups []string, perGroup int) ([]models.Song, error)
+	ListSongs(ctx context.Context, filter storage.SongFilter, limit int) ([]models.Song, error)
+}
+
+// Resolver изменения идут через songs, как у REST-хендлеров, чтобы кеш песен оставался согласованным
+type Resolver struct {
+	log   *slog.Logger
+	songs songcache.Store
+	store Store
+}
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 500
+)
+
+// songFields ограничения те же, что у save.Request и updateSong.Request, имена полей - из схемы
+type songFields struct {
+	Title       string `json:"title" validate:"required,max=100"`
+	Group       string `json:"group" validate:"omitempty,max=50"`
+	Text        string `json:"text"`
+	ReleaseDate string `json:"releaseDate" validate:"omitempty,songdate"`
+	Link        string `json:"link" validate:"omitempty,url,max=255"`
+}
+
+// songFieldsOf проверяет ввод и приводит дату к 2006-01-02
+func songFieldsOf(ctx context.Context, input model.SongInput) (songFields, error) {
+	fields := songFields{
+		Title:       input.Title,
+		Group:       deref(input.Group),
+		Text:        deref(input.Text),
+		ReleaseDate: deref(input.ReleaseDate),
+		Link:        deref(input.Link),
+	}
+	if err := validate.Struct(fields); err != nil {
+		return songFields{}, validationError(ctx, err)
+	}
+
+	fields.ReleaseDate, _ = validate.NormalizeDate(fields.ReleaseDate)
+	return fields, nil
+}
+
+// reload читает песню после изменения мимо уже загруженной в этом запросе версии
+func (r *Resolver) reload(ctx context.Context, log *slog.Logger, id int64) (*models.Song, error) {
+	l := loadersFrom(ctx)
+	l.songByID.Clear(id)
+
+	song, err := l.songByID.Load(ctx, id)
+	if err != nil {
+		return nil, storageError(ctx, log, "failed to load song", err)
+	}
+	if song == nil {
+		// песню успели удалить между изменением и чтением
+		return nil, storageError(ctx, log, "failed to load song", storage.ErrSongNotFound)
+	}
+	return song, nil
+}
+
+// page страница песен по фильтру. Курсор - id последней песни предыдущей страницы, как page_token в gRPC.
+func (r *Resolver) page(ctx context.Context, filter storage.SongFilter, first *int, after *string) (*model.SongConnection, error) {
+	limit := defaultPageSize
+	if first != nil {
+		limit = *first
+	}
+	if limit < 1 || limit > maxPageSize {
+		return nil, badInput(ctx, "first", errors.New("first must be between 1 and 500"))
+	}
+
+	if cursor := deref(after); cursor != "" {
+		afterID, err := strconv.ParseInt(cursor, 10, 64)
+		if err != nil || afterID < 1 {
+			return nil, badInput(ctx, "after", errors.New("invalid cursor"))
+		}
+		filter.AfterID = afterID
+	}
+
+	// на одну больше, чтобы знать, есть ли следующая страница
+	songs, err := r.store.ListSongs(ctx, filter, limit+1)
+	if err != nil {
+		return nil, storageError(ctx, r.log, "failed to list songs", err)
+	}
+
+	conn := &model.SongConnection{PageInfo: &model.PageInfo{}}
+	if len(songs) > limit {
+		songs = songs[:limit]
+		conn.PageInfo.HasNextPage = true
+	}
+	if len(songs) > 0 {
+		cursor := strconv.FormatInt(songs[len(songs)-1].ID, 10)
+		conn.PageInfo.EndCursor = &cursor
+	}
+
+	// песни страницы уже прочитаны, Song по тем же id в этом запросе не пойдет в базу
+	l := loadersFrom(ctx)
+	for i := range songs {
+		l.songByID.Prime(songs[i].ID, &songs[i])
+	}
+
+	conn.Nodes = songs
+	if conn.Nodes == nil {
+		conn.Nodes = []models.Song{}
+	}
+	return conn, nil
+}
+
+func (r *Resolver) logger(op string) *slog.Logger {
+	return r.log.With(
+		slog.String("op", op),
+	)
+}
+
+func parseDate(s string) (*time.Time, error) {
+	if s == "" {
+		return nil, nil
+	}
+	t, err := validate.ParseDate(s)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
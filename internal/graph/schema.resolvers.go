@@ -0,0 +1,160 @@
+package graph
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.49
+
+import (
+	"RestApi_v1/internal/config/internal/graph/model"
+	"RestApi_v1/internal/config/internal/lib/validate"
+	"RestApi_v1/internal/config/internal/models"
+	"RestApi_v1/internal/config/internal/storage"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+)
+
+// Songs is the resolver for the songs field.
+func (r *groupResolver) Songs(ctx context.Context, obj *model.Group, first *int) ([]models.Song, error) {
+	limit := 10
+	if first != nil {
+		limit = *first
+	}
+	if limit < 1 || limit > maxPageSize {
+		return nil, badInput(ctx, "first", errors.New("first must be between 1 and 500"))
+	}
+
+	songs, err := loadersFrom(ctx).groupSongs.Load(ctx, groupKey{name: strings.ToLower(obj.Name), first: limit})
+	if err != nil {
+		return nil, storageError(ctx, r.log, "failed to load group songs", err)
+	}
+	return songs, nil
+}
+
+// SaveSong is the resolver for the saveSong field.
+func (r *mutationResolver) SaveSong(ctx context.Context, input model.SongInput) (*models.Song, error) {
+	const op = "graph.SaveSong"
+
+	log := r.logger(op)
+
+	fields, err := songFieldsOf(ctx, input)
+	if err != nil {
+		log.InfoContext(ctx, "invalid input", slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	id, err := r.songs.SaveSong(ctx, fields.Title, fields.Group, fields.Text, fields.ReleaseDate, fields.Link)
+	if err != nil {
+		return nil, storageError(ctx, log, "failed to add song", err)
+	}
+
+	log.InfoContext(ctx, "song added", slog.Int64("id", id))
+	return r.reload(ctx, log, id)
+}
+
+// UpdateSong is the resolver for the updateSong field.
+func (r *mutationResolver) UpdateSong(ctx context.Context, id int64, input model.SongInput) (*models.Song, error) {
+	const op = "graph.UpdateSong"
+
+	log := r.logger(op)
+
+	fields, err := songFieldsOf(ctx, input)
+	if err != nil {
+		log.InfoContext(ctx, "invalid input", slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	_, err = r.songs.UpdateSong(ctx, int(id), fields.Title, fields.Group, fields.Text, fields.ReleaseDate, fields.Link)
+	if err != nil {
+		return nil, storageError(ctx, log, "failed to update song", err)
+	}
+
+	log.InfoContext(ctx, "song updated", slog.Int64("id", id))
+	return r.reload(ctx, log, id)
+}
+
+// DeleteSong is the resolver for the deleteSong field.
+func (r *mutationResolver) DeleteSong(ctx context.Context, title string) (bool, error) {
+	const op = "graph.DeleteSong"
+
+	log := r.logger(op)
+
+	if title == "" {
+		return false, badInput(ctx, "title", errors.New("title is empty"))
+	}
+
+	if _, err := r.songs.DeleteSong(ctx, title); err != nil {
+		return false, storageError(ctx, log, "failed to delete song", err)
+	}
+
+	log.InfoContext(ctx, "song deleted", slog.String("song", title))
+	return true, nil
+}
+
+// Song is the resolver for the song field.
+func (r *queryResolver) Song(ctx context.Context, id int64) (*models.Song, error) {
+	song, err := loadersFrom(ctx).songByID.Load(ctx, id)
+	if err != nil {
+		return nil, storageError(ctx, r.log, "failed to load song", err)
+	}
+	return song, nil
+}
+
+// Songs is the resolver for the songs field.
+func (r *queryResolver) Songs(ctx context.Context, filter *model.SongFilter, first *int, after *string) (*model.SongConnection, error) {
+	var songFilter storage.SongFilter
+	if filter != nil {
+		songFilter.Group = deref(filter.Group)
+		songFilter.Song = deref(filter.Title)
+
+		var err error
+		if songFilter.ReleasedFrom, err = parseDate(deref(filter.ReleasedFrom)); err != nil {
+			return nil, badInput(ctx, "releasedFrom", err)
+		}
+		if songFilter.ReleasedTo, err = parseDate(deref(filter.ReleasedTo)); err != nil {
+			return nil, badInput(ctx, "releasedTo", err)
+		}
+	}
+
+	return r.page(ctx, songFilter, first, after)
+}
+
+// Search is the resolver for the search field.
+func (r *queryResolver) Search(ctx context.Context, query string, first *int, after *string) (*model.SongConnection, error) {
+	if query == "" {
+		return nil, badInput(ctx, "query", errors.New("query is empty"))
+	}
+
+	return r.page(ctx, storage.SongFilter{Query: query}, first, after)
+}
+
+// Group is the resolver for the group field.
+func (r *songResolver) Group(ctx context.Context, obj *models.Song) (*model.Group, error) {
+	if obj.Group == "" {
+		return nil, nil
+	}
+	return &model.Group{Name: obj.Group}, nil
+}
+
+// ReleaseDate is the resolver for the releaseDate field.
+func (r *songResolver) ReleaseDate(ctx context.Context, obj *models.Song) (*string, error) {
+	if !obj.ReleaseDate.Valid {
+		return nil, nil
+	}
+	date := obj.ReleaseDate.Time.Format(validate.DateLayoutISO)
+	return &date, nil
+}
+
+func (r *Resolver) Group() GroupResolver { return &groupResolver{r} }
+
+func (r *Resolver) Mutation() MutationResolver { return &mutationResolver{r} }
+
+func (r *Resolver) Query() QueryResolver { return &queryResolver{r} }
+
+func (r *Resolver) Song() SongResolver { return &songResolver{r} }
+
+type groupResolver struct{ *Resolver }
+type mutationResolver struct{ *Resolver }
+type queryResolver struct{ *Resolver }
+type songResolver struct{ *Resolver }
@@ -0,0 +1,80 @@
+package interceptor
+
+import (
+	"RestApi_v1/internal/config/internal/lib/auth"
+	"RestApi_v1/internal/config/internal/lib/logger/sl"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Auth аутентифицирует вызов по API-ключу или bearer-токену из метаданных, как middleware/auth в REST.
+// roles - минимальная роль по полному имени метода; методы без записи доступны и анонимно.
+func Auth(log *slog.Logger, header string, authenticator *auth.Authenticator, roles map[string]auth.Role) grpc.UnaryServerInterceptor {
+	log = log.With(
+		slog.String("component", "grpc/auth"),
+	)
+	// ключи метаданных gRPC всегда в нижнем регистре
+	header = strings.ToLower(header)
+
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		log := log.With(
+			slog.String("method", info.FullMethod),
+		)
+
+		md, _ := metadata.FromIncomingContext(ctx)
+
+		var (
+			principal auth.Principal
+			err       error
+			found     = true
+		)
+
+		switch {
+		case first(md, header) != "":
+			principal, err = authenticator.APIKey(ctx, first(md, header))
+		case strings.HasPrefix(first(md, "authorization"), "Bearer "):
+			principal, err = authenticator.Bearer(strings.TrimPrefix(first(md, "authorization"), "Bearer "))
+		default:
+			found = false
+		}
+
+		if errors.Is(err, auth.ErrInvalidCredentials) {
+			log.InfoContext(ctx, "authentication failed", sl.Err(err))
+			return nil, status.Error(codes.Unauthenticated, "unauthorized")
+		}
+		if err != nil {
+			log.ErrorContext(ctx, "failed to authenticate request", sl.Err(err))
+			return nil, status.Error(codes.Internal, "internal server error")
+		}
+		if found {
+			ctx = auth.WithPrincipal(ctx, principal)
+		}
+
+		required, ok := roles[info.FullMethod]
+		if !ok {
+			return handler(ctx, req)
+		}
+		if !found {
+			return nil, status.Error(codes.Unauthenticated, "unauthorized")
+		}
+		if !principal.Role.Allows(required) {
+			return nil, status.Error(codes.PermissionDenied, "forbidden")
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+func first(md metadata.MD, key string) string {
+	if v := md.Get(key); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
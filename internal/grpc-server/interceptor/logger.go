@@ -0,0 +1,80 @@
+package interceptor
+
+import (
+	"RestApi_v1/internal/config/internal/lib/auth"
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// Logger пишет в лог каждый вызов с кодом ответа и длительностью
+func Logger(log *slog.Logger) grpc.UnaryServerInterceptor {
+	log = log.With(
+		slog.String("component", "grpc/logger"),
+	)
+
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		entry := log.With(
+			slog.String("method", info.FullMethod),
+		)
+		if p, ok := peer.FromContext(ctx); ok {
+			entry = entry.With(slog.String("remote_addr", p.Addr.String()))
+		}
+		// как и в REST, логгер стоит до аутентификации и узнает пользователя после вызова
+		ctx, principal := auth.WithPrincipalSlot(ctx)
+
+		t1 := time.Now()
+		res, err := handler(ctx, req)
+
+		if p, ok := principal(); ok {
+			entry = entry.With(
+				slog.String("principal", p.String()),
+				slog.String("role", string(p.Role)),
+			)
+		}
+		entry.InfoContext(ctx, "request completed",
+			slog.String("code", status.Code(err).String()),
+			slog.String("duration", time.Since(t1).String()),
+		)
+
+		return res, err
+	}
+}
+
+// Recovery превращает панику в хендлере в ответ Internal, как middleware.Recoverer в REST
+func Recovery(log *slog.Logger) grpc.UnaryServerInterceptor {
+	log = log.With(
+		slog.String("component", "grpc/recovery"),
+	)
+
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (res any, err error) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				log.ErrorContext(ctx, "panic in grpc handler",
+					slog.String("method", info.FullMethod),
+					slog.String("panic", fmt.Sprint(rec)),
+					slog.String("stack", string(debug.Stack())),
+				)
+				err = status.Error(codes.Internal, "internal server error")
+			}
+		}()
+
+		return handler(ctx, req)
+	}
+}
+
+// serverError коды, которые означают сбой сервера, а не ошибку клиента
+func serverError(code codes.Code) bool {
+	switch code {
+	case codes.Unknown, codes.DeadlineExceeded, codes.Unimplemented, codes.Internal, codes.Unavailable, codes.DataLoss:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,111 @@
+package interceptor
+
+import (
+	"RestApi_v1/internal/config/internal/lib/auth"
+	"RestApi_v1/internal/config/internal/lib/i18n"
+	"RestApi_v1/internal/config/internal/lib/logger/sl"
+	"RestApi_v1/internal/config/internal/lib/ratelimit"
+	"context"
+	"log/slog"
+	"math"
+	"net"
+	"strconv"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// RateLimit ограничивает частоту вызовов, как middleware/ratelimit в REST: те же группы и бакеты,
+// поэтому клиент делит лимит между REST и gRPC. groups - группа по полному имени метода,
+// методы без группы не ограничиваются. Правило берется на каждый вызов, чтобы лимиты менялись при перезагрузке конфига.
+func RateLimit(log *slog.Logger, store ratelimit.Store, groups map[string]string, rules func(group string) ratelimit.Rule) grpc.UnaryServerInterceptor {
+	log = log.With(
+		slog.String("component", "grpc/ratelimit"),
+	)
+
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		group, ok := groups[info.FullMethod]
+		if !ok {
+			return handler(ctx, req)
+		}
+		rule := rules(group)
+		// лимит для группы не задан
+		if rule.Requests <= 0 || rule.Window <= 0 {
+			return handler(ctx, req)
+		}
+
+		key := group + ":" + clientKey(ctx)
+
+		res, err := store.Take(ctx, key, rule)
+		if err != nil {
+			// хранилище лимитов недоступно - пропускаем вызов, а не роняем API
+			log.ErrorContext(ctx, "failed to check rate limit",
+				slog.String("group", group),
+				sl.Err(err),
+			)
+			return handler(ctx, req)
+		}
+
+		header := metadata.Pairs(
+			"ratelimit-limit", strconv.Itoa(res.Limit),
+			"ratelimit-remaining", strconv.Itoa(res.Remaining),
+			"ratelimit-reset", ceilSeconds(res.Reset),
+			"ratelimit-policy", strconv.Itoa(rule.Requests)+";w="+ceilSeconds(rule.Window),
+		)
+
+		if !res.Allowed {
+			log.InfoContext(ctx, "rate limit exceeded",
+				slog.String("group", group),
+				slog.String("key", key),
+			)
+			header.Set("retry-after", ceilSeconds(res.RetryAfter))
+			_ = grpc.SetHeader(ctx, header)
+			return nil, rateLimited(ctx, res.RetryAfter)
+		}
+
+		_ = grpc.SetHeader(ctx, header)
+		return handler(ctx, req)
+	}
+}
+
+// rateLimited ответ ResourceExhausted с переводом и RetryInfo, по которому клиент может подождать
+func rateLimited(ctx context.Context, retryAfter time.Duration) error {
+	lang := i18n.DefaultLang
+	md, _ := metadata.FromIncomingContext(ctx)
+	if langs := md.Get("accept-language"); len(langs) > 0 {
+		lang = i18n.Match(langs[0])
+	}
+
+	st := status.New(codes.ResourceExhausted, i18n.Get(lang).T(i18n.MsgRateLimited, ceilSeconds(retryAfter)))
+	if withDetails, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(retryAfter)}); err == nil {
+		st = withDetails
+	}
+	return st.Err()
+}
+
+// clientKey тот же ключ, что в REST: пользователь, для анонимных вызовов - IP клиента
+func clientKey(ctx context.Context) string {
+	if principal, ok := auth.PrincipalFromContext(ctx); ok {
+		return principal.String()
+	}
+
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return "ip:unknown"
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		host = p.Addr.String()
+	}
+	return "ip:" + host
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
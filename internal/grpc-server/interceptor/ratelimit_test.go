@@ -0,0 +1,138 @@
+package interceptor
+
+import (
+	"RestApi_v1/internal/config/internal/lib/auth"
+	"RestApi_v1/internal/config/internal/lib/ratelimit"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	limitedMethod = "/song.v1.SongService/Create"
+	freeMethod    = "/grpc.health.v1.Health/Check"
+)
+
+type failingStore struct{}
+
+func (failingStore) Take(context.Context, string, ratelimit.Rule) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("db is down")
+}
+
+func fromPeer(ip string) context.Context {
+	return peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(ip), Port: 50000}})
+}
+
+func TestRateLimit(t *testing.T) {
+	alice := auth.WithPrincipal(fromPeer("10.0.0.1"), auth.Principal{Subject: "alice", Role: auth.RoleEditor, Method: "key"})
+
+	tests := []struct {
+		name   string
+		store  ratelimit.Store
+		rule   ratelimit.Rule
+		method string
+		calls  []context.Context
+		want   []codes.Code
+	}{
+		{
+			name:   "over the limit is exhausted",
+			rule:   ratelimit.Rule{Requests: 2, Window: time.Minute},
+			method: limitedMethod,
+			calls:  []context.Context{alice, alice, alice},
+			want:   []codes.Code{codes.OK, codes.OK, codes.ResourceExhausted},
+		},
+		{
+			name:   "anonymous clients are keyed by address",
+			rule:   ratelimit.Rule{Requests: 1, Window: time.Minute},
+			method: limitedMethod,
+			calls:  []context.Context{fromPeer("10.0.0.1"), fromPeer("10.0.0.2"), fromPeer("10.0.0.1")},
+			want:   []codes.Code{codes.OK, codes.OK, codes.ResourceExhausted},
+		},
+		{
+			name:   "user and address have separate buckets",
+			rule:   ratelimit.Rule{Requests: 1, Window: time.Minute},
+			method: limitedMethod,
+			calls:  []context.Context{alice, fromPeer("10.0.0.1")},
+			want:   []codes.Code{codes.OK, codes.OK},
+		},
+		{
+			name:   "method without group is not limited",
+			rule:   ratelimit.Rule{Requests: 1, Window: time.Minute},
+			method: freeMethod,
+			calls:  []context.Context{alice, alice},
+			want:   []codes.Code{codes.OK, codes.OK},
+		},
+		{
+			name:   "disabled group is not limited",
+			method: limitedMethod,
+			calls:  []context.Context{alice, alice},
+			want:   []codes.Code{codes.OK, codes.OK},
+		},
+		{
+			name:   "store failure lets the call through",
+			store:  failingStore{},
+			rule:   ratelimit.Rule{Requests: 1, Window: time.Minute},
+			method: limitedMethod,
+			calls:  []context.Context{alice, alice},
+			want:   []codes.Code{codes.OK, codes.OK},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := tt.store
+			if store == nil {
+				store = ratelimit.NewMemoryStore()
+			}
+			limit := RateLimit(slog.New(slog.NewTextHandler(io.Discard, nil)), store,
+				map[string]string{limitedMethod: "songs_write"},
+				func(string) ratelimit.Rule { return tt.rule })
+
+			info := &grpc.UnaryServerInfo{FullMethod: tt.method}
+			handler := func(context.Context, any) (any, error) { return "ok", nil }
+
+			for i, ctx := range tt.calls {
+				_, err := limit(ctx, nil, info, handler)
+				if got := status.Code(err); got != tt.want[i] {
+					t.Errorf("call %d: code = %s, want %s", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRateLimitRetryInfo(t *testing.T) {
+	limit := RateLimit(slog.New(slog.NewTextHandler(io.Discard, nil)), ratelimit.NewMemoryStore(),
+		map[string]string{limitedMethod: "songs_write"},
+		func(string) ratelimit.Rule { return ratelimit.Rule{Requests: 1, Window: time.Minute} })
+
+	info := &grpc.UnaryServerInfo{FullMethod: limitedMethod}
+	handler := func(context.Context, any) (any, error) { return "ok", nil }
+
+	_, _ = limit(fromPeer("10.0.0.1"), nil, info, handler)
+	_, err := limit(fromPeer("10.0.0.1"), nil, info, handler)
+
+	st := status.Convert(err)
+	var retry *errdetails.RetryInfo
+	for _, d := range st.Details() {
+		if r, ok := d.(*errdetails.RetryInfo); ok {
+			retry = r
+		}
+	}
+	if retry == nil {
+		t.Fatalf("status %v has no RetryInfo", st)
+	}
+	if d := retry.GetRetryDelay().AsDuration(); d <= 0 || d > time.Minute {
+		t.Errorf("retry delay = %s, want within the window", d)
+	}
+}
@@ -0,0 +1,73 @@
+package interceptor
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+const tracerName = "RestApi_v1/grpc-server"
+
+// Tracing создает входящий спан на каждый вызов, продолжая трейс из метаданных traceparent
+func Tracing() grpc.UnaryServerInterceptor {
+	tracer := otel.Tracer(tracerName)
+
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		ctx = otel.GetTextMapPropagator().Extract(ctx, metadataCarrier(md))
+
+		attrs := []attribute.KeyValue{
+			semconv.RPCSystemGRPC,
+			attribute.String("rpc.method", info.FullMethod),
+		}
+		if p, ok := peer.FromContext(ctx); ok {
+			attrs = append(attrs, semconv.ClientAddress(p.Addr.String()))
+		}
+
+		ctx, span := tracer.Start(ctx, info.FullMethod,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(attrs...),
+		)
+		defer span.End()
+
+		res, err := handler(ctx, req)
+
+		st := status.Convert(err)
+		span.SetAttributes(semconv.RPCGRPCStatusCodeKey.Int(int(st.Code())))
+		if serverError(st.Code()) {
+			span.SetStatus(codes.Error, st.Message())
+		}
+
+		return res, err
+	}
+}
+
+// metadataCarrier метаданные gRPC как носитель для пропагатора
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	if v := metadata.MD(c).Get(key); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+func (c metadataCarrier) Set(key string, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
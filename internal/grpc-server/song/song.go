@@ -0,0 +1,324 @@
+package song
+
+import (
+	songv1 "RestApi_v1/internal/config/api/song/v1"
+	"RestApi_v1/internal/config/internal/lib/i18n"
+	"RestApi_v1/internal/config/internal/lib/logger/sl"
+	"RestApi_v1/internal/config/internal/lib/songcache"
+	"RestApi_v1/internal/config/internal/lib/validate"
+	"RestApi_v1/internal/config/internal/models"
+	"RestApi_v1/internal/config/internal/storage"
+	"context"
+	"errors"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 500
+)
+
+// songFields ограничения те же, что у save.Request и updateSong.Request, имена полей - из proto
+type songFields struct {
+	Song        string `json:"song" validate:"required,max=100"`
+	Group       string `json:"group" validate:"omitempty,max=50"`
+	Text        string `json:"text"`
+	ReleaseDate string `json:"release_date" validate:"omitempty,songdate"`
+	Link        string `json:"link" validate:"omitempty,url,max=255"`
+}
+
+type SongLister interface {
+	ListSongs(ctx context.Context, filter storage.SongFilter, limit int) ([]models.Song, error)
+}
+
+// Server SongService поверх тех же хранилищ, что и REST-хендлеры
+type Server struct {
+	songv1.UnimplementedSongServiceServer
+
+	log    *slog.Logger
+	songs  songcache.Store
+	lister SongLister
+}
+
+// Register регистрирует SongService на gRPC-сервере
+func Register(gRPC *grpc.Server, log *slog.Logger, songs songcache.Store, lister SongLister) {
+	songv1.RegisterSongServiceServer(gRPC, &Server{
+		log:    log,
+		songs:  songs,
+		lister: lister,
+	})
+}
+
+func (s *Server) Create(ctx context.Context, req *songv1.CreateRequest) (*songv1.CreateResponse, error) {
+	const op = "grpc.song.Create"
+
+	log := s.logger(op)
+
+	fields := songFields{
+		Song:        req.GetSong(),
+		Group:       req.GetGroup(),
+		Text:        req.GetText(),
+		ReleaseDate: req.GetReleaseDate(),
+		Link:        req.GetLink(),
+	}
+	if err := validateFields(ctx, fields); err != nil {
+		log.InfoContext(ctx, "invalid request", sl.Err(err))
+		return nil, err
+	}
+
+	// в базу дата уходит в формате 2006-01-02
+	fields.ReleaseDate, _ = validate.NormalizeDate(fields.ReleaseDate)
+
+	id, err := s.songs.SaveSong(ctx, fields.Song, fields.Group, fields.Text, fields.ReleaseDate, fields.Link)
+	if err != nil {
+		return nil, storageError(ctx, log, "failed to add song", err)
+	}
+
+	log.InfoContext(ctx, "song added", slog.Int64("id", id))
+	return &songv1.CreateResponse{Id: id}, nil
+}
+
+func (s *Server) Get(ctx context.Context, req *songv1.GetRequest) (*songv1.Song, error) {
+	const op = "grpc.song.Get"
+
+	log := s.logger(op)
+
+	if req.GetId() < 1 {
+		return nil, invalidArgument(ctx, "id", errors.New("id must be positive"))
+	}
+
+	songs, err := s.songs.GetSongWithPagination(ctx, int(req.GetId()), 1, 1)
+	if err != nil {
+		return nil, storageError(ctx, log, "failed to get song", err)
+	}
+	if len(songs) == 0 {
+		return nil, storageError(ctx, log, "failed to get song", storage.ErrSongNotFound)
+	}
+
+	return toProto(songs[0]), nil
+}
+
+func (s *Server) List(ctx context.Context, req *songv1.ListRequest) (*songv1.ListResponse, error) {
+	const op = "grpc.song.List"
+
+	log := s.logger(op)
+
+	filter := storage.SongFilter{
+		Group: req.GetGroup(),
+		Song:  req.GetSong(),
+	}
+
+	var err error
+	if filter.ReleasedFrom, err = parseDate(req.GetReleasedFrom()); err != nil {
+		return nil, invalidArgument(ctx, "released_from", err)
+	}
+	if filter.ReleasedTo, err = parseDate(req.GetReleasedTo()); err != nil {
+		return nil, invalidArgument(ctx, "released_to", err)
+	}
+
+	return s.page(ctx, log, filter, req.GetPageSize(), req.GetPageToken())
+}
+
+func (s *Server) Search(ctx context.Context, req *songv1.SearchRequest) (*songv1.ListResponse, error) {
+	const op = "grpc.song.Search"
+
+	log := s.logger(op)
+
+	if req.GetQuery() == "" {
+		return nil, invalidArgument(ctx, "query", errors.New("query is empty"))
+	}
+
+	return s.page(ctx, log, storage.SongFilter{Query: req.GetQuery()}, req.GetPageSize(), req.GetPageToken())
+}
+
+func (s *Server) Update(ctx context.Context, req *songv1.UpdateRequest) (*emptypb.Empty, error) {
+	const op = "grpc.song.Update"
+
+	log := s.logger(op)
+
+	if req.GetId() < 1 {
+		return nil, invalidArgument(ctx, "id", errors.New("id must be positive"))
+	}
+
+	fields := songFields{
+		Song:        req.GetSong(),
+		Group:       req.GetGroup(),
+		Text:        req.GetText(),
+		ReleaseDate: req.GetReleaseDate(),
+		Link:        req.GetLink(),
+	}
+	if err := validateFields(ctx, fields); err != nil {
+		log.InfoContext(ctx, "invalid request", sl.Err(err))
+		return nil, err
+	}
+
+	fields.ReleaseDate, _ = validate.NormalizeDate(fields.ReleaseDate)
+
+	_, err := s.songs.UpdateSong(ctx, int(req.GetId()), fields.Song, fields.Group, fields.Text, fields.ReleaseDate, fields.Link)
+	if err != nil {
+		return nil, storageError(ctx, log, "failed to update song", err)
+	}
+
+	log.InfoContext(ctx, "song updated", slog.Int64("id", req.GetId()))
+	return &emptypb.Empty{}, nil
+}
+
+func (s *Server) Delete(ctx context.Context, req *songv1.DeleteRequest) (*emptypb.Empty, error) {
+	const op = "grpc.song.Delete"
+
+	log := s.logger(op)
+
+	if req.GetSong() == "" {
+		return nil, invalidArgument(ctx, "song", errors.New("song is empty"))
+	}
+
+	if _, err := s.songs.DeleteSong(ctx, req.GetSong()); err != nil {
+		return nil, storageError(ctx, log, "failed to delete song", err)
+	}
+
+	log.InfoContext(ctx, "song deleted", slog.String("song", req.GetSong()))
+	return &emptypb.Empty{}, nil
+}
+
+// page страница песен по фильтру. page_token - id последней песни предыдущей страницы.
+func (s *Server) page(ctx context.Context, log *slog.Logger, filter storage.SongFilter, pageSize int32, pageToken string) (*songv1.ListResponse, error) {
+	limit := int(pageSize)
+	switch {
+	case limit == 0:
+		limit = defaultPageSize
+	case limit < 0 || limit > maxPageSize:
+		return nil, invalidArgument(ctx, "page_size", errors.New("page_size must be between 1 and 500"))
+	}
+
+	if pageToken != "" {
+		afterID, err := strconv.ParseInt(pageToken, 10, 64)
+		if err != nil || afterID < 1 {
+			return nil, invalidArgument(ctx, "page_token", errors.New("invalid page token"))
+		}
+		filter.AfterID = afterID
+	}
+
+	// на одну больше, чтобы знать, есть ли следующая страница
+	songs, err := s.lister.ListSongs(ctx, filter, limit+1)
+	if err != nil {
+		return nil, storageError(ctx, log, "failed to list songs", err)
+	}
+
+	res := &songv1.ListResponse{}
+	if len(songs) > limit {
+		songs = songs[:limit]
+		res.NextPageToken = strconv.FormatInt(songs[limit-1].ID, 10)
+	}
+	for _, song := range songs {
+		res.Songs = append(res.Songs, toProto(song))
+	}
+	return res, nil
+}
+
+func (s *Server) logger(op string) *slog.Logger {
+	return s.log.With(
+		slog.String("op", op),
+	)
+}
+
+func toProto(song models.Song) *songv1.Song {
+	res := &songv1.Song{
+		Id:    song.ID,
+		Song:  song.Song,
+		Group: song.Group,
+		Text:  song.Text,
+		Link:  song.Link,
+	}
+	if song.ReleaseDate.Valid {
+		res.ReleaseDate = song.ReleaseDate.Time.Format(validate.DateLayoutISO)
+	}
+	if !song.UpdatedAt.IsZero() {
+		res.UpdatedAt = timestamppb.New(song.UpdatedAt)
+	}
+	return res
+}
+
+func parseDate(s string) (*time.Time, error) {
+	if s == "" {
+		return nil, nil
+	}
+	t, err := validate.ParseDate(s)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// translator язык сообщений об ошибках из метаданных accept-language, как у REST
+func translator(ctx context.Context) *i18n.Translator {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if langs := md.Get("accept-language"); len(langs) > 0 {
+		return i18n.Get(i18n.Match(langs[0]))
+	}
+	return i18n.Get(i18n.DefaultLang)
+}
+
+// validateFields проверяет поля песни; ошибки уходят клиенту в BadRequest с переводом
+func validateFields(ctx context.Context, fields songFields) error {
+	err := validate.Struct(fields)
+	if err == nil {
+		return nil
+	}
+
+	t := translator(ctx)
+
+	var validateErrs validator.ValidationErrors
+	if !errors.As(err, &validateErrs) {
+		return status.Error(codes.Internal, t.T(i18n.MsgInternalError))
+	}
+
+	details := &errdetails.BadRequest{}
+	for _, fe := range validateErrs {
+		details.FieldViolations = append(details.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       fe.Field(),
+			Description: fe.Translate(t.Validation()),
+		})
+	}
+	return withDetails(status.New(codes.InvalidArgument, t.T(i18n.MsgValidationError)), details)
+}
+
+func invalidArgument(ctx context.Context, field string, err error) error {
+	return withDetails(status.New(codes.InvalidArgument, translator(ctx).T(i18n.MsgInvalidRequest)), &errdetails.BadRequest{
+		FieldViolations: []*errdetails.BadRequest_FieldViolation{{Field: field, Description: err.Error()}},
+	})
+}
+
+func withDetails(st *status.Status, details *errdetails.BadRequest) error {
+	if withDetails, err := st.WithDetails(details); err == nil {
+		st = withDetails
+	}
+	return st.Err()
+}
+
+// storageError переводит ошибки хранилища в коды gRPC так же, как REST в HTTP-статусы
+func storageError(ctx context.Context, log *slog.Logger, msg string, err error) error {
+	t := translator(ctx)
+
+	switch {
+	case errors.Is(err, storage.ErrSongNotFound):
+		return status.Error(codes.NotFound, t.T(i18n.MsgNotFound))
+	case errors.Is(err, storage.ErrSongExist):
+		return status.Error(codes.AlreadyExists, t.T(i18n.MsgSongExists))
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return status.FromContextError(err).Err()
+	default:
+		log.ErrorContext(ctx, msg, sl.Err(err))
+		return status.Error(codes.Internal, t.T(i18n.MsgInternalError))
+	}
+}
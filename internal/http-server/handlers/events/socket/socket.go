@@ -0,0 +1,128 @@
+package socket
+
+import (
+	resp "RestApi_v1/internal/config/internal/lib/api/response"
+	"RestApi_v1/internal/config/internal/lib/events"
+	"RestApi_v1/internal/config/internal/lib/i18n"
+	"RestApi_v1/internal/config/internal/lib/logger/sl"
+	"context"
+	"errors"
+	"github.com/gorilla/websocket"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// writeTimeout на отправку одного сообщения клиенту
+const writeTimeout = 10 * time.Second
+
+type Follower interface {
+	Follow(ctx context.Context, filter events.Filter, lastID int64, resume bool, sink events.Sink) error
+}
+
+// message сообщение клиенту: type song с событием или type reset без него
+type message struct {
+	Type  string        `json:"type"`
+	Event *events.Event `json:"event,omitempty"`
+}
+
+// New отдает тот же поток изменений песен, что и GET /events, через WebSocket.
+// Фильтры те же, продолжение - параметром last_event_id: браузер не дает поставить заголовок.
+// Сообщения клиента не читаются, кроме служебных кадров.
+func New(log *slog.Logger, follower Follower) http.HandlerFunc {
+	upgrader := websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 4096,
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.events.socket.New"
+
+		log := log.With(
+			slog.String("op", op),
+		)
+
+		filter, err := events.ParseFilter(r.URL.Query())
+		if err != nil {
+			log.InfoContext(r.Context(), "invalid events filter", sl.Err(err))
+			resp.WriteProblem(w, r, resp.NewLocalizedProblem(r, http.StatusBadRequest, i18n.MsgInvalidRequest))
+			return
+		}
+
+		lastID, resume, err := events.LastEventID(r)
+		if err != nil {
+			log.InfoContext(r.Context(), "invalid last event id", sl.Err(err))
+			resp.WriteProblem(w, r, resp.NewLocalizedProblem(r, http.StatusBadRequest, i18n.MsgInvalidRequest))
+			return
+		}
+
+		// при ошибке Upgrade сам отвечает клиенту
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			log.InfoContext(r.Context(), "websocket upgrade failed", sl.Err(err))
+			return
+		}
+		defer conn.Close()
+
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+
+		// чтение нужно, чтобы обрабатывать ping и close клиента; закрытие останавливает поток
+		go func() {
+			defer cancel()
+			for {
+				if _, _, err := conn.NextReader(); err != nil {
+					return
+				}
+			}
+		}()
+
+		log.InfoContext(r.Context(), "events socket opened", slog.Bool("resume", resume), slog.Int64("last_event_id", lastID))
+
+		s := &sink{conn: conn}
+		err = follower.Follow(ctx, filter, lastID, resume, s)
+
+		code, reason := websocket.CloseNormalClosure, ""
+		switch {
+		case err == nil, errors.Is(err, context.Canceled):
+			log.InfoContext(r.Context(), "events socket closed by client", slog.Int("sent", s.sent))
+		case errors.Is(err, events.ErrLagging):
+			log.InfoContext(r.Context(), "events socket subscriber is too slow", slog.Int("sent", s.sent))
+			code, reason = websocket.CloseTryAgainLater, "too slow, reconnect with last_event_id"
+		case errors.Is(err, events.ErrClosed):
+			log.InfoContext(r.Context(), "events socket closed on shutdown", slog.Int("sent", s.sent))
+			code = websocket.CloseGoingAway
+		default:
+			log.ErrorContext(r.Context(), "events socket failed", slog.Int("sent", s.sent), sl.Err(err))
+			code = websocket.CloseInternalServerErr
+		}
+
+		_ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(writeTimeout))
+	}
+}
+
+type sink struct {
+	conn *websocket.Conn
+	sent int
+}
+
+func (s *sink) Send(event events.Event) error {
+	if err := s.write(message{Type: "song", Event: &event}); err != nil {
+		return err
+	}
+	s.sent++
+	return nil
+}
+
+func (s *sink) Reset() error {
+	return s.write(message{Type: "reset"})
+}
+
+func (s *sink) Heartbeat() error {
+	return s.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeTimeout))
+}
+
+func (s *sink) write(m message) error {
+	_ = s.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	return s.conn.WriteJSON(m)
+}
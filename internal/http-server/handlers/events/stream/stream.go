@@ -0,0 +1,135 @@
+package stream
+
+import (
+	resp "RestApi_v1/internal/config/internal/lib/api/response"
+	"RestApi_v1/internal/config/internal/lib/events"
+	"RestApi_v1/internal/config/internal/lib/i18n"
+	"RestApi_v1/internal/config/internal/lib/logger/sl"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// retryInterval через сколько EventSource переподключается после обрыва
+const retryInterval = 3 * time.Second
+
+type Follower interface {
+	Follow(ctx context.Context, filter events.Filter, lastID int64, resume bool, sink events.Sink) error
+}
+
+// New отдает изменения песен потоком Server-Sent Events
+//
+// @Summary      Song change feed (SSE)
+// @Description  Поток изменений песен: event song с JSON события, id - номер события. При переподключении с Last-Event-ID пропущенные события досылаются из журнала; если журнал их уже не хранит, приходит event reset и данные нужно перечитать. Тот же поток по WebSocket: GET /events/ws. Нужна роль reader.
+// @Tags         events
+// @Produce      text/event-stream
+// @Param        group          query     []string  false  "only songs of these groups, case-insensitive"  collectionFormat(multi)
+// @Param        op             query     []string  false  "only these operations"  collectionFormat(multi)  Enums(INSERT, UPDATE, DELETE)
+// @Param        Last-Event-ID  header    int       false  "resume after this event"
+// @Param        last_event_id  query     int       false  "resume after this event, for clients that cannot set headers"
+// @Success      200            {object}  events.Event  "stream of events"
+// @Failure      400            {object}  response.Problem   "invalid filter or event id"
+// @Failure      401            {object}  response.Response  "no credentials"
+// @Failure      429            {object}  response.Problem   "rate limit exceeded"
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @x-stream     true
+// @Router       /events [get]
+func New(log *slog.Logger, follower Follower) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.events.stream.New"
+
+		log := log.With(
+			slog.String("op", op),
+		)
+
+		filter, err := events.ParseFilter(r.URL.Query())
+		if err != nil {
+			log.InfoContext(r.Context(), "invalid events filter", sl.Err(err))
+			resp.WriteProblem(w, r, resp.NewLocalizedProblem(r, http.StatusBadRequest, i18n.MsgInvalidRequest))
+			return
+		}
+
+		lastID, resume, err := events.LastEventID(r)
+		if err != nil {
+			log.InfoContext(r.Context(), "invalid last event id", sl.Err(err))
+			resp.WriteProblem(w, r, resp.NewLocalizedProblem(r, http.StatusBadRequest, i18n.MsgInvalidRequest))
+			return
+		}
+
+		rc := http.NewResponseController(w)
+		// поток живет дольше WriteTimeout сервера
+		_ = rc.SetWriteDeadline(time.Time{})
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		// nginx не должен буферизовать поток
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+
+		s := &sink{w: w, rc: rc}
+		if _, err := fmt.Fprintf(w, "retry: %d\n\n", retryInterval.Milliseconds()); err != nil {
+			return
+		}
+		if err := s.flush(); err != nil {
+			return
+		}
+
+		log.InfoContext(r.Context(), "events stream opened", slog.Bool("resume", resume), slog.Int64("last_event_id", lastID))
+
+		err = follower.Follow(r.Context(), filter, lastID, resume, s)
+		switch {
+		case err == nil, errors.Is(err, context.Canceled):
+			log.InfoContext(r.Context(), "events stream closed by client", slog.Int("sent", s.sent))
+		case errors.Is(err, events.ErrLagging), errors.Is(err, events.ErrClosed):
+			// EventSource переподключится сам и продолжит с последнего id
+			log.InfoContext(r.Context(), "events stream closed", slog.Int("sent", s.sent), sl.Err(err))
+		default:
+			log.ErrorContext(r.Context(), "events stream failed", slog.Int("sent", s.sent), sl.Err(err))
+		}
+	}
+}
+
+type sink struct {
+	w    http.ResponseWriter
+	rc   *http.ResponseController
+	sent int
+}
+
+func (s *sink) Send(event events.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(s.w, "id: %d\nevent: song\ndata: %s\n\n", event.ID, data); err != nil {
+		return err
+	}
+	s.sent++
+	return s.flush()
+}
+
+func (s *sink) Reset() error {
+	if _, err := fmt.Fprint(s.w, "event: reset\ndata: {}\n\n"); err != nil {
+		return err
+	}
+	return s.flush()
+}
+
+// Heartbeat комментарий, чтобы прокси не закрывали молчащее соединение
+func (s *sink) Heartbeat() error {
+	if _, err := fmt.Fprint(s.w, ": ping\n\n"); err != nil {
+		return err
+	}
+	return s.flush()
+}
+
+func (s *sink) flush() error {
+	if err := s.rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	return nil
+}
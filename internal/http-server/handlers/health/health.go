@@ -0,0 +1,150 @@
+package health
+
+import (
+	"RestApi_v1/internal/config/internal/storage"
+	"context"
+	"fmt"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	StatusOK       = "ok"
+	StatusFail     = "fail"
+	StatusReady    = "ready"
+	StatusNotReady = "not_ready"
+
+	// доля занятых соединений, начиная с которой пул считается перегруженным
+	poolSaturationLimit = 0.9
+
+	checkTimeout = 2 * time.Second
+)
+
+// Checker интерфейс хранилища для проверок готовности
+type Checker interface {
+	Ping(ctx context.Context) error
+	MigrationStatus(ctx context.Context) (storage.MigrationStatus, error)
+	PoolStats() storage.PoolStats
+}
+
+type Check struct {
+	Status  string `json:"status"`
+	Latency string `json:"latency"`
+	Error   string `json:"error,omitempty"`
+	Details any    `json:"details,omitempty"`
+}
+
+type Response struct {
+	Status string           `json:"status"`
+	Checks map[string]Check `json:"checks,omitempty"`
+}
+
+// Liveness отвечает 200, пока процесс жив и обслуживает запросы
+//
+// @Summary  Liveness probe
+// @Tags     health
+// @Produce  json
+// @Success  200  {object}  health.Response
+// @Router   /healthz [get]
+func Liveness() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		render.JSON(w, r, Response{Status: StatusOK})
+	}
+}
+
+// Readiness проверяет, готов ли сервис принимать трафик
+type Readiness struct {
+	log      *slog.Logger
+	checker  Checker
+	draining atomic.Bool
+}
+
+func NewReadiness(log *slog.Logger, checker Checker) *Readiness {
+	return &Readiness{
+		log:     log.With(slog.String("op", "handlers.health.Readiness")),
+		checker: checker,
+	}
+}
+
+// SetNotReady переводит сервис в состояние not ready, чтобы балансировщик перестал слать запросы
+func (rd *Readiness) SetNotReady() {
+	rd.draining.Store(true)
+}
+
+// Handler отвечает 503, пока хоть одна проверка не проходит
+//
+// @Summary  Readiness probe
+// @Tags     health
+// @Produce  json
+// @Success  200  {object}  health.Response
+// @Failure  503  {object}  health.Response
+// @Router   /readyz [get]
+func (rd *Readiness) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), checkTimeout)
+		defer cancel()
+
+		checks := map[string]Check{}
+
+		if rd.draining.Load() {
+			checks["shutdown"] = Check{Status: StatusFail, Latency: "0s", Error: "server is shutting down"}
+		} else {
+			checks["shutdown"] = Check{Status: StatusOK, Latency: "0s"}
+		}
+
+		checks["database"] = timed(func() (any, error) {
+			return nil, rd.checker.Ping(ctx)
+		})
+
+		checks["migrations"] = timed(func() (any, error) {
+			status, err := rd.checker.MigrationStatus(ctx)
+			if err != nil {
+				return nil, err
+			}
+			if !status.UpToDate() {
+				return status, fmt.Errorf("schema version %d, expected %d", status.Current, status.Latest)
+			}
+			return status, nil
+		})
+
+		checks["pool"] = timed(func() (any, error) {
+			stats := rd.checker.PoolStats()
+			if stats.Saturation() >= poolSaturationLimit {
+				return stats, fmt.Errorf("pool saturated: %d of %d connections acquired", stats.Acquired, stats.Max)
+			}
+			return stats, nil
+		})
+
+		resp := Response{Status: StatusReady, Checks: checks}
+		for name, c := range checks {
+			if c.Status != StatusOK {
+				resp.Status = StatusNotReady
+				rd.log.WarnContext(r.Context(), "readiness check failed", slog.String("check", name), slog.String("error", c.Error))
+			}
+		}
+
+		if resp.Status != StatusReady {
+			render.Status(r, http.StatusServiceUnavailable)
+		}
+		render.JSON(w, r, resp)
+	}
+}
+
+func timed(fn func() (any, error)) Check {
+	start := time.Now()
+	details, err := fn()
+
+	c := Check{
+		Status:  StatusOK,
+		Latency: time.Since(start).String(),
+		Details: details,
+	}
+	if err != nil {
+		c.Status = StatusFail
+		c.Error = err.Error()
+	}
+	return c
+}
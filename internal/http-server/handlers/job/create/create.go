@@ -0,0 +1,182 @@
+package create
+
+import (
+	resp "RestApi_v1/internal/config/internal/lib/api/response"
+	"RestApi_v1/internal/config/internal/lib/auth"
+	"RestApi_v1/internal/config/internal/lib/i18n"
+	"RestApi_v1/internal/config/internal/lib/jobs"
+	"RestApi_v1/internal/config/internal/lib/logger/sl"
+	"RestApi_v1/internal/config/internal/lib/songimport"
+	"RestApi_v1/internal/config/internal/models"
+	"RestApi_v1/internal/config/internal/storage"
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/render"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// MaxPayloadBytes ограничение на файл для фоновой загрузки, он хранится при задаче частями
+const MaxPayloadBytes = 128 << 20
+
+type JobCreator interface {
+	CreateJob(ctx context.Context, job storage.NewJob) (int64, error)
+}
+
+// New ставит фоновую задачу загрузки или выгрузки в очередь
+//
+// @Summary      Create job
+// @Description  Ставит в очередь фоновую загрузку (kind=import, нужна роль editor) или выгрузку (kind=export, роль reader).
+// @Description  Для import тело и mode как у POST /songs/import, для export фильтр как у GET /songs/export.
+// @Description  Ответ 202 с заголовком Location на статус задачи, результат потом забирается по result_url.
+// @Tags         jobs
+// @Accept       application/x-ndjson
+// @Accept       json
+// @Accept       text/csv
+// @Produce      json
+// @Param        kind     query     string             true   "job kind"  Enums(import, export)
+// @Param        mode     query     string             false  "import: atomic or best_effort"  Enums(atomic, best_effort)
+// @Param        group    query     string             false  "export: exact group name, case-insensitive"
+// @Param        song     query     string             false  "export: substring of the song title"
+// @Param        from     query     string             false  "export: released on or after, 2006-01-02 or 02.01.2006"
+// @Param        to       query     string             false  "export: released on or before, 2006-01-02 or 02.01.2006"
+// @Param        request  body      []save.Request     false  "import: songs to import"
+// @Param        Idempotency-Key  header  string  false  "repeat with the same key returns the stored response"
+// @Success      202      {object}  jobs.Status
+// @Failure      400      {object}  response.Problem   "unknown kind, mode or filter"
+// @Failure      401      {object}  response.Response  "no credentials"
+// @Failure      403      {object}  response.Response  "role is too low for the job kind"
+// @Failure      413      {object}  response.Problem   "body is too large"
+// @Failure      415      {object}  response.Problem   "unsupported content type"
+// @Failure      409      {object}  response.Problem   "request with this Idempotency-Key is in progress"
+// @Failure      422      {object}  response.Problem   "Idempotency-Key reused with a different request"
+// @Failure      429      {object}  response.Problem   "rate limit exceeded"
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @x-raw-body   true
+// @Router       /jobs [post]
+func New(log *slog.Logger, creator JobCreator, maxAttempts int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.job.create.New"
+
+		log := log.With(
+			slog.String("op", op),
+		)
+
+		principal, _ := auth.PrincipalFromContext(r.Context())
+
+		job := storage.NewJob{
+			Kind:        r.URL.Query().Get("kind"),
+			MaxAttempts: maxAttempts,
+			CreatedBy:   principal.String(),
+		}
+
+		var err error
+		switch job.Kind {
+		case jobs.KindImport:
+			if !principal.Role.Allows(auth.RoleEditor) {
+				render.Status(r, http.StatusForbidden)
+				render.JSON(w, r, resp.LocalizedError(r, i18n.MsgForbidden))
+				return
+			}
+			if !importJob(w, r, log, &job) {
+				return
+			}
+		case jobs.KindExport:
+			params := jobs.ExportParams{
+				Group: r.URL.Query().Get("group"),
+				Song:  r.URL.Query().Get("song"),
+				From:  r.URL.Query().Get("from"),
+				To:    r.URL.Query().Get("to"),
+			}
+			if _, err := params.Filter(); err != nil {
+				log.InfoContext(r.Context(), "invalid export filter", sl.Err(err))
+				resp.WriteProblem(w, r, resp.NewLocalizedProblem(r, http.StatusBadRequest, i18n.MsgInvalidRequest))
+				return
+			}
+			job.Params, err = json.Marshal(params)
+		default:
+			log.InfoContext(r.Context(), "unknown job kind", slog.String("kind", job.Kind))
+			resp.WriteProblem(w, r, resp.NewLocalizedProblem(r, http.StatusBadRequest, i18n.MsgInvalidRequest))
+			return
+		}
+		if err != nil {
+			log.ErrorContext(r.Context(), "failed to encode job params", sl.Err(err))
+			resp.WriteProblem(w, r, resp.NewLocalizedProblem(r, http.StatusInternalServerError, i18n.MsgInternalError))
+			return
+		}
+
+		id, err := creator.CreateJob(r.Context(), job)
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			log.InfoContext(r.Context(), "import body is too large", sl.Err(err))
+			resp.WriteProblem(w, r, resp.NewLocalizedProblem(r, http.StatusRequestEntityTooLarge, i18n.MsgPayloadTooLarge, strconv.Itoa(MaxPayloadBytes)))
+			return
+		}
+		if err != nil {
+			log.ErrorContext(r.Context(), "failed to create job", sl.Err(err))
+			resp.WriteProblem(w, r, resp.NewLocalizedProblem(r, http.StatusInternalServerError, i18n.MsgInternalError))
+			return
+		}
+
+		log.InfoContext(r.Context(), "job created", slog.Int64("job_id", id), slog.String("kind", job.Kind))
+
+		w.Header().Set("Location", "/jobs/"+strconv.FormatInt(id, 10))
+		render.Status(r, http.StatusAccepted)
+		render.JSON(w, r, jobs.StatusOf(models.Job{
+			ID:          id,
+			Kind:        job.Kind,
+			Status:      models.JobQueued,
+			MaxAttempts: job.MaxAttempts,
+			CreatedAt:   time.Now(),
+		}))
+	}
+}
+
+// importJob читает файл загрузки в задачу. Если ответ с ошибкой уже отправлен, возвращает false.
+func importJob(w http.ResponseWriter, r *http.Request, log *slog.Logger, job *storage.NewJob) bool {
+	mode, err := songimport.ParseMode(r.URL.Query().Get("mode"))
+	if err != nil {
+		log.InfoContext(r.Context(), "invalid import mode", sl.Err(err))
+		resp.WriteProblem(w, r, resp.NewLocalizedProblem(r, http.StatusBadRequest, i18n.MsgInvalidRequest))
+		return false
+	}
+
+	job.PayloadType, err = songimport.MediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		log.InfoContext(r.Context(), "unsupported import format", sl.Err(err))
+		resp.WriteProblem(w, r, resp.NewLocalizedProblem(r, http.StatusUnsupportedMediaType, i18n.MsgUnsupportedMedia))
+		return false
+	}
+
+	// тело дочитывается хранилищем при постановке задачи, здесь только проверяем, что оно не пустое
+	body := bufio.NewReader(http.MaxBytesReader(w, r.Body, MaxPayloadBytes))
+	if _, err := body.Peek(1); err != nil {
+		if errors.Is(err, io.EOF) {
+			log.InfoContext(r.Context(), "import body is empty")
+			resp.WriteProblem(w, r, resp.NewLocalizedProblem(r, http.StatusBadRequest, i18n.MsgEmptyRequest))
+			return false
+		}
+		log.InfoContext(r.Context(), "failed to read import body", sl.Err(err))
+		resp.WriteProblem(w, r, resp.NewLocalizedProblem(r, http.StatusBadRequest, i18n.MsgFailedToDecode))
+		return false
+	}
+	job.Payload = body
+
+	job.Params, err = json.Marshal(jobs.ImportParams{
+		Mode: mode,
+		Lang: i18n.FromRequest(r).Lang(),
+	})
+	if err != nil {
+		log.ErrorContext(r.Context(), "failed to encode job params", sl.Err(err))
+		resp.WriteProblem(w, r, resp.NewLocalizedProblem(r, http.StatusInternalServerError, i18n.MsgInternalError))
+		return false
+	}
+
+	return true
+}
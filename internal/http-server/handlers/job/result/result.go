@@ -0,0 +1,90 @@
+package result
+
+import (
+	"RestApi_v1/internal/config/internal/http-server/handlers/job/status"
+	resp "RestApi_v1/internal/config/internal/lib/api/response"
+	"RestApi_v1/internal/config/internal/lib/i18n"
+	"RestApi_v1/internal/config/internal/lib/jobs"
+	"RestApi_v1/internal/config/internal/lib/logger/sl"
+	"RestApi_v1/internal/config/internal/models"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+type JobResultGetter interface {
+	status.JobGetter
+	JobResult(ctx context.Context, id int64, fn func(chunk []byte) error) error
+}
+
+// New отдает результат завершенной задачи файлом, потоком по сохраненным частям
+//
+// @Summary      Job result
+// @Description  Результат успешной задачи: NDJSON для export, JSON-отчет songimport.Report для import.
+// @Tags         jobs
+// @Produce      json
+// @Produce      application/x-ndjson
+// @Param        id   path      int  true  "job id"
+// @Success      200  {object}  songimport.Report  "import report; export result is NDJSON with one song per line"
+// @Failure      400  {object}  response.Problem   "id is not a number"
+// @Failure      401  {object}  response.Response  "no credentials"
+// @Failure      404  {object}  response.Problem   "no such job"
+// @Failure      409  {object}  response.Problem   "job is not finished"
+// @Failure      429  {object}  response.Problem   "rate limit exceeded"
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /jobs/{id}/result [get]
+func New(log *slog.Logger, getter JobResultGetter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.job.result.New"
+
+		log := log.With(
+			slog.String("op", op),
+		)
+
+		job, ok := status.Load(w, r, log, getter)
+		if !ok {
+			return
+		}
+
+		if job.Status != models.JobSucceeded {
+			resp.WriteProblem(w, r, resp.NewLocalizedProblem(r, http.StatusConflict, i18n.MsgJobNotFinished, job.Status))
+			return
+		}
+
+		ext := ".json"
+		if job.ResultType == jobs.ContentTypeNDJSON {
+			ext = ".ndjson"
+		}
+
+		// заголовки ставятся перед первой частью: если чтение упадет сразу, еще можно ответить ошибкой
+		written := false
+		send := func(chunk []byte) error {
+			if !written {
+				written = true
+				w.Header().Set("Content-Type", job.ResultType)
+				w.Header().Set("Content-Length", strconv.FormatInt(job.ResultSize, 10))
+				w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-%d%s"`, job.Kind, job.ID, ext))
+				w.WriteHeader(http.StatusOK)
+			}
+			_, err := w.Write(chunk)
+			return err
+		}
+
+		err := getter.JobResult(r.Context(), job.ID, send)
+		if err != nil && !written {
+			log.ErrorContext(r.Context(), "failed to get job result", sl.Err(err))
+			resp.WriteProblem(w, r, resp.NewLocalizedProblem(r, http.StatusInternalServerError, i18n.MsgInternalError))
+			return
+		}
+		if err != nil {
+			log.InfoContext(r.Context(), "failed to send job result", sl.Err(err))
+			return
+		}
+		if !written {
+			_ = send(nil)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package status
+
+import (
+	"RestApi_v1/internal/config/internal/http-server/middleware/httpcache"
+	resp "RestApi_v1/internal/config/internal/lib/api/response"
+	"RestApi_v1/internal/config/internal/lib/auth"
+	"RestApi_v1/internal/config/internal/lib/i18n"
+	"RestApi_v1/internal/config/internal/lib/jobs"
+	"RestApi_v1/internal/config/internal/lib/logger/sl"
+	"RestApi_v1/internal/config/internal/models"
+	"RestApi_v1/internal/config/internal/storage"
+	"context"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+type JobGetter interface {
+	JobByID(ctx context.Context, id int64) (models.Job, error)
+}
+
+// New отдает статус и прогресс задачи
+//
+// @Summary      Job status
+// @Description  Статус, прогресс и ошибка последней попытки. Чужие задачи видит только admin.
+// @Tags         jobs
+// @Produce      json
+// @Param        id                 path      int     true   "job id"
+// @Param        If-None-Match      header    string  false  "ETag of a previously received status"
+// @Param        If-Modified-Since  header    string  false  "Last-Modified of a previously received status"
+// @Success      200  {object}  jobs.Status
+// @Success      304  "status has not changed"
+// @Failure      400  {object}  response.Problem   "id is not a number"
+// @Failure      401  {object}  response.Response  "no credentials"
+// @Failure      404  {object}  response.Problem   "no such job"
+// @Failure      429  {object}  response.Problem   "rate limit exceeded"
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /jobs/{id} [get]
+func New(log *slog.Logger, getter JobGetter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.job.status.New"
+
+		log := log.With(
+			slog.String("op", op),
+		)
+
+		job, ok := Load(w, r, log, getter)
+		if !ok {
+			return
+		}
+
+		httpcache.SetLastModified(w, job.UpdatedAt)
+		render.JSON(w, r, jobs.StatusOf(job))
+	}
+}
+
+// Load достает задачу по {id} из пути и проверяет, что пользователь может ее видеть.
+// Если ответ с ошибкой уже отправлен, возвращает false.
+func Load(w http.ResponseWriter, r *http.Request, log *slog.Logger, getter JobGetter) (models.Job, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.InfoContext(r.Context(), "invalid job id", sl.Err(err))
+		resp.WriteProblem(w, r, resp.NewLocalizedProblem(r, http.StatusBadRequest, i18n.MsgInvalidRequest))
+		return models.Job{}, false
+	}
+
+	job, err := getter.JobByID(r.Context(), id)
+	if errors.Is(err, storage.ErrJobNotFound) {
+		resp.WriteProblem(w, r, resp.NewLocalizedProblem(r, http.StatusNotFound, i18n.MsgNotFound))
+		return job, false
+	}
+	if err != nil {
+		log.ErrorContext(r.Context(), "failed to get job", sl.Err(err))
+		resp.WriteProblem(w, r, resp.NewLocalizedProblem(r, http.StatusInternalServerError, i18n.MsgInternalError))
+		return job, false
+	}
+
+	// о чужой задаче не сообщаем даже то, что она есть
+	principal, _ := auth.PrincipalFromContext(r.Context())
+	if !jobs.Visible(job, principal) {
+		log.InfoContext(r.Context(), "job belongs to another user", slog.Int64("job_id", id))
+		resp.WriteProblem(w, r, resp.NewLocalizedProblem(r, http.StatusNotFound, i18n.MsgNotFound))
+		return models.Job{}, false
+	}
+
+	return job, true
+}
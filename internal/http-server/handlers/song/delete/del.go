@@ -2,11 +2,12 @@ package del
 
 import (
 	resp "RestApi_v1/internal/config/internal/lib/api/response"
+	"RestApi_v1/internal/config/internal/lib/i18n"
 	"RestApi_v1/internal/config/internal/lib/logger/sl"
 	"RestApi_v1/internal/config/internal/storage"
+	"context"
 	"errors"
 	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
 
 	"log/slog"
@@ -14,40 +15,56 @@ import (
 )
 
 type SongDelete interface {
-	DeleteSong(songName string) (string, error)
+	DeleteSong(ctx context.Context, songName string) (string, error)
 }
 
+// New удаляет песню по названию
+//
+// @Summary      Delete song
+// @Description  Удаляет песню по названию. Нужна роль admin.
+// @Tags         songs
+// @Produce      json
+// @Param        song  path      string  true  "song title"
+// @Param        Idempotency-Key  header  string  false  "repeat with the same key returns the stored response"
+// @Success      200   {string}  string  "title of the deleted song"
+// @Failure      401   {object}  response.Response  "no credentials"
+// @Failure      403   {object}  response.Response  "role is lower than admin"
+// @Failure      409   {object}  response.Problem   "request with this Idempotency-Key is in progress"
+// @Failure      422   {object}  response.Problem   "Idempotency-Key reused with a different request"
+// @Failure      429   {object}  response.Problem   "rate limit exceeded"
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /{song} [delete]
 func New(log *slog.Logger, songDelete SongDelete) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		const op = "handlers.song.save.New"
+		const op = "handlers.song.delete.New"
 
-		log = log.With(
+		log := log.With(
 			slog.String("op", op),
-			slog.String("request_id", middleware.GetReqID(r.Context())),
 		)
 
 		song := chi.URLParam(r, "song")
 
 		if song == "" {
-			log.Info("song is empty")
+			log.InfoContext(r.Context(), "song is empty")
 
-			render.JSON(w, r, resp.Error("invalid request"))
+			render.JSON(w, r, resp.LocalizedError(r, i18n.MsgInvalidRequest))
 			return
 		}
 
-		resSong, err := songDelete.DeleteSong(song)
+		resSong, err := songDelete.DeleteSong(r.Context(), song)
 		if errors.Is(err, storage.ErrSongNotFound) {
-			log.Info("song not found", "song", song)
-			render.JSON(w, r, resp.Error("not found"))
+			log.InfoContext(r.Context(), "song not found", "song", song)
+			render.JSON(w, r, resp.LocalizedError(r, i18n.MsgNotFound))
 			return
 		}
 		if err != nil {
-			log.Error(op, "failed to delete song", sl.Err(err))
-			render.JSON(w, r, resp.Error("internal server error"))
+			log.ErrorContext(r.Context(), op, "failed to delete song", sl.Err(err))
+			render.JSON(w, r, resp.LocalizedError(r, i18n.MsgInternalError))
 			return
 		}
 
-		log.Info("delete song", slog.String("song", resSong))
+		log.InfoContext(r.Context(), "delete song", slog.String("song", resSong))
 		render.JSON(w, r, resSong)
 
 	}
@@ -0,0 +1,208 @@
+package export
+
+import (
+	resp "RestApi_v1/internal/config/internal/lib/api/response"
+	"RestApi_v1/internal/config/internal/lib/i18n"
+	"RestApi_v1/internal/config/internal/lib/logger/sl"
+	"RestApi_v1/internal/config/internal/lib/validate"
+	"RestApi_v1/internal/config/internal/models"
+	"RestApi_v1/internal/config/internal/storage"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/render"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	ContentTypeNDJSON = "application/x-ndjson"
+
+	// сколько строк читаем из курсора за раз
+	batchSize = 500
+
+	// буфер отправляется клиенту каждые flushRows строк или flushInterval
+	flushRows     = 500
+	flushInterval = time.Second
+)
+
+type SongExporter interface {
+	ExportSongs(ctx context.Context, filter storage.SongFilter, batchSize int, fn func(models.Song) error) error
+}
+
+// New выгружает каталог построчно в NDJSON, не собирая его в памяти
+//
+// @Summary      Export songs
+// @Description  Потоковая выгрузка песен в NDJSON, по одной песне в строке. С Accept-Encoding: gzip ответ сжимается. Нужна роль reader.
+// @Tags         songs
+// @Produce      application/x-ndjson
+// @Param        group  query     string  false  "exact group name, case-insensitive"
+// @Param        song   query     string  false  "substring of the song title"
+// @Param        from   query     string  false  "released on or after, 2006-01-02 or 02.01.2006"
+// @Param        to     query     string  false  "released on or before, 2006-01-02 or 02.01.2006"
+// @Success      200    {object}  models.Song  "one JSON object per line"
+// @Failure      400    {object}  response.Response
+// @Failure      401    {object}  response.Response  "no credentials"
+// @Failure      429    {object}  response.Problem   "rate limit exceeded"
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /songs/export [get]
+func New(log *slog.Logger, exporter SongExporter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.song.export.New"
+
+		log := log.With(
+			slog.String("op", op),
+		)
+
+		filter, err := parseFilter(r)
+		if err != nil {
+			log.InfoContext(r.Context(), "invalid export filter", sl.Err(err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.LocalizedError(r, i18n.MsgInvalidRequest))
+			return
+		}
+
+		s := newStream(w, r)
+
+		err = exporter.ExportSongs(r.Context(), filter, batchSize, s.write)
+		if err == nil && !s.started {
+			// пустая выгрузка - тоже успешный ответ
+			s.start()
+		}
+		if closeErr := s.close(); err == nil {
+			err = closeErr
+		}
+
+		switch {
+		case errors.Is(err, context.Canceled):
+			log.InfoContext(r.Context(), "export interrupted by client", slog.Int("songs", s.count))
+		case err != nil && !s.started:
+			log.ErrorContext(r.Context(), "failed to export songs", sl.Err(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.LocalizedError(r, i18n.MsgInternalError))
+		case err != nil:
+			// заголовки уже отправлены, клиент увидит оборванный поток
+			log.ErrorContext(r.Context(), "export failed mid-stream", sl.Err(err), slog.Int("songs", s.count))
+		default:
+			log.InfoContext(r.Context(), "songs exported", slog.Int("songs", s.count))
+		}
+	}
+}
+
+func parseFilter(r *http.Request) (storage.SongFilter, error) {
+	q := r.URL.Query()
+
+	filter := storage.SongFilter{
+		Group: q.Get("group"),
+		Song:  q.Get("song"),
+	}
+
+	for param, dst := range map[string]**time.Time{"from": &filter.ReleasedFrom, "to": &filter.ReleasedTo} {
+		if v := q.Get(param); v != "" {
+			t, err := validate.ParseDate(v)
+			if err != nil {
+				return filter, err
+			}
+			*dst = &t
+		}
+	}
+
+	return filter, nil
+}
+
+// stream пишет заголовки и открывает gzip только на первой строке,
+// чтобы до нее еще можно было ответить обычной ошибкой
+type stream struct {
+	w  http.ResponseWriter
+	r  *http.Request
+	rc *http.ResponseController
+
+	out       io.Writer
+	gz        *gzip.Writer
+	enc       *json.Encoder
+	started   bool
+	count     int
+	lastFlush time.Time
+}
+
+func newStream(w http.ResponseWriter, r *http.Request) *stream {
+	return &stream{w: w, r: r, rc: http.NewResponseController(w)}
+}
+
+func (s *stream) start() {
+	s.started = true
+	s.lastFlush = time.Now()
+
+	// общий WriteTimeout сервера оборвал бы длинную выгрузку
+	_ = s.rc.SetWriteDeadline(time.Time{})
+
+	h := s.w.Header()
+	h.Set("Content-Type", ContentTypeNDJSON)
+	h.Add("Vary", "Accept-Encoding")
+
+	s.out = s.w
+	if acceptsGzip(s.r) {
+		h.Set("Content-Encoding", "gzip")
+		s.gz = gzip.NewWriter(s.w)
+		s.out = s.gz
+	}
+
+	s.w.WriteHeader(http.StatusOK)
+	s.enc = json.NewEncoder(s.out)
+}
+
+func (s *stream) write(song models.Song) error {
+	if err := s.r.Context().Err(); err != nil {
+		return err
+	}
+
+	if !s.started {
+		s.start()
+	}
+
+	if err := s.enc.Encode(song); err != nil {
+		return err
+	}
+	s.count++
+
+	if s.count%flushRows == 0 || time.Since(s.lastFlush) >= flushInterval {
+		return s.flush()
+	}
+	return nil
+}
+
+func (s *stream) flush() error {
+	s.lastFlush = time.Now()
+
+	if s.gz != nil {
+		if err := s.gz.Flush(); err != nil {
+			return err
+		}
+	}
+	if err := s.rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	return nil
+}
+
+func (s *stream) close() error {
+	if s.gz != nil {
+		return s.gz.Close()
+	}
+	return nil
+}
+
+func acceptsGzip(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		enc, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if strings.TrimSpace(enc) == "gzip" && strings.TrimSpace(params) != "q=0" {
+			return true
+		}
+	}
+	return false
+}
@@ -1,18 +1,20 @@
 package get
 
 import (
+	"RestApi_v1/internal/config/internal/http-server/middleware/httpcache"
 	resp "RestApi_v1/internal/config/internal/lib/api/response"
+	"RestApi_v1/internal/config/internal/lib/i18n"
 	"RestApi_v1/internal/config/internal/lib/logger/sl"
 	"RestApi_v1/internal/config/internal/models"
 	"RestApi_v1/internal/config/internal/storage"
 	"context"
 	"errors"
 	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
 	"log/slog"
 	"net/http"
 	"strconv"
+	"time"
 )
 
 // интерфейс для получения песни с пагинацией
@@ -20,49 +22,62 @@ type SongGetter interface {
 	GetSongWithPagination(ctx context.Context, id int, page int, pageSize int) ([]models.Song, error)
 }
 
+// New возвращает песни по id с пагинацией
+//
+// @Summary      Get song
+// @Description  Возвращает песню по id, page и pageSize задают страницу выдачи.
+// @Tags         songs
+// @Produce      json
+// @Produce      xml
+// @Produce      text/csv
+// @Produce      application/yaml
+// @Param        id        path      int  true  "song id"    minimum(1)
+// @Param        page      path      int  true  "page number"  minimum(1)
+// @Param        pageSize  path      int  true  "page size"    minimum(1)
+// @Param        format    query     string  false  "response format, overrides Accept: json, xml, csv or yaml"
+// @Param        If-None-Match      header  string  false  "ETag of a previously received response"
+// @Param        If-Modified-Since  header  string  false  "Last-Modified of a previously received response"
+// @Success      200       {array}   models.Song
+// @Success      304       "song has not changed"
+// @Failure      406       {object}  response.Problem  "format is not supported"
+// @Failure      429       {object}  response.Problem  "rate limit exceeded"
+// @Router       /{id}/{page}/{pageSize} [get]
 func New(log *slog.Logger, songGetter SongGetter) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.song.get.New"
 
-		log = log.With(
+		log := log.With(
 			slog.String("op", op),
-			slog.String("request_id", middleware.GetReqID(r.Context())),
 		)
 
-		id, err := strconv.Atoi(chi.URLParam(r, "id"))
-		if err != nil || id <= 0 {
-			log.Info("invalid song id", "id", id)
-			render.JSON(w, r, resp.Error("invalid request"))
-			return
-		}
-
-		page, err := strconv.Atoi(chi.URLParam(r, "page"))
-		if err != nil || page <= 0 {
-			log.Info("invalid page number", "page", page)
-			render.JSON(w, r, resp.Error("invalid request"))
-			return
-		}
-
-		pageSize, err := strconv.Atoi(chi.URLParam(r, "pageSize"))
-		if err != nil || pageSize <= 0 {
-			log.Info("invalid page size", "pageSize", pageSize)
-			render.JSON(w, r, resp.Error("invalid request"))
-			return
-		}
+		// параметры уже проверены по спецификации в middleware/openapi
+		id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+		page, _ := strconv.Atoi(chi.URLParam(r, "page"))
+		pageSize, _ := strconv.Atoi(chi.URLParam(r, "pageSize"))
 
 		songs, err := songGetter.GetSongWithPagination(r.Context(), id, page, pageSize)
 		if errors.Is(err, storage.ErrSongNotFound) {
-			log.Info("song not found", "song id", id)
-			render.JSON(w, r, resp.Error("not found"))
+			log.InfoContext(r.Context(), "song not found", "song id", id)
+			render.Respond(w, r, resp.LocalizedError(r, i18n.MsgNotFound))
 			return
 		}
 		if err != nil {
-			log.Error(op, "failed to get song", sl.Err(err))
-			render.JSON(w, r, resp.Error("internal server error"))
+			log.ErrorContext(r.Context(), op, "failed to get song", sl.Err(err))
+			render.Respond(w, r, resp.LocalizedError(r, i18n.MsgInternalError))
 			return
 		}
 
-		log.Info("got songs", "songs", songs)
-		render.JSON(w, r, songs)
+		log.InfoContext(r.Context(), "got songs", "songs", songs)
+
+		var lastModified time.Time
+		for _, song := range songs {
+			if song.UpdatedAt.After(lastModified) {
+				lastModified = song.UpdatedAt
+			}
+		}
+		httpcache.SetLastModified(w, lastModified)
+
+		// формат ответа выбирается по Accept или ?format=
+		render.Respond(w, r, models.Songs(songs))
 	}
 }
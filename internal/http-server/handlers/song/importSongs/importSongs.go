@@ -0,0 +1,105 @@
+package importSongs
+
+import (
+	resp "RestApi_v1/internal/config/internal/lib/api/response"
+	"RestApi_v1/internal/config/internal/lib/i18n"
+	"RestApi_v1/internal/config/internal/lib/logger/sl"
+	"RestApi_v1/internal/config/internal/lib/songimport"
+	"errors"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+// MaxBodyBytes ограничение на размер загружаемого файла
+const MaxBodyBytes = 32 << 20
+
+// New загружает пачку песен из NDJSON, JSON-массива или CSV
+//
+// @Summary      Import songs
+// @Description  Пакетная загрузка песен. Тело - NDJSON (по объекту save.Request в строке), JSON-массив таких объектов или CSV с заголовком (song, group, text_song/text, date_song/release_date, link_song/link).
+// @Description  mode=best_effort (по умолчанию) сохраняет корректные строки, mode=atomic - все или ничего, тогда при ошибках ответ 422.
+// @Description  В отчете для каждой строки статус created, duplicate, invalid или skipped. Нужна роль editor.
+// @Tags         songs
+// @Accept       application/x-ndjson
+// @Accept       json
+// @Accept       text/csv
+// @Produce      json
+// @Param        mode     query     string             false  "atomic or best_effort"  Enums(atomic, best_effort)
+// @Param        request  body      []save.Request     true   "songs to import"
+// @Param        Idempotency-Key  header  string  false  "repeat with the same key returns the stored response"
+// @Success      200      {object}  songimport.Report  "per-row report"
+// @Failure      400      {object}  response.Problem   "unknown mode or unreadable body"
+// @Failure      401      {object}  response.Response  "no credentials"
+// @Failure      403      {object}  response.Response  "role is lower than editor"
+// @Failure      413      {object}  response.Problem   "body is too large"
+// @Failure      415      {object}  response.Problem   "unsupported content type"
+// @Failure      422      {object}  songimport.Report  "atomic import is not applied (or Idempotency-Key reused, as a problem)"
+// @Failure      409      {object}  response.Problem   "request with this Idempotency-Key is in progress"
+// @Failure      429      {object}  response.Problem   "rate limit exceeded"
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @x-raw-body   true
+// @Router       /songs/import [post]
+func New(log *slog.Logger, importer songimport.Importer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.song.importSongs.New"
+
+		log := log.With(
+			slog.String("op", op),
+		)
+
+		mode, err := songimport.ParseMode(r.URL.Query().Get("mode"))
+		if err != nil {
+			log.InfoContext(r.Context(), "invalid import mode", sl.Err(err))
+			resp.WriteProblem(w, r, resp.NewLocalizedProblem(r, http.StatusBadRequest, i18n.MsgInvalidRequest))
+			return
+		}
+
+		body := http.MaxBytesReader(w, r.Body, MaxBodyBytes)
+		records, err := songimport.Decode(body, r.Header.Get("Content-Type"))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			switch {
+			case errors.As(err, &tooLarge):
+				log.InfoContext(r.Context(), "import body is too large", sl.Err(err))
+				resp.WriteProblem(w, r, resp.NewLocalizedProblem(r, http.StatusRequestEntityTooLarge, i18n.MsgPayloadTooLarge, strconv.Itoa(MaxBodyBytes)))
+			case errors.Is(err, songimport.ErrUnsupportedFormat):
+				log.InfoContext(r.Context(), "unsupported import format", sl.Err(err))
+				resp.WriteProblem(w, r, resp.NewLocalizedProblem(r, http.StatusUnsupportedMediaType, i18n.MsgUnsupportedMedia))
+			default:
+				log.InfoContext(r.Context(), "failed to decode import body", sl.Err(err))
+				resp.WriteProblem(w, r, resp.NewLocalizedProblem(r, http.StatusBadRequest, i18n.MsgFailedToDecode))
+			}
+			return
+		}
+
+		if len(records) == 0 {
+			log.InfoContext(r.Context(), "import body is empty")
+			resp.WriteProblem(w, r, resp.NewLocalizedProblem(r, http.StatusBadRequest, i18n.MsgEmptyRequest))
+			return
+		}
+
+		log.InfoContext(r.Context(), "importing songs", slog.Int("rows", len(records)), slog.String("mode", string(mode)))
+
+		report, err := songimport.Run(r.Context(), importer, records, mode, i18n.FromRequest(r))
+		if err != nil {
+			log.ErrorContext(r.Context(), "failed to import songs", sl.Err(err))
+			resp.WriteProblem(w, r, resp.NewLocalizedProblem(r, http.StatusInternalServerError, i18n.MsgInternalError))
+			return
+		}
+
+		log.InfoContext(r.Context(), "songs imported",
+			slog.Bool("committed", report.Committed),
+			slog.Int("created", report.Created),
+			slog.Int("duplicates", report.Duplicates),
+			slog.Int("invalid", report.Invalid),
+		)
+
+		if mode == songimport.ModeAtomic && !report.Committed {
+			render.Status(r, http.StatusUnprocessableEntity)
+		}
+		render.JSON(w, r, report)
+	}
+}
@@ -2,10 +2,12 @@ package save
 
 import (
 	resp "RestApi_v1/internal/config/internal/lib/api/response"
+	"RestApi_v1/internal/config/internal/lib/i18n"
 	"RestApi_v1/internal/config/internal/lib/logger/sl"
+	"RestApi_v1/internal/config/internal/lib/validate"
 	"RestApi_v1/internal/config/internal/storage"
+	"context"
 	"errors"
-	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
 	"github.com/go-playground/validator/v10"
 	"io"
@@ -13,12 +15,13 @@ import (
 	"net/http"
 )
 
+// Request длины полей совпадают с размерами колонок в таблице songs
 type Request struct {
-	Song     string `json:"song" validate:"required"`
-	Group    string `json:"group,omitempty"`
+	Song     string `json:"song" validate:"required,max=100" maxLength:"100"`
+	Group    string `json:"group,omitempty" validate:"omitempty,max=50" maxLength:"50"`
 	TextSong string `json:"text_song,omitempty"`
-	DateSong string `json:"date_song,omitempty"`
-	LinkSong string `json:"link_song,omitempty"`
+	DateSong string `json:"date_song,omitempty" validate:"omitempty,songdate" example:"2006-01-02"` // 2006-01-02 или 02.01.2006
+	LinkSong string `json:"link_song,omitempty" validate:"omitempty,url,max=255" maxLength:"255" format:"uri"`
 }
 
 type Response struct {
@@ -26,46 +29,67 @@ type Response struct {
 }
 
 type SongSaver interface {
-	SaveSong(SongToSave string, GroupToSave string, TextSongToSave string, DateToSave string, LinkToSave string) (int64, error)
+	SaveSong(ctx context.Context, SongToSave string, GroupToSave string, TextSongToSave string, DateToSave string, LinkToSave string) (int64, error)
 }
 
+// New добавляет песню в базу
+//
+// @Summary      Add song
+// @Description  Добавляет песню в базу данных. Нужна роль editor.
+// @Tags         songs
+// @Accept       json
+// @Produce      json
+// @Param        request  body      save.Request       true  "song to save"
+// @Param        Idempotency-Key  header  string  false  "repeat with the same key returns the stored response"
+// @Success      200      {object}  save.Response      "status OK, or status Error with a message"
+// @Failure      401      {object}  response.Response  "no credentials"
+// @Failure      403      {object}  response.Response  "role is lower than editor"
+// @Failure      409      {object}  response.Problem   "request with this Idempotency-Key is in progress"
+// @Failure      422      {object}  response.Problem   "Idempotency-Key reused with a different request"
+// @Failure      429      {object}  response.Problem   "rate limit exceeded"
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /song [post]
 func New(log *slog.Logger, songSaver SongSaver) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.song.save.New"
 
-		log = log.With(
+		log := log.With(
 			slog.String("op", op),
-			slog.String("request_id", middleware.GetReqID(r.Context())),
 		)
 
 		var req Request
 		err := render.DecodeJSON(r.Body, &req)
 		if err != nil {
 			if errors.Is(err, io.EOF) {
-				log.Error("request body is empty")
-				render.JSON(w, r, resp.Error("empty request"))
+				log.ErrorContext(r.Context(), "request body is empty")
+				render.JSON(w, r, resp.LocalizedError(r, i18n.MsgEmptyRequest))
 			} else {
-				log.Error("failed to decode request body", sl.Err(err))
-				render.JSON(w, r, resp.Error("failed to decode request"))
+				log.ErrorContext(r.Context(), "failed to decode request body", sl.Err(err))
+				render.JSON(w, r, resp.LocalizedError(r, i18n.MsgFailedToDecode))
 			}
 			return
 		}
 
-		log.Info("request body decoded", slog.Any("request", req))
+		log.InfoContext(r.Context(), "request body decoded", slog.Any("request", req))
 
-		if err := validator.New().Struct(req); err != nil {
+		if err := validate.Struct(req); err != nil {
 			if validateErr, ok := err.(validator.ValidationErrors); ok {
-				log.Error("invalid request", sl.Err(err))
-				render.JSON(w, r, resp.ValidationError(validateErr))
+				log.ErrorContext(r.Context(), "invalid request", sl.Err(err))
+				render.JSON(w, r, resp.LocalizedValidationError(r, validateErr))
 			} else {
-				log.Error("unexpected error during validation", sl.Err(err))
-				render.JSON(w, r, resp.Error("validation error"))
+				log.ErrorContext(r.Context(), "unexpected error during validation", sl.Err(err))
+				render.JSON(w, r, resp.LocalizedError(r, i18n.MsgValidationError))
 			}
 			return
 		}
 
-		log.Info("Saving song", slog.String("song", req.Song), slog.String("group", req.Group), slog.String("textSong", req.TextSong), slog.String("linkSong", req.LinkSong))
+		// в базу дата уходит в формате 2006-01-02
+		req.DateSong, _ = validate.NormalizeDate(req.DateSong)
+
+		log.InfoContext(r.Context(), "Saving song", slog.String("song", req.Song), slog.String("group", req.Group), slog.String("textSong", req.TextSong), slog.String("linkSong", req.LinkSong))
 		id, err := songSaver.SaveSong(
+			r.Context(),
 			req.Song,
 			req.Group,
 			req.TextSong,
@@ -73,17 +97,17 @@ func New(log *slog.Logger, songSaver SongSaver) http.HandlerFunc {
 			req.LinkSong,
 		)
 		if errors.Is(err, storage.ErrSongExist) {
-			log.Info("song already exists", slog.String("song", req.Song))
-			render.JSON(w, r, resp.Error("song already exists"))
+			log.InfoContext(r.Context(), "song already exists", slog.String("song", req.Song))
+			render.JSON(w, r, resp.LocalizedError(r, i18n.MsgSongExists))
 			return
 		}
 		if err != nil {
-			log.Error("failed to add song", sl.Err(err))
-			render.JSON(w, r, resp.Error("failed to add song"))
+			log.ErrorContext(r.Context(), "failed to add song", sl.Err(err))
+			render.JSON(w, r, resp.LocalizedError(r, i18n.MsgFailedToAddSong))
 			return
 		}
 
-		log.Info("song added", slog.Int64("id", id))
+		log.InfoContext(r.Context(), "song added", slog.Int64("id", id))
 		responseOK(w, r)
 	}
 }
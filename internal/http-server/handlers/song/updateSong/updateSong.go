@@ -2,20 +2,24 @@ package updateSong
 
 import (
 	resp "RestApi_v1/internal/config/internal/lib/api/response"
+	"RestApi_v1/internal/config/internal/lib/i18n"
 	"RestApi_v1/internal/config/internal/lib/logger/sl"
+	"RestApi_v1/internal/config/internal/lib/validate"
+	"context"
 	"github.com/go-chi/render"
 	"github.com/go-playground/validator/v10"
 	"log/slog"
 	"net/http"
 )
 
+// Request длины полей совпадают с размерами колонок в таблице songs
 type Request struct {
-	ID       int    `json:"id"`
-	Song     string `json:"song"`
-	Group    string `json:"group,omitempty"`
+	ID       int    `json:"id" validate:"required,min=1" minimum:"1"`
+	Song     string `json:"song" validate:"required,max=100" maxLength:"100"`
+	Group    string `json:"group,omitempty" validate:"omitempty,max=50" maxLength:"50"`
 	TextSong string `json:"text_song,omitempty"`
-	DateSong string `json:"date_song,omitempty"`
-	LinkSong string `json:"link_song,omitempty"`
+	DateSong string `json:"date_song,omitempty" validate:"omitempty,songdate" example:"2006-01-02"` // 2006-01-02 или 02.01.2006
+	LinkSong string `json:"link_song,omitempty" validate:"omitempty,url,max=255" maxLength:"255" format:"uri"`
 }
 
 type Response struct {
@@ -23,28 +27,59 @@ type Response struct {
 }
 
 type SongUpdater interface {
-	UpdateSong(ID int, SongToSave string, GroupToSave string, TextSongToSave string, DateToSave string, LinkToSave string) (string, error)
+	UpdateSong(ctx context.Context, ID int, SongToSave string, GroupToSave string, TextSongToSave string, DateToSave string, LinkToSave string) (string, error)
 }
 
+// New обновляет песню по id из тела запроса
+//
+// @Summary      Update song
+// @Description  Изменяет песню, поиск идет по id. Нужна роль editor.
+// @Tags         songs
+// @Accept       json
+// @Produce      json
+// @Param        request  body      updateSong.Request  true  "song with id"
+// @Param        Idempotency-Key  header  string  false  "repeat with the same key returns the stored response"
+// @Success      200      {object}  updateSong.Response  "status OK, or status Error with a message"
+// @Failure      401      {object}  response.Response    "no credentials"
+// @Failure      403      {object}  response.Response    "role is lower than editor"
+// @Failure      409      {object}  response.Problem     "request with this Idempotency-Key is in progress"
+// @Failure      422      {object}  response.Problem     "Idempotency-Key reused with a different request"
+// @Failure      429      {object}  response.Problem     "rate limit exceeded"
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /edit [put]
 func New(log *slog.Logger, songUpdater SongUpdater) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		log = log.With(slog.String("op", "handlers.song.save.New"))
+		const op = "handlers.song.updateSong.New"
+
+		log := log.With(
+			slog.String("op", op),
+		)
 
 		var req Request
 		err := render.DecodeJSON(r.Body, &req)
 		if err != nil {
-			log.Error("failed to decode request body", sl.Err(err))
-			render.JSON(w, r, resp.Error("failed to decode request"))
+			log.ErrorContext(r.Context(), "failed to decode request body", sl.Err(err))
+			render.JSON(w, r, resp.LocalizedError(r, i18n.MsgFailedToDecode))
 			return
 		}
 
-		if err := validator.New().Struct(req); err != nil {
-			log.Error("invalid request", sl.Err(err))
-			render.JSON(w, r, resp.ValidationError(err.(validator.ValidationErrors)))
+		if err := validate.Struct(req); err != nil {
+			if validateErr, ok := err.(validator.ValidationErrors); ok {
+				log.ErrorContext(r.Context(), "invalid request", sl.Err(err))
+				render.JSON(w, r, resp.LocalizedValidationError(r, validateErr))
+			} else {
+				log.ErrorContext(r.Context(), "unexpected error during validation", sl.Err(err))
+				render.JSON(w, r, resp.LocalizedError(r, i18n.MsgValidationError))
+			}
 			return
 		}
 
+		// в базу дата уходит в формате 2006-01-02
+		req.DateSong, _ = validate.NormalizeDate(req.DateSong)
+
 		_, err = songUpdater.UpdateSong(
+			r.Context(),
 			req.ID,
 			req.Song,
 			req.Group,
@@ -52,12 +87,12 @@ func New(log *slog.Logger, songUpdater SongUpdater) http.HandlerFunc {
 			req.DateSong,
 			req.LinkSong,
 		)
-		log.Info("Updating song with ID", slog.Int("ID", req.ID))
+		log.InfoContext(r.Context(), "Updating song with ID", slog.Int("ID", req.ID))
 
 		if err != nil {
 
-			log.Error("failed to update song", sl.Err(err))
-			render.JSON(w, r, resp.Error("failed to update song"))
+			log.ErrorContext(r.Context(), "failed to update song", sl.Err(err))
+			render.JSON(w, r, resp.LocalizedError(r, i18n.MsgFailedToUpdateSong))
 			return
 		}
 
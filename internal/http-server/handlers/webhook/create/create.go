@@ -0,0 +1,114 @@
+package create
+
+import (
+	resp "RestApi_v1/internal/config/internal/lib/api/response"
+	"RestApi_v1/internal/config/internal/lib/auth"
+	"RestApi_v1/internal/config/internal/lib/i18n"
+	"RestApi_v1/internal/config/internal/lib/logger/sl"
+	"RestApi_v1/internal/config/internal/lib/validate"
+	"RestApi_v1/internal/config/internal/lib/webhooks"
+	"RestApi_v1/internal/config/internal/models"
+	"RestApi_v1/internal/config/internal/storage"
+	"context"
+	"errors"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+)
+
+type Request struct {
+	URL    string   `json:"url" validate:"required,http_url,max=2048" format:"uri" maxLength:"2048" example:"https://partner.example.com/hooks/songs"`
+	Events []string `json:"events" validate:"required,min=1,dive,oneof=song.created song.updated song.deleted" minItems:"1" enums:"song.created,song.updated,song.deleted"`
+	// Secret ключ HMAC-подписи, без него генерируется случайный
+	Secret string `json:"secret,omitempty" validate:"omitempty,min=16,max=255" minLength:"16" maxLength:"255"`
+}
+
+type WebhookCreator interface {
+	CreateWebhook(ctx context.Context, webhook storage.NewWebhook) (models.WebhookSubscription, error)
+}
+
+// New создает подписку на события песен
+//
+// @Summary      Create webhook
+// @Description  Подписывает URL на события песен. Каждое событие приходит POST-запросом с JSON и заголовком X-Webhook-Signature: sha256=HMAC-SHA256(secret, X-Webhook-Timestamp + "." + тело).
+// @Description  Секрет возвращается только в этом ответе. Нужна роль admin.
+// @Tags         webhooks
+// @Accept       json
+// @Produce      json
+// @Param        request  body      create.Request  true  "subscription"
+// @Param        Idempotency-Key  header  string  false  "repeat with the same key returns the stored response"
+// @Success      201      {object}  webhooks.Subscription
+// @Failure      400      {object}  response.Problem   "invalid subscription"
+// @Failure      401      {object}  response.Response  "no credentials"
+// @Failure      403      {object}  response.Response  "role is too low"
+// @Failure      409      {object}  response.Problem   "request with this Idempotency-Key is in progress"
+// @Failure      422      {object}  response.Problem   "Idempotency-Key reused with a different request"
+// @Failure      429      {object}  response.Problem   "rate limit exceeded"
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /webhooks [post]
+func New(log *slog.Logger, creator WebhookCreator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.webhook.create.New"
+
+		log := log.With(
+			slog.String("op", op),
+		)
+
+		var req Request
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			msg := i18n.MsgFailedToDecode
+			if errors.Is(err, io.EOF) {
+				msg = i18n.MsgEmptyRequest
+			}
+			log.InfoContext(r.Context(), "failed to decode request body", sl.Err(err))
+			resp.WriteProblem(w, r, resp.NewLocalizedProblem(r, http.StatusBadRequest, msg))
+			return
+		}
+
+		if err := validate.Struct(req); err != nil {
+			var validateErrs validator.ValidationErrors
+			if errors.As(err, &validateErrs) {
+				log.InfoContext(r.Context(), "invalid webhook", sl.Err(err))
+				resp.WriteProblem(w, r, resp.LocalizedValidationProblem(r, validateErrs))
+				return
+			}
+			log.ErrorContext(r.Context(), "unexpected error during validation", sl.Err(err))
+			resp.WriteProblem(w, r, resp.NewLocalizedProblem(r, http.StatusInternalServerError, i18n.MsgInternalError))
+			return
+		}
+
+		if req.Secret == "" {
+			secret, err := webhooks.NewSecret()
+			if err != nil {
+				log.ErrorContext(r.Context(), "failed to generate webhook secret", sl.Err(err))
+				resp.WriteProblem(w, r, resp.NewLocalizedProblem(r, http.StatusInternalServerError, i18n.MsgInternalError))
+				return
+			}
+			req.Secret = secret
+		}
+
+		principal, _ := auth.PrincipalFromContext(r.Context())
+
+		slices.Sort(req.Events)
+		sub, err := creator.CreateWebhook(r.Context(), storage.NewWebhook{
+			URL:       req.URL,
+			Events:    slices.Compact(req.Events),
+			Secret:    req.Secret,
+			CreatedBy: principal.String(),
+		})
+		if err != nil {
+			log.ErrorContext(r.Context(), "failed to create webhook", sl.Err(err))
+			resp.WriteProblem(w, r, resp.NewLocalizedProblem(r, http.StatusInternalServerError, i18n.MsgInternalError))
+			return
+		}
+
+		log.InfoContext(r.Context(), "webhook created", slog.Int64("webhook_id", sub.ID), slog.String("url", sub.URL), slog.Any("events", sub.Events))
+
+		render.Status(r, http.StatusCreated)
+		render.JSON(w, r, webhooks.SubscriptionOf(sub, true))
+	}
+}
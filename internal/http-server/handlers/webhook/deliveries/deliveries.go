@@ -0,0 +1,100 @@
+package deliveries
+
+import (
+	resp "RestApi_v1/internal/config/internal/lib/api/response"
+	"RestApi_v1/internal/config/internal/lib/i18n"
+	"RestApi_v1/internal/config/internal/lib/logger/sl"
+	"RestApi_v1/internal/config/internal/lib/webhooks"
+	"RestApi_v1/internal/config/internal/models"
+	"RestApi_v1/internal/config/internal/storage"
+	"context"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+const (
+	defaultLimit = 50
+	maxLimit     = 500
+)
+
+type DeliveryLister interface {
+	WebhookDeliveries(ctx context.Context, subscriptionID int64, status string, limit int) ([]models.WebhookDelivery, error)
+}
+
+// New возвращает журнал доставок подписки
+//
+// @Summary      Webhook deliveries
+// @Description  Доставки событий подписке, новые первыми: статус, число попыток, код и ошибка последней попытки. Нужна роль admin.
+// @Tags         webhooks
+// @Produce      json
+// @Param        id      path      int     true   "webhook id"
+// @Param        status  query     string  false  "only deliveries in this status"  Enums(pending, delivered, dead)
+// @Param        limit   query     int     false  "max deliveries"  minimum(1)  maximum(500)  default(50)
+// @Success      200     {array}   webhooks.Delivery
+// @Failure      400     {object}  response.Problem   "invalid id or filter"
+// @Failure      401     {object}  response.Response  "no credentials"
+// @Failure      403     {object}  response.Response  "role is too low"
+// @Failure      404     {object}  response.Problem   "no such webhook"
+// @Failure      429     {object}  response.Problem   "rate limit exceeded"
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /webhooks/{id}/deliveries [get]
+func New(log *slog.Logger, lister DeliveryLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.webhook.deliveries.New"
+
+		log := log.With(
+			slog.String("op", op),
+		)
+
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			log.InfoContext(r.Context(), "invalid webhook id", sl.Err(err))
+			resp.WriteProblem(w, r, resp.NewLocalizedProblem(r, http.StatusBadRequest, i18n.MsgInvalidRequest))
+			return
+		}
+
+		q := r.URL.Query()
+
+		status := q.Get("status")
+		switch status {
+		case "", models.DeliveryPending, models.DeliveryDelivered, models.DeliveryDead:
+		default:
+			log.InfoContext(r.Context(), "invalid delivery status", slog.String("status", status))
+			resp.WriteProblem(w, r, resp.NewLocalizedProblem(r, http.StatusBadRequest, i18n.MsgInvalidRequest))
+			return
+		}
+
+		limit := defaultLimit
+		if v := q.Get("limit"); v != "" {
+			limit, err = strconv.Atoi(v)
+			if err != nil || limit < 1 || limit > maxLimit {
+				log.InfoContext(r.Context(), "invalid limit", slog.String("limit", v))
+				resp.WriteProblem(w, r, resp.NewLocalizedProblem(r, http.StatusBadRequest, i18n.MsgInvalidRequest))
+				return
+			}
+		}
+
+		list, err := lister.WebhookDeliveries(r.Context(), id, status, limit)
+		if errors.Is(err, storage.ErrWebhookNotFound) {
+			resp.WriteProblem(w, r, resp.NewLocalizedProblem(r, http.StatusNotFound, i18n.MsgNotFound))
+			return
+		}
+		if err != nil {
+			log.ErrorContext(r.Context(), "failed to list webhook deliveries", sl.Err(err))
+			resp.WriteProblem(w, r, resp.NewLocalizedProblem(r, http.StatusInternalServerError, i18n.MsgInternalError))
+			return
+		}
+
+		views := make([]webhooks.Delivery, 0, len(list))
+		for _, d := range list {
+			views = append(views, webhooks.DeliveryOf(d))
+		}
+
+		render.JSON(w, r, views)
+	}
+}
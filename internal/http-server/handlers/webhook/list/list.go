@@ -0,0 +1,54 @@
+package list
+
+import (
+	resp "RestApi_v1/internal/config/internal/lib/api/response"
+	"RestApi_v1/internal/config/internal/lib/i18n"
+	"RestApi_v1/internal/config/internal/lib/logger/sl"
+	"RestApi_v1/internal/config/internal/lib/webhooks"
+	"RestApi_v1/internal/config/internal/models"
+	"context"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+)
+
+type WebhookLister interface {
+	Webhooks(ctx context.Context) ([]models.WebhookSubscription, error)
+}
+
+// New возвращает все подписки без секретов
+//
+// @Summary      List webhooks
+// @Description  Все подписки на события песен, секреты не показываются. Нужна роль admin.
+// @Tags         webhooks
+// @Produce      json
+// @Success      200  {array}   webhooks.Subscription
+// @Failure      401  {object}  response.Response  "no credentials"
+// @Failure      403  {object}  response.Response  "role is too low"
+// @Failure      429  {object}  response.Problem   "rate limit exceeded"
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /webhooks [get]
+func New(log *slog.Logger, lister WebhookLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.webhook.list.New"
+
+		log := log.With(
+			slog.String("op", op),
+		)
+
+		subs, err := lister.Webhooks(r.Context())
+		if err != nil {
+			log.ErrorContext(r.Context(), "failed to list webhooks", sl.Err(err))
+			resp.WriteProblem(w, r, resp.NewLocalizedProblem(r, http.StatusInternalServerError, i18n.MsgInternalError))
+			return
+		}
+
+		views := make([]webhooks.Subscription, 0, len(subs))
+		for _, sub := range subs {
+			views = append(views, webhooks.SubscriptionOf(sub, false))
+		}
+
+		render.JSON(w, r, views)
+	}
+}
@@ -0,0 +1,75 @@
+package redeliver
+
+import (
+	resp "RestApi_v1/internal/config/internal/lib/api/response"
+	"RestApi_v1/internal/config/internal/lib/i18n"
+	"RestApi_v1/internal/config/internal/lib/logger/sl"
+	"RestApi_v1/internal/config/internal/storage"
+	"context"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+type DeliveryRetrier interface {
+	RetryWebhookDelivery(ctx context.Context, subscriptionID int64, deliveryID int64) error
+}
+
+// New ставит доставку на повтор с полным набором попыток, в том числе из dead
+//
+// @Summary      Redeliver webhook
+// @Description  Возвращает доставку в очередь со сброшенным счетчиком попыток, так можно повторить dead после исправления на стороне подписчика. Нужна роль admin.
+// @Tags         webhooks
+// @Produce      json
+// @Param        id          path  int  true  "webhook id"
+// @Param        deliveryId  path  int  true  "delivery id"
+// @Param        Idempotency-Key  header  string  false  "repeat with the same key returns the stored response"
+// @Success      202  "queued"
+// @Failure      400  {object}  response.Problem   "id is not a number"
+// @Failure      401  {object}  response.Response  "no credentials"
+// @Failure      403  {object}  response.Response  "role is too low"
+// @Failure      404  {object}  response.Problem   "no such delivery"
+// @Failure      409  {object}  response.Problem   "request with this Idempotency-Key is in progress"
+// @Failure      422  {object}  response.Problem   "Idempotency-Key reused with a different request"
+// @Failure      429  {object}  response.Problem   "rate limit exceeded"
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /webhooks/{id}/deliveries/{deliveryId}/retry [post]
+func New(log *slog.Logger, retrier DeliveryRetrier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.webhook.redeliver.New"
+
+		log := log.With(
+			slog.String("op", op),
+		)
+
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			log.InfoContext(r.Context(), "invalid webhook id", sl.Err(err))
+			resp.WriteProblem(w, r, resp.NewLocalizedProblem(r, http.StatusBadRequest, i18n.MsgInvalidRequest))
+			return
+		}
+		deliveryID, err := strconv.ParseInt(chi.URLParam(r, "deliveryId"), 10, 64)
+		if err != nil {
+			log.InfoContext(r.Context(), "invalid delivery id", sl.Err(err))
+			resp.WriteProblem(w, r, resp.NewLocalizedProblem(r, http.StatusBadRequest, i18n.MsgInvalidRequest))
+			return
+		}
+
+		err = retrier.RetryWebhookDelivery(r.Context(), id, deliveryID)
+		if errors.Is(err, storage.ErrDeliveryNotFound) {
+			resp.WriteProblem(w, r, resp.NewLocalizedProblem(r, http.StatusNotFound, i18n.MsgNotFound))
+			return
+		}
+		if err != nil {
+			log.ErrorContext(r.Context(), "failed to retry webhook delivery", sl.Err(err))
+			resp.WriteProblem(w, r, resp.NewLocalizedProblem(r, http.StatusInternalServerError, i18n.MsgInternalError))
+			return
+		}
+
+		log.InfoContext(r.Context(), "webhook delivery queued for retry", slog.Int64("webhook_id", id), slog.Int64("delivery_id", deliveryID))
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
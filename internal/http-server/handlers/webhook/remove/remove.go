@@ -0,0 +1,68 @@
+package remove
+
+import (
+	resp "RestApi_v1/internal/config/internal/lib/api/response"
+	"RestApi_v1/internal/config/internal/lib/i18n"
+	"RestApi_v1/internal/config/internal/lib/logger/sl"
+	"RestApi_v1/internal/config/internal/storage"
+	"context"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+type WebhookDeleter interface {
+	DeleteWebhook(ctx context.Context, id int64) error
+}
+
+// New удаляет подписку вместе с журналом доставок, неотправленные доставки отменяются
+//
+// @Summary      Delete webhook
+// @Description  Удаляет подписку и ее журнал доставок, неотправленные события ей больше не придут. Нужна роль admin.
+// @Tags         webhooks
+// @Produce      json
+// @Param        id   path      int  true  "webhook id"
+// @Param        Idempotency-Key  header  string  false  "repeat with the same key returns the stored response"
+// @Success      204  "deleted"
+// @Failure      400  {object}  response.Problem   "id is not a number"
+// @Failure      401  {object}  response.Response  "no credentials"
+// @Failure      403  {object}  response.Response  "role is too low"
+// @Failure      404  {object}  response.Problem   "no such webhook"
+// @Failure      409  {object}  response.Problem   "request with this Idempotency-Key is in progress"
+// @Failure      422  {object}  response.Problem   "Idempotency-Key reused with a different request"
+// @Failure      429  {object}  response.Problem   "rate limit exceeded"
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /webhooks/{id} [delete]
+func New(log *slog.Logger, deleter WebhookDeleter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.webhook.remove.New"
+
+		log := log.With(
+			slog.String("op", op),
+		)
+
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			log.InfoContext(r.Context(), "invalid webhook id", sl.Err(err))
+			resp.WriteProblem(w, r, resp.NewLocalizedProblem(r, http.StatusBadRequest, i18n.MsgInvalidRequest))
+			return
+		}
+
+		err = deleter.DeleteWebhook(r.Context(), id)
+		if errors.Is(err, storage.ErrWebhookNotFound) {
+			resp.WriteProblem(w, r, resp.NewLocalizedProblem(r, http.StatusNotFound, i18n.MsgNotFound))
+			return
+		}
+		if err != nil {
+			log.ErrorContext(r.Context(), "failed to delete webhook", sl.Err(err))
+			resp.WriteProblem(w, r, resp.NewLocalizedProblem(r, http.StatusInternalServerError, i18n.MsgInternalError))
+			return
+		}
+
+		log.InfoContext(r.Context(), "webhook deleted", slog.Int64("webhook_id", id))
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
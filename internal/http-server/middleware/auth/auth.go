@@ -0,0 +1,84 @@
+package auth
+
+import (
+	resp "RestApi_v1/internal/config/internal/lib/api/response"
+	"RestApi_v1/internal/config/internal/lib/auth"
+	"RestApi_v1/internal/config/internal/lib/i18n"
+	"RestApi_v1/internal/config/internal/lib/logger/sl"
+	"errors"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// New аутентифицирует запрос по API-ключу или bearer-токену и кладет пользователя в контекст.
+// Запрос без учетных данных проходит анонимно, с неверными - получает 401.
+func New(log *slog.Logger, header string, authenticator *auth.Authenticator) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		log := log.With(
+			slog.String("component", "middleware/auth"),
+		)
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			var (
+				principal auth.Principal
+				err       error
+			)
+
+			switch {
+			case r.Header.Get(header) != "":
+				principal, err = authenticator.APIKey(r.Context(), r.Header.Get(header))
+			case strings.HasPrefix(r.Header.Get("Authorization"), "Bearer "):
+				principal, err = authenticator.Bearer(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+			default:
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if errors.Is(err, auth.ErrInvalidCredentials) {
+				log.InfoContext(r.Context(), "authentication failed", sl.Err(err))
+				unauthorized(w, r)
+				return
+			}
+			if err != nil {
+				log.ErrorContext(r.Context(), "failed to authenticate request", sl.Err(err))
+				render.Status(r, http.StatusInternalServerError)
+				render.JSON(w, r, resp.LocalizedError(r, i18n.MsgInternalError))
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+// Require пропускает только пользователей с ролью не ниже role
+func Require(role auth.Role) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := auth.PrincipalFromContext(r.Context())
+			if !ok {
+				unauthorized(w, r)
+				return
+			}
+			if !principal.Role.Allows(role) {
+				render.Status(r, http.StatusForbidden)
+				render.JSON(w, r, resp.LocalizedError(r, i18n.MsgForbidden))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+func unauthorized(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="songs"`)
+	render.Status(r, http.StatusUnauthorized)
+	render.JSON(w, r, resp.LocalizedError(r, i18n.MsgUnauthorized))
+}
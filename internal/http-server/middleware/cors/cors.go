@@ -0,0 +1,55 @@
+package cors
+
+import (
+	"RestApi_v1/internal/config/internal/http-server/middleware/idempotency"
+	"net/http"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-chi/cors"
+)
+
+// New отвечает на preflight и добавляет CORS-заголовки для разрешенных источников.
+// Список источников и max age берутся на каждый запрос, чтобы они менялись при перезагрузке конфига.
+// apiKeyHeader - заголовок с API-ключом из конфига, браузеру нужно разрешить его явно.
+func New(origins func() []string, apiKeyHeader string, maxAge func() time.Duration) func(next http.Handler) http.Handler {
+	options := func(maxAge time.Duration) cors.Options {
+		return cors.Options{
+			AllowOriginFunc: func(_ *http.Request, origin string) bool {
+				allowed := origins()
+				return slices.Contains(allowed, "*") || slices.Contains(allowed, origin)
+			},
+			AllowedMethods: []string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodDelete, http.MethodOptions},
+			AllowedHeaders: []string{
+				"Accept", "Accept-Language", "Authorization", "Content-Type", apiKeyHeader,
+				"Idempotency-Key", "If-Match", "If-None-Match", "If-Modified-Since", "Last-Event-ID",
+			},
+			ExposedHeaders: []string{
+				"ETag", "Last-Modified", "Location", "Retry-After", "Content-Language", "Content-Disposition", idempotency.ReplayedHeader,
+				"RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy",
+			},
+			MaxAge: int(maxAge.Seconds()),
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		// go-chi/cors принимает max age только при создании, поэтому обработчик
+		// пересобирается, когда значение в конфиге меняется
+		type built struct {
+			maxAge  time.Duration
+			handler http.Handler
+		}
+		var current atomic.Pointer[built]
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			age := maxAge()
+			b := current.Load()
+			if b == nil || b.maxAge != age {
+				b = &built{maxAge: age, handler: cors.Handler(options(age))(next)}
+				current.Store(b)
+			}
+			b.handler.ServeHTTP(w, r)
+		})
+	}
+}
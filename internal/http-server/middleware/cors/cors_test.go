@@ -0,0 +1,34 @@
+package cors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestMaxAgeReload(t *testing.T) {
+	maxAge := 10 * time.Minute
+	handler := New(func() []string { return []string{"*"} }, "X-API-Key", func() time.Duration { return maxAge })(
+		http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}),
+	)
+
+	preflight := func() string {
+		r := httptest.NewRequest(http.MethodOptions, "/songs", nil)
+		r.Header.Set("Origin", "https://example.com")
+		r.Header.Set("Access-Control-Request-Method", http.MethodGet)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Header().Get("Access-Control-Max-Age")
+	}
+
+	if got := preflight(); got != "600" {
+		t.Fatalf("Access-Control-Max-Age = %q, want %q", got, "600")
+	}
+
+	// после перезагрузки конфига
+	maxAge = time.Minute
+	if got := preflight(); got != "60" {
+		t.Errorf("Access-Control-Max-Age after reload = %q, want %q", got, "60")
+	}
+}
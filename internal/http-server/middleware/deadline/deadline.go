@@ -0,0 +1,24 @@
+package deadline
+
+import (
+	"net/http"
+	"time"
+)
+
+// New продлевает дедлайны чтения и записи соединения до timeout от начала запроса.
+// Общие ReadTimeout и WriteTimeout сервера рассчитаны на обычные запросы и оборвали бы
+// загрузку большого файла. Подключается до middleware, которые читают тело (идемпотентность).
+func New(timeout time.Duration) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			until := time.Now().Add(timeout)
+			rc := http.NewResponseController(w)
+			_ = rc.SetReadDeadline(until)
+			_ = rc.SetWriteDeadline(until)
+
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
@@ -0,0 +1,47 @@
+package deadline
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// Тело, которое идет дольше таймаутов сервера, дочитывается до конца.
+func TestSlowUpload(t *testing.T) {
+	echo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write(body)
+	})
+
+	srv := httptest.NewUnstartedServer(New(5 * time.Second)(echo))
+	srv.Config.ReadTimeout = 100 * time.Millisecond
+	srv.Config.WriteTimeout = 100 * time.Millisecond
+	srv.Start()
+	defer srv.Close()
+
+	body, upload := io.Pipe()
+	go func() {
+		for i := 0; i < 4; i++ {
+			_, _ = upload.Write([]byte("a"))
+			time.Sleep(50 * time.Millisecond)
+		}
+		_ = upload.Close()
+	}()
+
+	resp, err := http.Post(srv.URL, "text/plain", body)
+	if err != nil {
+		t.Fatalf("Post() error = %v", err)
+	}
+	defer resp.Body.Close()
+
+	got, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || string(got) != "aaaa" {
+		t.Errorf("response = %d %q, want 200 %q", resp.StatusCode, got, "aaaa")
+	}
+}
@@ -0,0 +1,139 @@
+package httpcache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// New добавляет к успешным ответам GET строгий ETag по содержимому тела и Cache-Control,
+// а на If-None-Match и If-Modified-Since с совпавшей версией отвечает 304 без тела.
+// Last-Modified ставит хендлер, если знает время изменения данных.
+// Vary: Accept ставит format.Respond, так что у каждого формата свой тег.
+// Тело ответа буферизуется, поэтому потоковые маршруты (выгрузка) сюда не подключаются.
+func New(cacheControl string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// заголовки хендлер пишет сразу в w, буферизуются только статус и тело
+			header := w.Header()
+			rec := &recorder{header: header}
+			next.ServeHTTP(rec, r)
+
+			status := rec.statusCode()
+			if status != http.StatusOK {
+				w.WriteHeader(status)
+				w.Write(rec.body.Bytes())
+				return
+			}
+
+			if header.Get("ETag") == "" {
+				header.Set("ETag", etag(rec.body.Bytes()))
+			}
+			if cacheControl != "" && header.Get("Cache-Control") == "" {
+				header.Set("Cache-Control", cacheControl)
+			}
+
+			if notModified(r, header) {
+				// RFC 9110: в 304 не передаются заголовки представления
+				header.Del("Content-Type")
+				header.Del("Content-Length")
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+
+			w.WriteHeader(status)
+			w.Write(rec.body.Bytes())
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+// etag строгий тег по содержимому: одинаковые байты - одинаковый тег на всех репликах
+func etag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// notModified проверяет условия запроса по RFC 9110: If-Modified-Since смотрим, только если нет If-None-Match
+func notModified(r *http.Request, header http.Header) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		return etagMatch(inm, header.Get("ETag"))
+	}
+
+	ims := r.Header.Get("If-Modified-Since")
+	lm := header.Get("Last-Modified")
+	if ims == "" || lm == "" {
+		return false
+	}
+
+	since, err := http.ParseTime(ims)
+	if err != nil {
+		return false
+	}
+	modified, err := http.ParseTime(lm)
+	if err != nil {
+		return false
+	}
+	// Last-Modified передается с точностью до секунды
+	return !modified.Truncate(time.Second).After(since)
+}
+
+// etagMatch слабое сравнение для If-None-Match: W/ не учитывается
+func etagMatch(list string, current string) bool {
+	current = strings.TrimPrefix(current, "W/")
+
+	for _, tag := range strings.Split(list, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == current {
+			return true
+		}
+	}
+	return false
+}
+
+type recorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (r *recorder) Header() http.Header {
+	return r.header
+}
+
+func (r *recorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.body.Write(b)
+}
+
+func (r *recorder) statusCode() int {
+	if r.status == 0 {
+		return http.StatusOK
+	}
+	return r.status
+}
+
+// SetLastModified ставит Last-Modified для проверки If-Modified-Since. Нулевое время пропускается.
+func SetLastModified(w http.ResponseWriter, t time.Time) {
+	if t.IsZero() {
+		return
+	}
+	w.Header().Set("Last-Modified", t.UTC().Format(http.TimeFormat))
+}
@@ -0,0 +1,55 @@
+package idempotency
+
+import (
+	"RestApi_v1/internal/config/internal/lib/logger/sl"
+	"context"
+	"log/slog"
+	"time"
+)
+
+type Purger interface {
+	PurgeIdempotencyKeys(ctx context.Context) (int64, error)
+}
+
+// Cleaner фоновый воркер, удаляющий истекшие ключи
+type Cleaner struct {
+	log      *slog.Logger
+	purger   Purger
+	interval time.Duration
+}
+
+func NewCleaner(log *slog.Logger, purger Purger, interval time.Duration) *Cleaner {
+	return &Cleaner{
+		log:      log.With(slog.String("component", "idempotency/cleaner")),
+		purger:   purger,
+		interval: interval,
+	}
+}
+
+func (c *Cleaner) Name() string {
+	return "idempotency-cleaner"
+}
+
+func (c *Cleaner) Run(ctx context.Context) error {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		deleted, err := c.purger.PurgeIdempotencyKeys(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				c.log.Error("failed to purge idempotency keys", sl.Err(err))
+			}
+			continue
+		}
+		if deleted > 0 {
+			c.log.Debug("expired idempotency keys purged", slog.Int64("deleted", deleted))
+		}
+	}
+}
@@ -0,0 +1,297 @@
+package idempotency
+
+import (
+	resp "RestApi_v1/internal/config/internal/lib/api/response"
+	"RestApi_v1/internal/config/internal/lib/auth"
+	"RestApi_v1/internal/config/internal/lib/i18n"
+	"RestApi_v1/internal/config/internal/lib/logger/sl"
+	"RestApi_v1/internal/config/internal/storage"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	Header = "Idempotency-Key"
+	// ReplayedHeader ставится на ответ, отданный из сохраненного
+	ReplayedHeader = "Idempotent-Replayed"
+
+	maxKeyLength = 255
+
+	// saveTimeout на запись ответа, когда клиент уже мог отключиться
+	saveTimeout = 3 * time.Second
+)
+
+// memoryBodyBytes тело до этого размера держится в памяти, больше - во временном файле
+var memoryBodyBytes int64 = 1 << 20
+
+// Store хранилище ключей идемпотентности
+type Store interface {
+	ReserveIdempotencyKey(ctx context.Context, scope string, key string, requestHash string, ttl time.Duration) (storage.IdempotencyRecord, bool, error)
+	CompleteIdempotencyKey(ctx context.Context, scope string, key string, status int, header map[string][]string, body []byte) error
+	DeleteIdempotencyKey(ctx context.Context, scope string, key string) error
+}
+
+// New поддерживает заголовок Idempotency-Key на изменяющих маршрутах.
+// Первый запрос с ключом выполняется, его ответ хранится ttl и отдается на повторы с тем же телом.
+// Тело с ключом принимается до maxBodyBytes: оно хешируется при чтении, большое ждет хендлер во временном файле.
+// Повтор с другим телом получает 422, повтор во время выполнения первого - 409.
+// Ответы 5xx не сохраняются: после сбоя запрос с тем же ключом можно повторить.
+// Запросы без заголовка проходят как обычно.
+func New(log *slog.Logger, store Store, ttl time.Duration, maxBodyBytes int64) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		log := log.With(
+			slog.String("component", "middleware/idempotency"),
+		)
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(Header)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			log := log.With(
+				slog.String("idempotency_key", key),
+			)
+
+			if !validKey(key) {
+				log.InfoContext(r.Context(), "invalid idempotency key")
+				resp.WriteProblem(w, r, resp.NewLocalizedProblem(r, http.StatusBadRequest, i18n.MsgInvalidRequest))
+				return
+			}
+
+			h := requestHash(r)
+			body, err := spoolBody(io.TeeReader(http.MaxBytesReader(w, r.Body, maxBodyBytes), h))
+			if err != nil {
+				var tooLarge *http.MaxBytesError
+				if errors.As(err, &tooLarge) {
+					log.InfoContext(r.Context(), "request body is too large for idempotency check", sl.Err(err))
+					resp.WriteProblem(w, r, resp.NewLocalizedProblem(r, http.StatusRequestEntityTooLarge,
+						i18n.MsgPayloadTooLarge, strconv.FormatInt(maxBodyBytes, 10)))
+					return
+				}
+				log.InfoContext(r.Context(), "failed to read request body", sl.Err(err))
+				resp.WriteProblem(w, r, resp.NewLocalizedProblem(r, http.StatusBadRequest, i18n.MsgFailedToDecode))
+				return
+			}
+			defer body.Close()
+			r.Body = body
+
+			scope := scopeOf(r)
+			hash := hex.EncodeToString(h.Sum(nil))
+
+			record, reserved, err := store.ReserveIdempotencyKey(r.Context(), scope, key, hash, ttl)
+			if err != nil {
+				log.ErrorContext(r.Context(), "failed to reserve idempotency key", sl.Err(err))
+				resp.WriteProblem(w, r, resp.NewLocalizedProblem(r, http.StatusInternalServerError, i18n.MsgInternalError))
+				return
+			}
+
+			if !reserved {
+				switch {
+				case record.RequestHash != hash:
+					log.InfoContext(r.Context(), "idempotency key reused with a different request")
+					resp.WriteProblem(w, r, resp.NewLocalizedProblem(r, http.StatusUnprocessableEntity, i18n.MsgIdempotencyReused))
+				case !record.Completed:
+					log.InfoContext(r.Context(), "request with idempotency key is in progress")
+					w.Header().Set("Retry-After", "1")
+					resp.WriteProblem(w, r, resp.NewLocalizedProblem(r, http.StatusConflict, i18n.MsgIdempotencyBusy))
+				default:
+					log.InfoContext(r.Context(), "replaying stored response", slog.Int("status", record.Status))
+					replay(w, record)
+				}
+				return
+			}
+
+			rec := &recorder{ResponseWriter: w}
+			completed := false
+			defer func() {
+				// хендлер упал или ответил 5xx - освобождаем ключ для повтора
+				if completed {
+					return
+				}
+				ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), saveTimeout)
+				defer cancel()
+				if err := store.DeleteIdempotencyKey(ctx, scope, key); err != nil {
+					log.ErrorContext(r.Context(), "failed to release idempotency key", sl.Err(err))
+				}
+			}()
+
+			next.ServeHTTP(rec, r)
+
+			status := rec.statusCode()
+			if status >= http.StatusInternalServerError {
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), saveTimeout)
+			defer cancel()
+			if err := store.CompleteIdempotencyKey(ctx, scope, key, status, storedHeader(rec.header), rec.body.Bytes()); err != nil {
+				log.ErrorContext(r.Context(), "failed to save idempotent response", sl.Err(err))
+				return
+			}
+			completed = true
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+// validKey ключ - видимые ASCII-символы, обычно UUID
+func validKey(key string) bool {
+	if len(key) > maxKeyLength {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] < '!' || key[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// scopeOf ключи разных пользователей и маршрутов не пересекаются
+func scopeOf(r *http.Request) string {
+	who := "ip:" + r.RemoteAddr
+	if principal, ok := auth.PrincipalFromContext(r.Context()); ok {
+		who = principal.String()
+	} else if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		who = "ip:" + host
+	}
+	return who + " " + r.Method + " " + r.URL.Path
+}
+
+// requestHash отпечаток запроса: query, тип, тело и заголовки, от которых зависит формат и язык ответа.
+// Иначе повтор с другим Accept получил бы сохраненный ответ не в том формате. Тело дописывается по мере чтения.
+func requestHash(r *http.Request) hash.Hash {
+	h := sha256.New()
+	io.WriteString(h, r.URL.RawQuery+"\n"+r.Header.Get("Content-Type")+"\n"+
+		r.Header.Get("Accept")+"\n"+r.Header.Get("Accept-Language")+"\n")
+	return h
+}
+
+// spool прочитанное тело для хендлера: небольшое в памяти, большое во временном файле,
+// чтобы загрузка файла с ключом не держала в памяти все тело
+type spool struct {
+	mem  *bytes.Reader
+	file *os.File
+}
+
+// spoolBody дочитывает src до конца. Файл удаляется в Close.
+func spoolBody(src io.Reader) (*spool, error) {
+	var mem bytes.Buffer
+	if _, err := io.CopyN(&mem, src, memoryBodyBytes+1); err != nil {
+		if errors.Is(err, io.EOF) {
+			return &spool{mem: bytes.NewReader(mem.Bytes())}, nil
+		}
+		return nil, err
+	}
+
+	file, err := os.CreateTemp("", "idempotency-*")
+	if err != nil {
+		return nil, err
+	}
+	s := &spool{file: file}
+	if _, err := mem.WriteTo(file); err != nil {
+		s.Close()
+		return nil, err
+	}
+	if _, err := io.Copy(file, src); err != nil {
+		s.Close()
+		return nil, err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		s.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *spool) Read(p []byte) (int, error) {
+	if s.file != nil {
+		return s.file.Read(p)
+	}
+	return s.mem.Read(p)
+}
+
+func (s *spool) Close() error {
+	if s.file == nil {
+		return nil
+	}
+	_ = s.file.Close()
+	return os.Remove(s.file.Name())
+}
+
+// skipHeader заголовки, которые у повтора свои: лимиты, дата, длина
+func skipHeader(name string) bool {
+	name = http.CanonicalHeaderKey(name)
+	return strings.HasPrefix(name, "Ratelimit-") || name == "Retry-After" || name == "Date" || name == "Content-Length"
+}
+
+func storedHeader(h http.Header) map[string][]string {
+	stored := make(map[string][]string, len(h))
+	for name, values := range h {
+		if !skipHeader(name) {
+			stored[name] = values
+		}
+	}
+	return stored
+}
+
+func replay(w http.ResponseWriter, record storage.IdempotencyRecord) {
+	for name, values := range record.Header {
+		if !skipHeader(name) {
+			w.Header()[name] = values
+		}
+	}
+	w.Header().Set(ReplayedHeader, "true")
+	w.WriteHeader(record.Status)
+	_, _ = w.Write(record.Body)
+}
+
+// recorder пропускает ответ клиенту и запоминает его копию
+type recorder struct {
+	http.ResponseWriter
+	status int
+	header http.Header
+	body   bytes.Buffer
+}
+
+func (r *recorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+		r.header = r.ResponseWriter.Header().Clone()
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.WriteHeader(http.StatusOK)
+	}
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *recorder) statusCode() int {
+	if r.status == 0 {
+		return http.StatusOK
+	}
+	return r.status
+}
+
+func (r *recorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
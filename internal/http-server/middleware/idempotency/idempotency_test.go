@@ -0,0 +1,274 @@
+package idempotency
+
+import (
+	"RestApi_v1/internal/config/internal/storage"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryStore ключи в памяти с той же логикой, что у Postgres
+type memoryStore struct {
+	mu      sync.Mutex
+	records map[string]storage.IdempotencyRecord
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{records: make(map[string]storage.IdempotencyRecord)}
+}
+
+func (s *memoryStore) ReserveIdempotencyKey(_ context.Context, scope string, key string, requestHash string, _ time.Duration) (storage.IdempotencyRecord, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if record, ok := s.records[scope+"|"+key]; ok {
+		return record, false, nil
+	}
+	s.records[scope+"|"+key] = storage.IdempotencyRecord{RequestHash: requestHash}
+	return storage.IdempotencyRecord{}, true, nil
+}
+
+func (s *memoryStore) CompleteIdempotencyKey(_ context.Context, scope string, key string, status int, header map[string][]string, body []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record := s.records[scope+"|"+key]
+	record.Completed = true
+	record.Status = status
+	record.Header = header
+	record.Body = body
+	s.records[scope+"|"+key] = record
+	return nil
+}
+
+func (s *memoryStore) DeleteIdempotencyKey(_ context.Context, scope string, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.records, scope+"|"+key)
+	return nil
+}
+
+// step запрос к обернутому хендлеру и ожидаемый ответ
+type step struct {
+	key      string
+	body     string
+	accept   string
+	language string
+	status   int // ответ хендлера
+	panics   bool
+	want     int
+	replayed bool
+	calls    int // сколько раз хендлер вызван к этому моменту
+}
+
+func TestIdempotency(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "no key passes through",
+			steps: []step{
+				{body: `{"song":"a"}`, status: http.StatusCreated, want: http.StatusCreated, calls: 1},
+				{body: `{"song":"a"}`, status: http.StatusCreated, want: http.StatusCreated, calls: 2},
+			},
+		},
+		{
+			name: "repeat is replayed",
+			steps: []step{
+				{key: "k1", body: `{"song":"a"}`, status: http.StatusCreated, want: http.StatusCreated, calls: 1},
+				{key: "k1", body: `{"song":"a"}`, status: http.StatusCreated, want: http.StatusCreated, replayed: true, calls: 1},
+			},
+		},
+		{
+			name: "different body is rejected",
+			steps: []step{
+				{key: "k1", body: `{"song":"a"}`, status: http.StatusCreated, want: http.StatusCreated, calls: 1},
+				{key: "k1", body: `{"song":"b"}`, want: http.StatusUnprocessableEntity, calls: 1},
+			},
+		},
+		{
+			name: "different accept is rejected",
+			steps: []step{
+				{key: "k1", body: `{"song":"a"}`, status: http.StatusCreated, want: http.StatusCreated, calls: 1},
+				{key: "k1", body: `{"song":"a"}`, accept: "application/xml", want: http.StatusUnprocessableEntity, calls: 1},
+			},
+		},
+		{
+			name: "different language is rejected",
+			steps: []step{
+				{key: "k1", body: `{"song":"a"}`, status: http.StatusCreated, want: http.StatusCreated, calls: 1},
+				{key: "k1", body: `{"song":"a"}`, language: "ru", want: http.StatusUnprocessableEntity, calls: 1},
+			},
+		},
+		{
+			name: "other keys are independent",
+			steps: []step{
+				{key: "k1", body: `{"song":"a"}`, status: http.StatusCreated, want: http.StatusCreated, calls: 1},
+				{key: "k2", body: `{"song":"b"}`, status: http.StatusCreated, want: http.StatusCreated, calls: 2},
+			},
+		},
+		{
+			name: "4xx is stored",
+			steps: []step{
+				{key: "k1", body: `{}`, status: http.StatusBadRequest, want: http.StatusBadRequest, calls: 1},
+				{key: "k1", body: `{}`, status: http.StatusCreated, want: http.StatusBadRequest, replayed: true, calls: 1},
+			},
+		},
+		{
+			name: "5xx releases the key",
+			steps: []step{
+				{key: "k1", body: `{"song":"a"}`, status: http.StatusInternalServerError, want: http.StatusInternalServerError, calls: 1},
+				{key: "k1", body: `{"song":"a"}`, status: http.StatusCreated, want: http.StatusCreated, calls: 2},
+				{key: "k1", body: `{"song":"a"}`, want: http.StatusCreated, replayed: true, calls: 2},
+			},
+		},
+		{
+			name: "panic releases the key",
+			steps: []step{
+				{key: "k1", body: `{"song":"a"}`, panics: true, calls: 1},
+				{key: "k1", body: `{"song":"a"}`, status: http.StatusCreated, want: http.StatusCreated, calls: 2},
+			},
+		},
+		{
+			name: "invalid key",
+			steps: []step{
+				{key: "bad key", body: `{}`, want: http.StatusBadRequest},
+			},
+		},
+		{
+			name: "body too large",
+			steps: []step{
+				{key: "k1", body: strings.Repeat("a", 65), want: http.StatusRequestEntityTooLarge},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				calls int
+				next  step
+			)
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				if next.panics {
+					panic("handler failed")
+				}
+				body, _ := io.ReadAll(r.Body)
+				w.Header().Set("Location", "/songs/1")
+				w.WriteHeader(next.status)
+				_, _ = w.Write(body)
+			})
+			mw := New(slog.New(slog.NewTextHandler(io.Discard, nil)), newMemoryStore(), time.Hour, 64)(handler)
+
+			for i, st := range tt.steps {
+				next = st
+				w := serve(mw, st)
+
+				if calls != st.calls {
+					t.Errorf("step %d: handler called %d times, want %d", i, calls, st.calls)
+				}
+				if st.panics {
+					continue
+				}
+				if w.Code != st.want {
+					t.Errorf("step %d: status = %d, want %d", i, w.Code, st.want)
+				}
+				if replayed := w.Header().Get(ReplayedHeader) == "true"; replayed != st.replayed {
+					t.Errorf("step %d: replayed = %v, want %v", i, replayed, st.replayed)
+				}
+				if st.replayed && (w.Body.String() != st.body || w.Header().Get("Location") != "/songs/1") {
+					t.Errorf("step %d: replayed body %q, location %q", i, w.Body.String(), w.Header().Get("Location"))
+				}
+			}
+		})
+	}
+}
+
+func serve(h http.Handler, st step) (w *httptest.ResponseRecorder) {
+	w = httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/songs", strings.NewReader(st.body))
+	r.Header.Set("Content-Type", "application/json")
+	if st.key != "" {
+		r.Header.Set(Header, st.key)
+	}
+	if st.accept != "" {
+		r.Header.Set("Accept", st.accept)
+	}
+	if st.language != "" {
+		r.Header.Set("Accept-Language", st.language)
+	}
+
+	defer func() { _ = recover() }()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestIdempotencyInProgress(t *testing.T) {
+	store := newMemoryStore()
+	started, release := make(chan struct{}), make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.WriteHeader(http.StatusCreated)
+	})
+	mw := New(slog.New(slog.NewTextHandler(io.Discard, nil)), store, time.Hour, 64)(handler)
+
+	first := make(chan *httptest.ResponseRecorder)
+	go func() { first <- serve(mw, step{key: "k1", body: `{}`}) }()
+	<-started
+
+	w := serve(mw, step{key: "k1", body: `{}`})
+	if w.Code != http.StatusConflict {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusConflict)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("Retry-After is not set")
+	}
+
+	close(release)
+	if w := <-first; w.Code != http.StatusCreated {
+		t.Errorf("first request status = %d, want %d", w.Code, http.StatusCreated)
+	}
+}
+
+// Тело больше буфера в памяти доходит до хендлера целиком и сверяется по хешу.
+func TestIdempotencyLargeBody(t *testing.T) {
+	defer func(n int64) { memoryBodyBytes = n }(memoryBodyBytes)
+	memoryBodyBytes = 8
+
+	var got []string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got = append(got, string(body))
+		w.WriteHeader(http.StatusAccepted)
+	})
+	mw := New(slog.New(slog.NewTextHandler(io.Discard, nil)), newMemoryStore(), time.Hour, 64)(handler)
+
+	body := strings.Repeat("song\n", 10)
+	steps := []struct {
+		body string
+		want int
+	}{
+		{body: body, want: http.StatusAccepted},
+		{body: body, want: http.StatusAccepted},
+		{body: body + "more\n", want: http.StatusUnprocessableEntity},
+	}
+	for i, st := range steps {
+		if w := serve(mw, step{key: "k1", body: st.body}); w.Code != st.want {
+			t.Errorf("step %d: status = %d, want %d", i, w.Code, st.want)
+		}
+	}
+
+	if len(got) != 1 || got[0] != body {
+		t.Errorf("handler got %q, want one full body", got)
+	}
+}
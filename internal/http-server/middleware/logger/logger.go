@@ -1,6 +1,7 @@
 package logger
 
 import (
+	"RestApi_v1/internal/config/internal/lib/auth"
 	"github.com/go-chi/chi/v5/middleware"
 	"log/slog"
 	"net/http"
@@ -21,20 +22,27 @@ func New(log *slog.Logger) func(next http.Handler) http.Handler {
 				slog.String("path", r.URL.Path),
 				slog.String("remote_addr", r.RemoteAddr),
 				slog.String("user_agent", r.UserAgent()),
-				slog.String("request_id", middleware.GetReqID(r.Context())),
 			)
+			// логгер стоит до аутентификации, пользователь становится известен только после ответа
+			ctx, principal := auth.WithPrincipalSlot(r.Context())
 			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
 
 			t1 := time.Now()
 			defer func() {
-				entry.Info("request completed",
+				if p, ok := principal(); ok {
+					entry = entry.With(
+						slog.String("principal", p.String()),
+						slog.String("role", string(p.Role)),
+					)
+				}
+				entry.InfoContext(r.Context(), "request completed",
 					slog.Int("status", ww.Status()),
 					slog.Int("bytes", ww.BytesWritten()),
 					slog.String("duration", time.Since(t1).String()),
 				)
 			}()
 
-			next.ServeHTTP(ww, r)
+			next.ServeHTTP(ww, r.WithContext(ctx))
 		}
 
 		return http.HandlerFunc(fn)
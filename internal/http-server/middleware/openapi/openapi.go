@@ -0,0 +1,269 @@
+package openapi
+
+import (
+	resp "RestApi_v1/internal/config/internal/lib/api/response"
+	"RestApi_v1/internal/config/internal/lib/i18n"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/getkin/kin-openapi/openapi2"
+	"github.com/getkin/kin-openapi/openapi2conv"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// Validator проверяет запросы (и в dev - ответы) по спецификации API
+type Validator struct {
+	log               *slog.Logger
+	router            routers.Router
+	validateResponses bool
+}
+
+// NewValidator загружает спецификацию Swagger 2.0 (как ее генерирует swag) и переводит ее в OpenAPI 3
+func NewValidator(log *slog.Logger, swaggerJSON []byte, validateResponses bool) (*Validator, error) {
+	const op = "middleware.openapi.NewValidator"
+
+	var doc2 openapi2.T
+	if err := json.Unmarshal(swaggerJSON, &doc2); err != nil {
+		return nil, fmt.Errorf("%s: failed to parse spec: %w", op, err)
+	}
+
+	doc, err := openapi2conv.ToV3(&doc2)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to convert spec: %w", op, err)
+	}
+
+	// host и basePath в спецификации - публичный адрес за прокси, а сервер отдает пути от корня
+	doc.Servers = nil
+
+	if err := doc.Validate(context.Background()); err != nil {
+		return nil, fmt.Errorf("%s: invalid spec: %w", op, err)
+	}
+
+	router, err := gorillamux.NewRouter(doc)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to build router: %w", op, err)
+	}
+
+	return &Validator{
+		log:               log.With(slog.String("component", "middleware/openapi")),
+		router:            router,
+		validateResponses: validateResponses,
+	}, nil
+}
+
+// Middleware отклоняет запросы, не подходящие под спецификацию, ответом 400 со списком всех нарушений.
+// Маршруты, которых нет в спецификации (например, /swagger/*), пропускаются без проверки.
+func (v *Validator) Middleware(next http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		route, pathParams, err := v.router.FindRoute(r)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		log := v.log.With(
+			slog.String("route", r.Method+" "+route.Path),
+		)
+
+		input := &openapi3filter.RequestValidationInput{
+			Request:    r,
+			PathParams: pathParams,
+			Route:      route,
+			Options: &openapi3filter.Options{
+				MultiError: true,
+				// аутентификацию проверяет middleware/auth
+				AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+				// тело в NDJSON/CSV разбирает сам хендлер, в спецификации оно помечено x-raw-body
+				ExcludeRequestBody: rawBody(route.Operation),
+			},
+		}
+
+		if err := openapi3filter.ValidateRequest(r.Context(), input); err != nil {
+			list := violations(err)
+			log.InfoContext(r.Context(), "request does not match spec", slog.Any("violations", list))
+
+			problem := resp.NewLocalizedProblem(r, http.StatusBadRequest, i18n.MsgSpecMismatch)
+			problem.Errors = list
+			if isContentTypeError(err) {
+				problem = resp.NewLocalizedProblem(r, http.StatusUnsupportedMediaType, i18n.MsgUnsupportedMedia)
+				problem.Errors = list
+			}
+			resp.WriteProblem(w, r, problem)
+			return
+		}
+
+		// бесконечный поток не буферизуем для сверки
+		if !v.validateResponses || stream(route.Operation) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rec := &recorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		err = openapi3filter.ValidateResponse(r.Context(), &openapi3filter.ResponseValidationInput{
+			RequestValidationInput: input,
+			Status:                 rec.status,
+			Header:                 w.Header(),
+			Body:                   io.NopCloser(bytes.NewReader(rec.body.Bytes())),
+			Options: &openapi3filter.Options{
+				MultiError:            true,
+				IncludeResponseStatus: true,
+				// схемы описаны для JSON, CSV/XML/YAML-представления не сверяем
+				ExcludeResponseBody: !strings.HasPrefix(w.Header().Get("Content-Type"), "application/json"),
+			},
+		})
+		if err != nil {
+			// ответ уже отправлен, расхождение со спецификацией только логируем
+			log.WarnContext(r.Context(), "response does not match spec",
+				slog.Int("status", rec.status),
+				slog.Any("violations", violations(err)),
+			)
+		}
+	}
+
+	return http.HandlerFunc(fn)
+}
+
+// rawBody сообщает, что тело операции не описывается JSON-схемой и не проверяется
+func rawBody(op *openapi3.Operation) bool {
+	if op == nil {
+		return false
+	}
+	raw, _ := op.Extensions["x-raw-body"].(bool)
+	return raw
+}
+
+// stream сообщает, что операция отдает долгий поток (x-stream) и ее ответ не сверяется
+func stream(op *openapi3.Operation) bool {
+	if op == nil {
+		return false
+	}
+	stream, _ := op.Extensions["x-stream"].(bool)
+	return stream
+}
+
+// violations раскрывает вложенные ошибки валидации в плоский список сообщений
+func violations(err error) []string {
+	// MultiError проверяем без errors.As: RequestError тоже разворачивается в MultiError и потерял бы префикс
+	if multi, ok := err.(openapi3.MultiError); ok {
+		var list []string
+		for _, e := range multi {
+			list = append(list, violations(e)...)
+		}
+		return list
+	}
+
+	var reqErr *openapi3filter.RequestError
+	if errors.As(err, &reqErr) {
+		prefix := "request"
+		switch {
+		case reqErr.Parameter != nil:
+			prefix = fmt.Sprintf("%s parameter %q", reqErr.Parameter.In, reqErr.Parameter.Name)
+		case reqErr.RequestBody != nil:
+			prefix = "request body"
+		}
+
+		if nested, ok := reqErr.Err.(openapi3.MultiError); ok {
+			var list []string
+			for _, e := range violations(nested) {
+				list = append(list, prefix+": "+e)
+			}
+			return list
+		}
+
+		msg := reqErr.Reason
+		if reqErr.Err != nil {
+			msg = schemaMessage(reqErr.Err)
+		}
+		return []string{prefix + ": " + msg}
+	}
+
+	var respErr *openapi3filter.ResponseError
+	if errors.As(err, &respErr) {
+		msg := respErr.Reason
+		if respErr.Err != nil {
+			msg = schemaMessage(respErr.Err)
+		}
+		return []string{"response: " + msg}
+	}
+
+	return []string{schemaMessage(err)}
+}
+
+func schemaMessage(err error) string {
+	var schemaErr *openapi3.SchemaError
+	if errors.As(err, &schemaErr) {
+		if path := schemaErr.JSONPointer(); len(path) > 0 {
+			return fmt.Sprintf("field %s: %s", strings.Join(path, "."), schemaErr.Reason)
+		}
+		return schemaErr.Reason
+	}
+	return err.Error()
+}
+
+func isContentTypeError(err error) bool {
+	if multi, ok := err.(openapi3.MultiError); ok {
+		for _, e := range multi {
+			if isContentTypeError(e) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var reqErr *openapi3filter.RequestError
+	if !errors.As(err, &reqErr) || reqErr.RequestBody == nil {
+		return false
+	}
+	if strings.HasPrefix(reqErr.Reason, "header Content-Type has unexpected value") {
+		return true
+	}
+
+	var parseErr *openapi3filter.ParseError
+	return errors.As(reqErr.Err, &parseErr) && parseErr.Kind == openapi3filter.KindUnsupportedFormat
+}
+
+// recorder пропускает ответ клиенту и сохраняет копию для проверки
+type recorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (r *recorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	// копим только JSON: потоковые и прочие ответы не сверяются и не должны оседать в памяти
+	if strings.HasPrefix(r.Header().Get("Content-Type"), "application/json") {
+		r.body.Write(b)
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *recorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+func (r *recorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
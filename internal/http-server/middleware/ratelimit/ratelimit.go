@@ -0,0 +1,84 @@
+package ratelimit
+
+import (
+	resp "RestApi_v1/internal/config/internal/lib/api/response"
+	"RestApi_v1/internal/config/internal/lib/auth"
+	"RestApi_v1/internal/config/internal/lib/i18n"
+	"RestApi_v1/internal/config/internal/lib/logger/sl"
+	"RestApi_v1/internal/config/internal/lib/ratelimit"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// New ограничивает частоту запросов группы маршрутов. Ключ бакета - пользователь
+// (API-ключ или subject токена), для анонимных запросов - IP клиента.
+// Правило берется на каждый запрос, чтобы лимиты менялись при перезагрузке конфига.
+func New(log *slog.Logger, store ratelimit.Store, group string, rules func() ratelimit.Rule) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		log := log.With(
+			slog.String("component", "middleware/ratelimit"),
+			slog.String("group", group),
+		)
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			rule := rules()
+			// лимит для группы не задан
+			if rule.Requests <= 0 || rule.Window <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			key := group + ":" + clientKey(r)
+
+			res, err := store.Take(r.Context(), key, rule)
+			if err != nil {
+				// хранилище лимитов недоступно - пропускаем запрос, а не роняем API
+				log.ErrorContext(r.Context(), "failed to check rate limit",
+					sl.Err(err),
+				)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			h := w.Header()
+			h.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+			h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			h.Set("RateLimit-Reset", ceilSeconds(res.Reset))
+			h.Set("RateLimit-Policy", strconv.Itoa(rule.Requests)+";w="+ceilSeconds(rule.Window))
+
+			if !res.Allowed {
+				log.InfoContext(r.Context(), "rate limit exceeded",
+					slog.String("key", key),
+				)
+				h.Set("Retry-After", ceilSeconds(res.RetryAfter))
+				resp.WriteProblem(w, r, resp.NewLocalizedProblem(r, http.StatusTooManyRequests,
+					i18n.MsgRateLimited, ceilSeconds(res.RetryAfter)))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+func clientKey(r *http.Request) string {
+	if principal, ok := auth.PrincipalFromContext(r.Context()); ok {
+		return principal.String()
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,181 @@
+package format
+
+import (
+	resp "RestApi_v1/internal/config/internal/lib/api/response"
+	"RestApi_v1/internal/config/internal/lib/i18n"
+	"encoding/csv"
+	"encoding/json"
+	"github.com/go-chi/render"
+	"gopkg.in/yaml.v3"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Форматы ответа, значения параметра ?format=
+const (
+	JSON = "json"
+	XML  = "xml"
+	CSV  = "csv"
+	YAML = "yaml"
+)
+
+// порядок - приоритет при Accept: */*
+var formats = []string{JSON, XML, CSV, YAML}
+
+var contentTypes = map[string]string{
+	JSON: "application/json; charset=utf-8",
+	XML:  "application/xml; charset=utf-8",
+	CSV:  "text/csv; charset=utf-8",
+	YAML: "application/yaml; charset=utf-8",
+}
+
+var mediaTypes = map[string]string{
+	"application/json":   JSON,
+	"application/xml":    XML,
+	"text/xml":           XML,
+	"text/csv":           CSV,
+	"application/yaml":   YAML,
+	"application/x-yaml": YAML,
+	"text/yaml":          YAML,
+}
+
+// CSVMarshaler значение, которое умеет выдать себя таблицей с заголовком
+type CSVMarshaler interface {
+	MarshalCSV() (header []string, records [][]string)
+}
+
+// Respond отвечает в формате из ?format= или заголовка Accept, по умолчанию JSON.
+// Ставится вместо render.Respond, чтобы все хендлеры отвечали одинаково.
+func Respond(w http.ResponseWriter, r *http.Request, v any) {
+	w.Header().Add("Vary", "Accept")
+
+	f, ok := Negotiate(r)
+	if !ok {
+		p := resp.NewLocalizedProblem(r, http.StatusNotAcceptable, i18n.MsgNotAcceptable, strings.Join(formats, ", "))
+		resp.WriteProblem(w, r, p)
+		return
+	}
+
+	switch f {
+	case XML:
+		render.XML(w, r, v)
+	case CSV:
+		m, ok := v.(CSVMarshaler)
+		if !ok {
+			// ошибки и прочие ответы без табличного вида отдаем как JSON
+			render.JSON(w, r, v)
+			return
+		}
+		writeCSV(w, r, m)
+	case YAML:
+		writeYAML(w, r, v)
+	default:
+		render.JSON(w, r, v)
+	}
+}
+
+// Negotiate выбирает формат ответа. false - клиент не принимает ни один из поддерживаемых форматов.
+func Negotiate(r *http.Request) (string, bool) {
+	if f := r.URL.Query().Get("format"); f != "" {
+		_, ok := contentTypes[f]
+		return f, ok
+	}
+
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return JSON, true
+	}
+
+	// specificity при равном q точный тип важнее type/*, а type/* важнее */*
+	type candidate struct {
+		format      string
+		q           float64
+		specificity int
+	}
+	var candidates []candidate
+
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+		if s, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(s, 64); err != nil {
+				continue
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+
+		switch {
+		case mediaType == "*/*":
+			candidates = append(candidates, candidate{format: JSON, q: q})
+		case mediaType == "application/*":
+			candidates = append(candidates, candidate{format: JSON, q: q, specificity: 1})
+		case mediaType == "text/*":
+			candidates = append(candidates, candidate{format: CSV, q: q, specificity: 1})
+		case mediaTypes[mediaType] != "":
+			candidates = append(candidates, candidate{format: mediaTypes[mediaType], q: q, specificity: 2})
+		}
+	}
+
+	if len(candidates) == 0 {
+		return "", false
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].q != candidates[j].q {
+			return candidates[i].q > candidates[j].q
+		}
+		return candidates[i].specificity > candidates[j].specificity
+	})
+	return candidates[0].format, true
+}
+
+func writeStatus(w http.ResponseWriter, r *http.Request) {
+	if status, ok := r.Context().Value(render.StatusCtxKey).(int); ok {
+		w.WriteHeader(status)
+	}
+}
+
+func writeCSV(w http.ResponseWriter, r *http.Request, m CSVMarshaler) {
+	header, records := m.MarshalCSV()
+
+	w.Header().Set("Content-Type", contentTypes[CSV])
+	writeStatus(w, r)
+
+	cw := csv.NewWriter(w)
+	_ = cw.Write(header)
+	_ = cw.WriteAll(records)
+}
+
+// writeYAML кодирует через JSON, чтобы имена полей совпадали с JSON-ответом
+func writeYAML(w http.ResponseWriter, r *http.Request, v any) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var generic any
+	if err := json.Unmarshal(raw, &generic); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	out, err := yaml.Marshal(generic)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentTypes[YAML])
+	writeStatus(w, r)
+	_, _ = w.Write(out)
+}
@@ -0,0 +1,106 @@
+package format
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		name   string
+		query  string
+		accept string
+		want   string
+		ok     bool
+	}{
+		{name: "no accept", want: JSON, ok: true},
+		{name: "any", accept: "*/*", want: JSON, ok: true},
+		{name: "xml", accept: "application/xml", want: XML, ok: true},
+		{name: "text xml", accept: "text/xml", want: XML, ok: true},
+		{name: "csv", accept: "text/csv", want: CSV, ok: true},
+		{name: "text wildcard", accept: "text/*", want: CSV, ok: true},
+		{name: "yaml alias", accept: "application/x-yaml", want: YAML, ok: true},
+		{name: "highest q wins", accept: "application/json;q=0.5, text/csv;q=0.9, */*;q=0.1", want: CSV, ok: true},
+		{name: "equal q keeps order", accept: "application/yaml, application/json", want: YAML, ok: true},
+		{name: "exact type beats any on equal q", accept: "*/*, text/csv", want: CSV, ok: true},
+		{name: "exact type beats wildcard subtype on equal q", accept: "text/*, application/yaml", want: YAML, ok: true},
+		{name: "wildcard subtype beats any on equal q", accept: "*/*, text/*", want: CSV, ok: true},
+		{name: "q beats specificity", accept: "*/*, text/csv;q=0.5", want: JSON, ok: true},
+		{name: "unsupported skipped", accept: "text/html, application/xml;q=0.8", want: XML, ok: true},
+		{name: "zero q excluded", accept: "application/xml;q=0, text/csv", want: CSV, ok: true},
+		{name: "broken q skipped", accept: "application/xml;q=abc, application/yaml", want: YAML, ok: true},
+		{name: "charset param", accept: "application/json; charset=utf-8", want: JSON, ok: true},
+		{name: "only unsupported", accept: "text/html, image/png", ok: false},
+		{name: "only zero q", accept: "application/json;q=0", ok: false},
+		{name: "query wins over accept", query: "yaml", accept: "application/xml", want: YAML, ok: true},
+		{name: "unknown query", query: "toml", accept: "application/json", want: "toml", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := "/songs"
+			if tt.query != "" {
+				target += "?format=" + tt.query
+			}
+			r := httptest.NewRequest(http.MethodGet, target, nil)
+			if tt.accept != "" {
+				r.Header.Set("Accept", tt.accept)
+			}
+
+			got, ok := Negotiate(r)
+			if ok != tt.ok || (ok && got != tt.want) {
+				t.Errorf("Negotiate() = %q, %v, want %q, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+type table struct{}
+
+func (table) MarshalCSV() ([]string, [][]string) {
+	return []string{"id", "song"}, [][]string{{"1", "Hysteria"}}
+}
+
+func TestRespond(t *testing.T) {
+	tests := []struct {
+		name        string
+		accept      string
+		value       any
+		status      int
+		contentType string
+		body        string
+	}{
+		{name: "json", value: map[string]int{"id": 1}, status: http.StatusOK, contentType: "application/json", body: `{"id":1}`},
+		{name: "csv", accept: "text/csv", value: table{}, status: http.StatusOK, contentType: "text/csv", body: "id,song\n1,Hysteria\n"},
+		{name: "csv falls back to json", accept: "text/csv", value: map[string]int{"id": 1}, status: http.StatusOK, contentType: "application/json", body: `{"id":1}`},
+		{name: "yaml", accept: "application/yaml", value: map[string]int{"id": 1}, status: http.StatusOK, contentType: "application/yaml", body: "id: 1\n"},
+		{name: "not acceptable", accept: "text/html", value: table{}, status: http.StatusNotAcceptable, contentType: "application/problem+json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/songs", nil)
+			if tt.accept != "" {
+				r.Header.Set("Accept", tt.accept)
+			}
+			w := httptest.NewRecorder()
+
+			Respond(w, r, tt.value)
+
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d", w.Code, tt.status)
+			}
+			if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, tt.contentType) {
+				t.Errorf("Content-Type = %q, want %q", ct, tt.contentType)
+			}
+			if tt.body != "" && strings.TrimSpace(w.Body.String()) != strings.TrimSpace(tt.body) {
+				t.Errorf("body = %q, want %q", w.Body.String(), tt.body)
+			}
+			if vary := w.Header().Get("Vary"); vary != "Accept" {
+				t.Errorf("Vary = %q, want %q", vary, "Accept")
+			}
+		})
+	}
+}
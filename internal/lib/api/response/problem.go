@@ -0,0 +1,59 @@
+package response
+
+import (
+	"RestApi_v1/internal/config/internal/lib/i18n"
+	"encoding/json"
+	"github.com/go-playground/validator/v10"
+	"net/http"
+)
+
+const ContentTypeProblem = "application/problem+json"
+
+// Problem ответ об ошибке в формате RFC 7807 (problem details)
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	// Errors список всех найденных нарушений, если их несколько
+	Errors []string `json:"errors,omitempty"`
+}
+
+// NewProblem создает problem с типом about:blank и стандартным заголовком статуса
+func NewProblem(status int, detail string) Problem {
+	return Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	}
+}
+
+// NewLocalizedProblem problem с detail на языке клиента, detail - ключ i18n.Msg*
+func NewLocalizedProblem(r *http.Request, status int, detail string, params ...string) Problem {
+	return NewProblem(status, i18n.FromRequest(r).T(detail, params...))
+}
+
+// WriteProblem отправляет problem с нужным статусом и Content-Type
+func WriteProblem(w http.ResponseWriter, r *http.Request, p Problem) {
+	if p.Instance == "" {
+		p.Instance = r.URL.Path
+	}
+
+	w.Header().Set("Content-Type", ContentTypeProblem)
+	w.Header().Set("Content-Language", i18n.Match(r.Header.Get("Accept-Language")))
+	w.WriteHeader(p.Status)
+	_ = json.NewEncoder(w).Encode(p)
+}
+
+// LocalizedValidationProblem 400 со списком нарушений валидации на языке клиента
+func LocalizedValidationProblem(r *http.Request, errs validator.ValidationErrors) Problem {
+	t := i18n.FromRequest(r)
+
+	p := NewProblem(http.StatusBadRequest, t.T(i18n.MsgValidationError))
+	for _, err := range errs {
+		p.Errors = append(p.Errors, err.Translate(t.Validation()))
+	}
+	return p
+}
@@ -1,8 +1,9 @@
 package response
 
 import (
-	"fmt"
+	"RestApi_v1/internal/config/internal/lib/i18n"
 	"github.com/go-playground/validator/v10"
+	"net/http"
 	"strings"
 )
 
@@ -29,16 +30,26 @@ func Error(msg string) Response {
 	}
 }
 
+// LocalizedError ошибка на языке клиента из Accept-Language, msg - ключ i18n.Msg*
+func LocalizedError(r *http.Request, msg string, params ...string) Response {
+	return Error(i18n.FromRequest(r).T(msg, params...))
+}
+
+// ValidationError сообщения об ошибках валидации на английском
 func ValidationError(errs validator.ValidationErrors) Response {
+	return validationError(i18n.Get(i18n.DefaultLang), errs)
+}
+
+// LocalizedValidationError сообщения об ошибках валидации на языке клиента
+func LocalizedValidationError(r *http.Request, errs validator.ValidationErrors) Response {
+	return validationError(i18n.FromRequest(r), errs)
+}
+
+func validationError(t *i18n.Translator, errs validator.ValidationErrors) Response {
 	var errMsgs []string
 
 	for _, err := range errs {
-		switch err.ActualTag() {
-		case "required":
-			errMsgs = append(errMsgs, fmt.Sprintf("field %s is a required field", err.Field()))
-		default:
-			errMsgs = append(errMsgs, fmt.Sprintf("field %s is not valid", err.Field()))
-		}
+		errMsgs = append(errMsgs, err.Translate(t.Validation()))
 	}
 
 	return Response{
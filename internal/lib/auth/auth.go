@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+type Role string
+
+const (
+	RoleReader Role = "reader"
+	RoleEditor Role = "editor"
+	RoleAdmin  Role = "admin"
+)
+
+const (
+	MethodAPIKey = "api_key"
+	MethodJWT    = "jwt"
+
+	apiKeyPrefix = "rk_"
+)
+
+var (
+	ErrUnknownRole        = errors.New("unknown role")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// rank уровень роли: роль с большим рангом включает права всех младших
+var rank = map[Role]int{
+	RoleReader: 1,
+	RoleEditor: 2,
+	RoleAdmin:  3,
+}
+
+func ParseRole(s string) (Role, error) {
+	r := Role(s)
+	if _, ok := rank[r]; !ok {
+		return "", fmt.Errorf("%w: %q", ErrUnknownRole, s)
+	}
+	return r, nil
+}
+
+// Allows сообщает, достаточно ли роли r для доступа, требующего роль required
+func (r Role) Allows(required Role) bool {
+	return rank[r] >= rank[required]
+}
+
+// Principal тот, от чьего имени выполняется запрос
+type Principal struct {
+	Subject string
+	Role    Role
+	Method  string
+}
+
+func (p Principal) String() string {
+	return p.Method + ":" + p.Subject
+}
+
+type (
+	ctxKey  struct{}
+	slotKey struct{}
+)
+
+// principalSlot место под пользователя в контексте внешнего middleware
+type principalSlot struct {
+	principal Principal
+	ok        bool
+}
+
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	if slot, ok := ctx.Value(slotKey{}).(*principalSlot); ok {
+		slot.principal, slot.ok = p, true
+	}
+	return context.WithValue(ctx, ctxKey{}, p)
+}
+
+// WithPrincipalSlot нужен middleware, которые стоят до аутентификации, но пишут пользователя
+// в лог после ответа. WithPrincipal ниже по цепочке запишет его в слот, principal вернет его.
+func WithPrincipalSlot(ctx context.Context) (_ context.Context, principal func() (Principal, bool)) {
+	slot := &principalSlot{}
+	return context.WithValue(ctx, slotKey{}, slot), func() (Principal, bool) {
+		return slot.principal, slot.ok
+	}
+}
+
+// PrincipalFromContext возвращает аутентифицированного пользователя, если он есть
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(ctxKey{}).(Principal)
+	return p, ok
+}
+
+// GenerateAPIKey создает новый ключ. В базе хранится только его хеш, сам ключ показывается один раз.
+func GenerateAPIKey() (key string, hash string, err error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", fmt.Errorf("failed to generate api key: %w", err)
+	}
+
+	key = apiKeyPrefix + hex.EncodeToString(buf)
+	return key, HashAPIKey(key), nil
+}
+
+// HashAPIKey хеширует ключ. Ключи случайные и длинные, поэтому соль не нужна.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
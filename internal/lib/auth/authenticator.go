@@ -0,0 +1,55 @@
+package auth
+
+import (
+	"RestApi_v1/internal/config/internal/models"
+	"RestApi_v1/internal/config/internal/storage"
+	"context"
+	"errors"
+	"strconv"
+)
+
+// KeyFinder ищет действующий API-ключ по его хешу
+type KeyFinder interface {
+	APIKeyByHash(ctx context.Context, keyHash string) (models.APIKey, error)
+}
+
+// Authenticator проверяет учетные данные одинаково для REST и gRPC
+type Authenticator struct {
+	keys     KeyFinder
+	verifier *JWTVerifier
+}
+
+// NewAuthenticator verifier может быть nil, тогда bearer-токены не принимаются
+func NewAuthenticator(keys KeyFinder, verifier *JWTVerifier) *Authenticator {
+	return &Authenticator{keys: keys, verifier: verifier}
+}
+
+// APIKey ищет пользователя по ключу. Неизвестный или отозванный ключ - ErrInvalidCredentials.
+func (a *Authenticator) APIKey(ctx context.Context, key string) (Principal, error) {
+	apiKey, err := a.keys.APIKeyByHash(ctx, HashAPIKey(key))
+	if errors.Is(err, storage.ErrAPIKeyNotFound) {
+		return Principal{}, ErrInvalidCredentials
+	}
+	if err != nil {
+		return Principal{}, err
+	}
+
+	role, err := ParseRole(apiKey.Role)
+	if err != nil {
+		return Principal{}, errors.Join(ErrInvalidCredentials, err)
+	}
+
+	return Principal{
+		Subject: strconv.FormatInt(apiKey.ID, 10),
+		Role:    role,
+		Method:  MethodAPIKey,
+	}, nil
+}
+
+// Bearer проверяет JWT без префикса "Bearer "
+func (a *Authenticator) Bearer(token string) (Principal, error) {
+	if a.verifier == nil {
+		return Principal{}, ErrInvalidCredentials
+	}
+	return a.verifier.Verify(token)
+}
@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"RestApi_v1/internal/config/internal/config"
+	"crypto/rsa"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"os"
+)
+
+// Claims токена: стандартные поля и роль пользователя
+type Claims struct {
+	Role string `json:"role"`
+	jwt.RegisteredClaims
+}
+
+// JWTVerifier проверяет bearer-токены, подписанные HS256 общим секретом или RS256 закрытым ключом
+type JWTVerifier struct {
+	hmacSecret []byte
+	rsaKey     *rsa.PublicKey
+	parser     *jwt.Parser
+}
+
+// NewJWTVerifier возвращает nil, если в конфиге не задан ни секрет, ни публичный ключ
+func NewJWTVerifier(cfg config.JWT) (*JWTVerifier, error) {
+	const op = "lib.auth.NewJWTVerifier"
+
+	v := &JWTVerifier{}
+	var methods []string
+
+	if cfg.HMACSecret != "" {
+		v.hmacSecret = []byte(cfg.HMACSecret)
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+
+	if cfg.RSAPublicKeyPath != "" {
+		pem, err := os.ReadFile(cfg.RSAPublicKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("%s: failed to read rsa public key: %w", op, err)
+		}
+		v.rsaKey, err = jwt.ParseRSAPublicKeyFromPEM(pem)
+		if err != nil {
+			return nil, fmt.Errorf("%s: failed to parse rsa public key: %w", op, err)
+		}
+		methods = append(methods, jwt.SigningMethodRS256.Alg())
+	}
+
+	if len(methods) == 0 {
+		return nil, nil
+	}
+
+	opts := []jwt.ParserOption{jwt.WithValidMethods(methods), jwt.WithExpirationRequired()}
+	if cfg.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		opts = append(opts, jwt.WithAudience(cfg.Audience))
+	}
+	v.parser = jwt.NewParser(opts...)
+
+	return v, nil
+}
+
+// Verify проверяет подпись и срок действия токена и возвращает пользователя
+func (v *JWTVerifier) Verify(token string) (Principal, error) {
+	var claims Claims
+
+	_, err := v.parser.ParseWithClaims(token, &claims, func(t *jwt.Token) (any, error) {
+		switch t.Method.Alg() {
+		case jwt.SigningMethodHS256.Alg():
+			return v.hmacSecret, nil
+		case jwt.SigningMethodRS256.Alg():
+			return v.rsaKey, nil
+		}
+		return nil, fmt.Errorf("unexpected signing method %s", t.Method.Alg())
+	})
+	if err != nil {
+		return Principal{}, fmt.Errorf("%w: %w", ErrInvalidCredentials, err)
+	}
+
+	role, err := ParseRole(claims.Role)
+	if err != nil {
+		return Principal{}, fmt.Errorf("%w: %w", ErrInvalidCredentials, err)
+	}
+
+	return Principal{
+		Subject: claims.Subject,
+		Role:    role,
+		Method:  MethodJWT,
+	}, nil
+}
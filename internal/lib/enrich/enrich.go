@@ -0,0 +1,251 @@
+package enrich
+
+import (
+	"RestApi_v1/internal/config/internal/config"
+	"RestApi_v1/internal/config/internal/lib/logger/sl"
+	"RestApi_v1/internal/config/internal/lib/ratelimit"
+	"RestApi_v1/internal/config/internal/lib/validate"
+	"RestApi_v1/internal/config/internal/models"
+	"RestApi_v1/internal/config/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "RestApi_v1/lib/enrich"
+
+// Итоги проверки песни
+const (
+	StatusEnriched = "enriched" // заполнено хотя бы одно поле
+	StatusNoData   = "no_data"  // сервис знает песню, но нового не сообщил
+	StatusNotFound = "not_found"
+	StatusFailed   = "failed"
+)
+
+// maxConsecutiveFailures после стольких ошибок сервиса подряд проход прерывается
+const maxConsecutiveFailures = 5
+
+// maxLinkLength размер колонки songs.link
+const maxLinkLength = 255
+
+// rateLimitKey бакет запросов к сервису в ratelimit.Store
+const rateLimitKey = "enrichment:provider"
+
+type Store interface {
+	IncompleteSongs(ctx context.Context, limit int, recheckBefore time.Time, retryBefore time.Time) ([]models.Song, error)
+	FillSong(ctx context.Context, id int64, details storage.SongDetails) error
+	RecordEnrichment(ctx context.Context, songID int64, outcome storage.EnrichmentOutcome) error
+}
+
+// Summary итог одного прохода
+type Summary struct {
+	Checked  int `json:"checked"`
+	Enriched int `json:"enriched"`
+	NoData   int `json:"no_data"`
+	NotFound int `json:"not_found"`
+	Failed   int `json:"failed"`
+}
+
+// Enricher дозаполняет пустые текст, дату и ссылку у песен данными из Provider.
+// Работает и разовым проходом (RunOnce), и как фоновый воркер (Run).
+type Enricher struct {
+	log      *slog.Logger
+	store    Store
+	provider Provider
+	limiter  ratelimit.Store
+	cfg      config.Enrichment
+}
+
+func New(log *slog.Logger, store Store, provider Provider, limiter ratelimit.Store, cfg config.Enrichment) *Enricher {
+	return &Enricher{
+		log:      log.With(slog.String("component", "enrich"), slog.String("provider", provider.Name())),
+		store:    store,
+		provider: provider,
+		limiter:  limiter,
+		cfg:      cfg,
+	}
+}
+
+func (e *Enricher) Name() string {
+	return "enrichment"
+}
+
+// Run проходит по песням сразу и затем каждые cfg.Interval до отмены ctx.
+// Полные пачки идут подряд без ожидания. Ошибка прохода логируется и не останавливает воркер.
+func (e *Enricher) Run(ctx context.Context) error {
+	ticker := time.NewTicker(e.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		summary, err := e.RunOnce(ctx)
+		if err != nil && ctx.Err() == nil {
+			e.log.Error("enrichment pass failed", sl.Err(err))
+		}
+		if err == nil && summary.Checked >= e.cfg.BatchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce проверяет одну пачку неполных песен, не больше cfg.BatchSize
+func (e *Enricher) RunOnce(ctx context.Context) (Summary, error) {
+	const op = "lib.enrich.RunOnce"
+
+	var summary Summary
+
+	now := time.Now()
+	songs, err := e.store.IncompleteSongs(ctx, e.cfg.BatchSize, now.Add(-e.cfg.RecheckAfter), now.Add(-e.cfg.RetryAfter))
+	if err != nil {
+		return summary, fmt.Errorf("%s: %w", op, err)
+	}
+	if len(songs) == 0 {
+		e.log.Debug("no incomplete songs")
+		return summary, nil
+	}
+
+	rule := ratelimit.Rule{Requests: e.cfg.RateLimit.Requests, Window: e.cfg.RateLimit.Window}
+	failures := 0
+
+	for _, song := range songs {
+		if err := ratelimit.Wait(ctx, e.limiter, rateLimitKey, rule); err != nil {
+			return summary, fmt.Errorf("%s: %w", op, err)
+		}
+
+		outcome := e.enrich(ctx, song)
+		if ctx.Err() != nil {
+			// прерванную проверку не записываем, песня попадет в следующий проход
+			return summary, ctx.Err()
+		}
+
+		if err := e.store.RecordEnrichment(ctx, song.ID, outcome); err != nil {
+			return summary, fmt.Errorf("%s: %w", op, err)
+		}
+
+		summary.Checked++
+		switch outcome.Status {
+		case StatusEnriched:
+			summary.Enriched++
+		case StatusNoData:
+			summary.NoData++
+		case StatusNotFound:
+			summary.NotFound++
+		case StatusFailed:
+			summary.Failed++
+		}
+
+		if outcome.Status != StatusFailed {
+			failures = 0
+			continue
+		}
+		if failures++; failures >= maxConsecutiveFailures {
+			e.log.Warn("provider keeps failing, pass stopped", slog.Int("failures", failures))
+			break
+		}
+	}
+
+	e.log.Info("enrichment pass finished",
+		slog.Int("checked", summary.Checked),
+		slog.Int("enriched", summary.Enriched),
+		slog.Int("no_data", summary.NoData),
+		slog.Int("not_found", summary.NotFound),
+		slog.Int("failed", summary.Failed),
+	)
+
+	return summary, nil
+}
+
+// enrich спрашивает сервис о песне и записывает найденное в пустые поля
+func (e *Enricher) enrich(ctx context.Context, song models.Song) storage.EnrichmentOutcome {
+	log := e.log.With(slog.Int64("song_id", song.ID), slog.String("song", song.Song))
+
+	info, err := e.lookup(ctx, song)
+	if errors.Is(err, ErrNotFound) {
+		log.Debug("song not found by provider")
+		return storage.EnrichmentOutcome{Status: StatusNotFound}
+	}
+	if err != nil {
+		log.Warn("provider lookup failed", sl.Err(err))
+		return storage.EnrichmentOutcome{Status: StatusFailed, Error: err.Error()}
+	}
+
+	details, filled := missing(song, info)
+	if len(filled) == 0 {
+		return storage.EnrichmentOutcome{Status: StatusNoData}
+	}
+
+	if err := e.store.FillSong(ctx, song.ID, details); err != nil {
+		log.Error("failed to fill song", sl.Err(err))
+		return storage.EnrichmentOutcome{Status: StatusFailed, Error: err.Error()}
+	}
+
+	log.Info("song enriched", slog.Any("filled", filled))
+	return storage.EnrichmentOutcome{Status: StatusEnriched, Filled: strings.Join(filled, ",")}
+}
+
+// lookup запрос к сервису в отдельном спане, чтобы его задержка была видна в трейсе
+func (e *Enricher) lookup(ctx context.Context, song models.Song) (info Info, err error) {
+	ctx, span := otel.Tracer(tracerName).Start(ctx, "enrich.lookup",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("enrich.provider", e.provider.Name()),
+			attribute.Int64("song.id", song.ID),
+		),
+	)
+	defer func() {
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	return e.provider.Lookup(ctx, song.Group, song.Song)
+}
+
+// missing оставляет из ответа сервиса только корректные значения для пустых полей песни
+func missing(song models.Song, info Info) (storage.SongDetails, []string) {
+	var (
+		details storage.SongDetails
+		filled  []string
+	)
+
+	if song.Text == "" && strings.TrimSpace(info.Text) != "" {
+		details.Text = info.Text
+		filled = append(filled, "text")
+	}
+	if !song.ReleaseDate.Valid && info.ReleaseDate != "" {
+		if date, err := validate.NormalizeDate(info.ReleaseDate); err == nil {
+			details.Date = date
+			filled = append(filled, "release_date")
+		}
+	}
+	if song.Link == "" && validLink(info.Link) {
+		details.Link = info.Link
+		filled = append(filled, "link")
+	}
+
+	return details, filled
+}
+
+func validLink(link string) bool {
+	if link == "" || len(link) > maxLinkLength {
+		return false
+	}
+	u, err := url.ParseRequestURI(link)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
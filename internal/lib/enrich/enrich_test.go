@@ -0,0 +1,259 @@
+package enrich
+
+import (
+	"RestApi_v1/internal/config/internal/config"
+	"RestApi_v1/internal/config/internal/lib/ratelimit"
+	"RestApi_v1/internal/config/internal/models"
+	"RestApi_v1/internal/config/internal/storage"
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeStore песни в памяти, запоминает заполненное и итоги проверок
+type fakeStore struct {
+	songs    []models.Song
+	fillErr  error
+	filled   map[int64]storage.SongDetails
+	outcomes map[int64]storage.EnrichmentOutcome
+}
+
+func (s *fakeStore) IncompleteSongs(_ context.Context, limit int, _ time.Time, _ time.Time) ([]models.Song, error) {
+	return s.songs[:min(limit, len(s.songs))], nil
+}
+
+func (s *fakeStore) FillSong(_ context.Context, id int64, details storage.SongDetails) error {
+	if s.fillErr != nil {
+		return s.fillErr
+	}
+	s.filled[id] = details
+	return nil
+}
+
+func (s *fakeStore) RecordEnrichment(_ context.Context, songID int64, outcome storage.EnrichmentOutcome) error {
+	s.outcomes[songID] = outcome
+	return nil
+}
+
+// brokenProvider сервис, который падает на песнях из failing, остальное отдает fake-провайдеру
+type brokenProvider struct {
+	*FakeProvider
+	failing map[string]bool
+}
+
+func (p brokenProvider) Lookup(ctx context.Context, group string, song string) (Info, error) {
+	if p.failing[song] {
+		return Info{}, errors.New("provider is down")
+	}
+	return p.FakeProvider.Lookup(ctx, group, song)
+}
+
+var fixtures = []Fixture{
+	{Group: "Muse", Song: "Hysteria", Info: Info{ReleaseDate: "01.12.2003", Text: "It's bugging me", Link: "https://example.com/hysteria"}},
+	{Group: "Muse", Song: "Uprising", Info: Info{}},
+	{Group: "Muse", Song: "Starlight", Info: Info{ReleaseDate: "not a date", Link: "ftp://example.com/starlight"}},
+}
+
+func song(id int64, name string) models.Song {
+	return models.Song{ID: id, Song: name, Group: "Muse"}
+}
+
+func newEnricher(store Store, provider Provider) *Enricher {
+	return New(slog.New(slog.NewTextHandler(io.Discard, nil)), store, provider, ratelimit.NewMemoryStore(), config.Enrichment{
+		BatchSize:    20,
+		RecheckAfter: time.Hour,
+		RetryAfter:   time.Hour,
+	})
+}
+
+func TestRunOnce(t *testing.T) {
+	tests := []struct {
+		name     string
+		songs    []models.Song
+		failing  []string
+		fillErr  error
+		want     Summary
+		statuses map[int64]string
+	}{
+		{
+			name:     "enriched",
+			songs:    []models.Song{song(1, "Hysteria")},
+			want:     Summary{Checked: 1, Enriched: 1},
+			statuses: map[int64]string{1: StatusEnriched},
+		},
+		{
+			name:     "no data",
+			songs:    []models.Song{song(1, "Uprising")},
+			want:     Summary{Checked: 1, NoData: 1},
+			statuses: map[int64]string{1: StatusNoData},
+		},
+		{
+			name:     "invalid date and link are no data",
+			songs:    []models.Song{song(1, "Starlight")},
+			want:     Summary{Checked: 1, NoData: 1},
+			statuses: map[int64]string{1: StatusNoData},
+		},
+		{
+			name:     "not found",
+			songs:    []models.Song{song(1, "Unknown")},
+			want:     Summary{Checked: 1, NotFound: 1},
+			statuses: map[int64]string{1: StatusNotFound},
+		},
+		{
+			name:     "provider failed",
+			songs:    []models.Song{song(1, "Hysteria"), song(2, "Uprising")},
+			failing:  []string{"Hysteria"},
+			want:     Summary{Checked: 2, NoData: 1, Failed: 1},
+			statuses: map[int64]string{1: StatusFailed, 2: StatusNoData},
+		},
+		{
+			name:     "fill failed",
+			songs:    []models.Song{song(1, "Hysteria")},
+			fillErr:  errors.New("db is down"),
+			want:     Summary{Checked: 1, Failed: 1},
+			statuses: map[int64]string{1: StatusFailed},
+		},
+		{
+			name: "consecutive failures stop the pass",
+			songs: []models.Song{
+				song(1, "Bad"), song(2, "Bad"), song(3, "Bad"), song(4, "Bad"), song(5, "Bad"), song(6, "Hysteria"),
+			},
+			failing: []string{"Bad"},
+			want:    Summary{Checked: maxConsecutiveFailures, Failed: maxConsecutiveFailures},
+		},
+		{
+			name: "success resets the failure count",
+			songs: []models.Song{
+				song(1, "Bad"), song(2, "Bad"), song(3, "Bad"), song(4, "Bad"), song(5, "Uprising"),
+				song(6, "Bad"), song(7, "Hysteria"),
+			},
+			failing: []string{"Bad"},
+			want:    Summary{Checked: 7, Enriched: 1, NoData: 1, Failed: 5},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakeStore{
+				songs:    tt.songs,
+				fillErr:  tt.fillErr,
+				filled:   make(map[int64]storage.SongDetails),
+				outcomes: make(map[int64]storage.EnrichmentOutcome),
+			}
+			provider := brokenProvider{FakeProvider: NewFakeProvider(fixtures), failing: make(map[string]bool)}
+			for _, name := range tt.failing {
+				provider.failing[name] = true
+			}
+
+			summary, err := newEnricher(store, provider).RunOnce(context.Background())
+			if err != nil {
+				t.Fatalf("RunOnce() error = %v", err)
+			}
+			if summary != tt.want {
+				t.Errorf("RunOnce() = %+v, want %+v", summary, tt.want)
+			}
+			if len(store.outcomes) != tt.want.Checked {
+				t.Errorf("recorded %d outcomes, want %d", len(store.outcomes), tt.want.Checked)
+			}
+			for id, status := range tt.statuses {
+				if got := store.outcomes[id].Status; got != status {
+					t.Errorf("song %d status = %q, want %q", id, got, status)
+				}
+			}
+		})
+	}
+}
+
+func TestRunOnceFillsOnlyMissing(t *testing.T) {
+	store := &fakeStore{
+		songs:    []models.Song{{ID: 1, Song: "Hysteria", Group: "Muse", Text: "own text"}},
+		filled:   make(map[int64]storage.SongDetails),
+		outcomes: make(map[int64]storage.EnrichmentOutcome),
+	}
+
+	if _, err := newEnricher(store, NewFakeProvider(fixtures)).RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce() error = %v", err)
+	}
+
+	want := storage.SongDetails{Date: "2003-12-01", Link: "https://example.com/hysteria"}
+	if got := store.filled[1]; got != want {
+		t.Errorf("filled = %+v, want %+v", got, want)
+	}
+	if got := store.outcomes[1].Filled; got != "release_date,link" {
+		t.Errorf("outcome filled = %q, want %q", got, "release_date,link")
+	}
+}
+
+func TestMissing(t *testing.T) {
+	released := sql.NullTime{Time: time.Date(2003, 12, 1, 0, 0, 0, 0, time.UTC), Valid: true}
+
+	tests := []struct {
+		name   string
+		song   models.Song
+		info   Info
+		want   storage.SongDetails
+		filled []string
+	}{
+		{
+			name:   "all empty",
+			song:   models.Song{},
+			info:   Info{Text: "text", ReleaseDate: "2003-12-01", Link: "https://example.com"},
+			want:   storage.SongDetails{Text: "text", Date: "2003-12-01", Link: "https://example.com"},
+			filled: []string{"text", "release_date", "link"},
+		},
+		{
+			name: "all filled",
+			song: models.Song{Text: "own", ReleaseDate: released, Link: "https://own.example.com"},
+			info: Info{Text: "text", ReleaseDate: "2003-12-01", Link: "https://example.com"},
+		},
+		{
+			name:   "date in dd.mm.yyyy",
+			info:   Info{ReleaseDate: "01.12.2003"},
+			want:   storage.SongDetails{Date: "2003-12-01"},
+			filled: []string{"release_date"},
+		},
+		{
+			name: "blank text and invalid values",
+			info: Info{Text: "  \n", ReleaseDate: "32.13.2003", Link: "example.com"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, filled := missing(tt.song, tt.info)
+			if got != tt.want {
+				t.Errorf("missing() details = %+v, want %+v", got, tt.want)
+			}
+			if strings.Join(filled, ",") != strings.Join(tt.filled, ",") {
+				t.Errorf("missing() filled = %v, want %v", filled, tt.filled)
+			}
+		})
+	}
+}
+
+func TestValidLink(t *testing.T) {
+	tests := []struct {
+		link string
+		want bool
+	}{
+		{link: "https://example.com/song", want: true},
+		{link: "http://example.com", want: true},
+		{link: "", want: false},
+		{link: "example.com/song", want: false},
+		{link: "ftp://example.com/song", want: false},
+		{link: "https:///song", want: false},
+		{link: "javascript:alert(1)", want: false},
+		{link: "https://example.com/" + strings.Repeat("a", maxLinkLength), want: false},
+	}
+
+	for _, tt := range tests {
+		if got := validLink(tt.link); got != tt.want {
+			t.Errorf("validLink(%q) = %v, want %v", tt.link, got, tt.want)
+		}
+	}
+}
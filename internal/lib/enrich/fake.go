@@ -0,0 +1,111 @@
+package enrich
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Fixture ответ fake-сервиса для одной песни
+type Fixture struct {
+	Group string `json:"group"`
+	Song  string `json:"song"`
+	Info
+}
+
+// FakeProvider локальный сервис для разработки и проверок. С фикстурами знает только их,
+// без фикстур для любой песни отдает детерминированные выдуманные данные.
+type FakeProvider struct {
+	fixtures map[string]Info
+}
+
+func NewFakeProvider(fixtures []Fixture) *FakeProvider {
+	p := &FakeProvider{}
+	if fixtures != nil {
+		p.fixtures = make(map[string]Info, len(fixtures))
+		for _, f := range fixtures {
+			p.fixtures[fixtureKey(f.Group, f.Song)] = f.Info
+		}
+	}
+	return p
+}
+
+// LoadFakeProvider читает фикстуры из JSON-массива
+func LoadFakeProvider(path string) (*FakeProvider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixtures: %w", err)
+	}
+
+	var fixtures []Fixture
+	if err := json.Unmarshal(data, &fixtures); err != nil {
+		return nil, fmt.Errorf("failed to parse fixtures %s: %w", path, err)
+	}
+	return NewFakeProvider(fixtures), nil
+}
+
+func (p *FakeProvider) Name() string {
+	return ProviderFake
+}
+
+func (p *FakeProvider) Lookup(ctx context.Context, group string, song string) (Info, error) {
+	if err := ctx.Err(); err != nil {
+		return Info{}, err
+	}
+
+	if p.fixtures != nil {
+		info, ok := p.fixtures[fixtureKey(group, song)]
+		if !ok {
+			return Info{}, ErrNotFound
+		}
+		return info, nil
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(group + "\x00" + song))
+	released := time.Date(1960, 1, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, int(h.Sum32()%(60*365)))
+
+	return Info{
+		ReleaseDate: released.Format("02.01.2006"),
+		Text:        fmt.Sprintf("%s\n\n(lyrics of %q by %s)", song, song, group),
+		Link:        "https://example.com/songs?q=" + url.QueryEscape(group+" "+song),
+	}, nil
+}
+
+func fixtureKey(group string, song string) string {
+	return strings.ToLower(group) + "\x00" + strings.ToLower(song)
+}
+
+// FakeHandler отдает провайдер по HTTP в формате GET /info?group=...&song=...,
+// чтобы проверять HTTPProvider без настоящего сервиса
+func FakeHandler(p Provider) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /info", func(w http.ResponseWriter, r *http.Request) {
+		group, song := r.URL.Query().Get("group"), r.URL.Query().Get("song")
+		if song == "" {
+			http.Error(w, "song is required", http.StatusBadRequest)
+			return
+		}
+
+		info, err := p.Lookup(r.Context(), group, song)
+		if errors.Is(err, ErrNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(info)
+	})
+	return mux
+}
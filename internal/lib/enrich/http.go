@@ -0,0 +1,73 @@
+package enrich
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// HTTPProvider сервис с API GET {baseURL}/info?group=...&song=...
+type HTTPProvider struct {
+	baseURL string
+	apiKey  string
+	client  *http.Client
+}
+
+func NewHTTPProvider(baseURL string, apiKey string, timeout time.Duration) *HTTPProvider {
+	return &HTTPProvider{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		client:  &http.Client{Timeout: timeout},
+	}
+}
+
+func (p *HTTPProvider) Name() string {
+	return ProviderHTTP
+}
+
+func (p *HTTPProvider) Lookup(ctx context.Context, group string, song string) (Info, error) {
+	const op = "lib.enrich.HTTPProvider.Lookup"
+
+	q := url.Values{}
+	q.Set("group", group)
+	q.Set("song", song)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.baseURL+"/info?"+q.Encode(), nil)
+	if err != nil {
+		return Info{}, fmt.Errorf("%s: %w", op, err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if p.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+	// трейс продолжается в сервисе, если он тоже с OpenTelemetry
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return Info{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return Info{}, ErrNotFound
+	case resp.StatusCode != http.StatusOK:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return Info{}, fmt.Errorf("%s: unexpected status %d: %s", op, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var info Info
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&info); err != nil {
+		return Info{}, fmt.Errorf("%s: failed to decode response: %w", op, err)
+	}
+	return info, nil
+}
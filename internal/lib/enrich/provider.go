@@ -0,0 +1,53 @@
+package enrich
+
+import (
+	"RestApi_v1/internal/config/internal/config"
+	"context"
+	"errors"
+	"fmt"
+)
+
+const (
+	ProviderHTTP = "http"
+	ProviderFake = "fake"
+)
+
+// ErrNotFound сервис ничего не знает о песне
+var ErrNotFound = errors.New("song not found by provider")
+
+// Info данные о песне от сервиса, как в ответе GET /info
+type Info struct {
+	ReleaseDate string `json:"releaseDate"` // 02.01.2006 или 2006-01-02
+	Text        string `json:"text"`
+	Link        string `json:"link"`
+}
+
+// Provider внешний сервис с данными о песнях
+type Provider interface {
+	Name() string
+	Lookup(ctx context.Context, group string, song string) (Info, error)
+}
+
+// NewProvider создает сервис по конфигу
+func NewProvider(cfg config.EnrichmentProvider) (Provider, error) {
+	const op = "lib.enrich.NewProvider"
+
+	switch cfg.Kind {
+	case ProviderHTTP:
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("%s: url is required for http provider", op)
+		}
+		return NewHTTPProvider(cfg.URL, cfg.APIKey, cfg.Timeout), nil
+	case ProviderFake, "":
+		if cfg.FixturesPath == "" {
+			return NewFakeProvider(nil), nil
+		}
+		p, err := LoadFakeProvider(cfg.FixturesPath)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		return p, nil
+	default:
+		return nil, fmt.Errorf("%s: unknown provider %q", op, cfg.Kind)
+	}
+}
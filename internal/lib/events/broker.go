@@ -0,0 +1,351 @@
+package events
+
+import (
+	"RestApi_v1/internal/config/internal/config"
+	"RestApi_v1/internal/config/internal/lib/logger/sl"
+	"RestApi_v1/internal/config/internal/models"
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// replayBatch событий за один запрос к журналу
+const replayBatch = 500
+
+const (
+	minReconnectDelay = time.Second
+	maxReconnectDelay = 30 * time.Second
+)
+
+type Store interface {
+	SongEventsAfter(ctx context.Context, afterID int64, limit int) ([]models.SongEvent, error)
+	SongEventBounds(ctx context.Context) (first int64, last int64, err error)
+	ListenSongEvents(ctx context.Context, ready func(), fn func(id int64)) error
+}
+
+// Subscription живая подписка на события. Канал закрывается при отставании или остановке, причина в Err.
+type Subscription struct {
+	filter Filter
+	events chan models.SongEvent
+	err    error
+}
+
+func (s *Subscription) Err() error {
+	return s.err
+}
+
+// Broker читает журнал song_events и раздает новые события подписчикам этого экземпляра.
+// Журнал пишет триггер на songs, поэтому в поток попадают изменения со всех реплик,
+// из импорта и дозаполнения. О новых записях сообщает NOTIFY, на случай его потери журнал
+// дополнительно опрашивается раз в PollInterval.
+//
+// id события выдается при вставке, а видно событие становится при коммите, поэтому меньший id
+// может появиться в журнале позже большего. События рассылаются строго по порядку id:
+// за пропуском broker ждет, пока недостающее событие не закоммитят, и только через GapTimeout
+// считает его откатом. Поэтому last - граница, до которой поток полон, и продолжение
+// с Last-Event-ID ничего не теряет.
+type Broker struct {
+	log   *slog.Logger
+	store Store
+	cfg   config.Events
+
+	mu     sync.Mutex
+	last   int64 // последнее разосланное событие, все до него уже разосланы или пропущены
+	subs   map[*Subscription]struct{}
+	closed bool
+
+	// gapSince когда замечен пропуск сразу после last, нулевое - пропуска нет
+	gapSince time.Time
+	now      func() time.Time
+
+	started chan struct{} // закрыт, когда last прочитан из журнала
+	wake    chan struct{}
+}
+
+func NewBroker(log *slog.Logger, store Store, cfg config.Events) *Broker {
+	return &Broker{
+		log:     log.With(slog.String("component", "events")),
+		store:   store,
+		cfg:     cfg,
+		subs:    make(map[*Subscription]struct{}),
+		started: make(chan struct{}),
+		wake:    make(chan struct{}, 1),
+		now:     time.Now,
+	}
+}
+
+func (b *Broker) Name() string {
+	return "events"
+}
+
+func (b *Broker) Run(ctx context.Context) error {
+	defer b.Close()
+
+	go b.listen(ctx)
+
+	ticker := time.NewTicker(b.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := b.poll(ctx); err != nil && ctx.Err() == nil {
+			b.log.Error("failed to read song events", sl.Err(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-b.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// Close отключает всех подписчиков и больше не принимает новых.
+// Вызывается до остановки HTTP-сервера, иначе открытые потоки не дадут ему завершиться.
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return
+	}
+	b.closed = true
+
+	for sub := range b.subs {
+		b.drop(sub, ErrClosed)
+	}
+}
+
+// Subscribe подписывает на новые события и возвращает id последнего события до подписки:
+// всё, что после него, придет в подписку.
+func (b *Broker) Subscribe(ctx context.Context, filter Filter) (*Subscription, int64, error) {
+	select {
+	case <-b.started:
+	case <-ctx.Done():
+		return nil, 0, ctx.Err()
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return nil, 0, ErrClosed
+	}
+
+	sub := &Subscription{
+		filter: filter,
+		events: make(chan models.SongEvent, b.cfg.Buffer),
+	}
+	b.subs[sub] = struct{}{}
+
+	return sub, b.last, nil
+}
+
+func (b *Broker) Unsubscribe(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.subs[sub]; ok {
+		delete(b.subs, sub)
+		close(sub.events)
+	}
+}
+
+// Follow отправляет в sink события по фильтру, пока не отменен ctx или не закрыта подписка.
+// Если resume, сначала досылает из журнала события после lastID.
+func (b *Broker) Follow(ctx context.Context, filter Filter, lastID int64, resume bool, sink Sink) error {
+	sub, last, err := b.Subscribe(ctx, filter)
+	if err != nil {
+		return err
+	}
+	defer b.Unsubscribe(sub)
+
+	if resume && lastID < last {
+		if err := b.replay(ctx, filter, lastID, last, sink); err != nil {
+			return err
+		}
+	}
+
+	heartbeat := time.NewTicker(b.cfg.Heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case e, ok := <-sub.events:
+			if !ok {
+				return sub.Err()
+			}
+			if err := sink.Send(newEvent(e)); err != nil {
+				return err
+			}
+		case <-heartbeat.C:
+			if err := sink.Heartbeat(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// replay досылает события из журнала в промежутке (after, until]
+func (b *Broker) replay(ctx context.Context, filter Filter, after int64, until int64, sink Sink) error {
+	first, _, err := b.store.SongEventBounds(ctx)
+	if err != nil {
+		return err
+	}
+
+	// начало промежутка уже удалено из журнала
+	if first == 0 || first > after+1 {
+		if err := sink.Reset(); err != nil {
+			return err
+		}
+		after = max(after, first-1)
+	}
+
+	for after < until {
+		events, err := b.store.SongEventsAfter(ctx, after, replayBatch)
+		if err != nil {
+			return err
+		}
+		if len(events) == 0 {
+			return nil
+		}
+
+		for _, e := range events {
+			if e.ID > until {
+				return nil
+			}
+			after = e.ID
+			if !filter.Match(e) {
+				continue
+			}
+			if err := sink.Send(newEvent(e)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// poll рассылает события журнала после last
+func (b *Broker) poll(ctx context.Context) error {
+	select {
+	case <-b.started:
+	default:
+		// подписчики получают только события после запуска
+		_, last, err := b.store.SongEventBounds(ctx)
+		if err != nil {
+			return err
+		}
+		b.last = last
+		close(b.started)
+	}
+
+	for {
+		b.mu.Lock()
+		after := b.last
+		b.mu.Unlock()
+
+		events, err := b.store.SongEventsAfter(ctx, after, replayBatch)
+		if err != nil {
+			return err
+		}
+
+		for _, e := range events {
+			if !b.publish(e) {
+				// ждем пропущенное событие, остальные перечитаем следующим опросом
+				return nil
+			}
+		}
+
+		if len(events) < replayBatch {
+			return nil
+		}
+	}
+}
+
+// publish рассылает событие, если все события до него уже разосланы или пропуск перед ним
+// длится дольше GapTimeout. false - событие отложено до следующего опроса.
+func (b *Broker) publish(e models.SongEvent) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if e.ID <= b.last {
+		return true
+	}
+	if e.ID > b.last+1 {
+		now := b.now()
+		if b.gapSince.IsZero() {
+			b.gapSince = now
+		}
+		if now.Sub(b.gapSince) < b.cfg.GapTimeout {
+			return false
+		}
+		b.log.Warn("skipping song events that were never committed",
+			slog.Int64("from", b.last+1),
+			slog.Int64("to", e.ID-1),
+		)
+	}
+
+	b.last = e.ID
+	b.gapSince = time.Time{}
+
+	for sub := range b.subs {
+		if !sub.filter.Match(e) {
+			continue
+		}
+
+		select {
+		case sub.events <- e:
+		default:
+			b.log.Warn("dropping slow events subscriber", slog.Int64("event_id", e.ID))
+			b.drop(sub, ErrLagging)
+		}
+	}
+
+	return true
+}
+
+// drop закрывает подписку, вызывается под b.mu
+func (b *Broker) drop(sub *Subscription, reason error) {
+	sub.err = reason
+	delete(b.subs, sub)
+	close(sub.events)
+}
+
+// listen будит poll по уведомлениям о новых событиях, переподключаясь при обрыве
+func (b *Broker) listen(ctx context.Context) {
+	delay := minReconnectDelay
+
+	for {
+		err := b.store.ListenSongEvents(ctx, func() {
+			// пока подписки не было, уведомления терялись
+			delay = minReconnectDelay
+			b.notify()
+		}, func(int64) {
+			b.notify()
+		})
+		if ctx.Err() != nil {
+			return
+		}
+
+		b.log.Error("song events subscription lost", slog.Duration("retry_in", delay), sl.Err(err))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxReconnectDelay)
+	}
+}
+
+func (b *Broker) notify() {
+	select {
+	case b.wake <- struct{}{}:
+	default:
+	}
+}
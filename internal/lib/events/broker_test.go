@@ -0,0 +1,203 @@
+package events
+
+import (
+	"RestApi_v1/internal/config/internal/config"
+	"RestApi_v1/internal/config/internal/models"
+	"context"
+	"io"
+	"log/slog"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// journal журнал событий, в котором видны только закоммиченные записи
+type journal struct {
+	mu     sync.Mutex
+	events []models.SongEvent
+}
+
+func (j *journal) commit(ids ...int64) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	for _, id := range ids {
+		j.events = append(j.events, models.SongEvent{ID: id, Op: models.SongInserted, SongID: id})
+	}
+	sort.Slice(j.events, func(a, b int) bool { return j.events[a].ID < j.events[b].ID })
+}
+
+func (j *journal) SongEventsAfter(_ context.Context, afterID int64, limit int) ([]models.SongEvent, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	var events []models.SongEvent
+	for _, e := range j.events {
+		if e.ID > afterID && len(events) < limit {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+func (j *journal) SongEventBounds(_ context.Context) (int64, int64, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if len(j.events) == 0 {
+		return 0, 0, nil
+	}
+	return j.events[0].ID, j.events[len(j.events)-1].ID, nil
+}
+
+func (j *journal) ListenSongEvents(ctx context.Context, ready func(), _ func(id int64)) error {
+	ready()
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+var testConfig = config.Events{
+	PollInterval: time.Minute,
+	Heartbeat:    time.Minute,
+	Buffer:       16,
+	GapTimeout:   10 * time.Second,
+}
+
+func received(sub *Subscription) []int64 {
+	var ids []int64
+	for {
+		select {
+		case e := <-sub.events:
+			ids = append(ids, e.ID)
+		default:
+			return ids
+		}
+	}
+}
+
+func sameIDs(a, b []int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// pollStep что закоммичено перед опросом, сколько прошло времени и что опрос разослал
+type pollStep struct {
+	commit  []int64
+	elapsed time.Duration
+	want    []int64
+	last    int64
+}
+
+func TestBrokerOutOfOrderCommit(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps []pollStep
+	}{
+		{
+			name: "lower id committed later is delivered in order",
+			steps: []pollStep{
+				{commit: []int64{2}, want: nil, last: 0},
+				{commit: []int64{1}, elapsed: time.Second, want: []int64{1, 2}, last: 2},
+				{commit: []int64{3}, want: []int64{3}, last: 3},
+			},
+		},
+		{
+			name: "rolled back id is skipped after the timeout",
+			steps: []pollStep{
+				{commit: []int64{1, 3}, want: []int64{1}, last: 1},
+				{elapsed: 5 * time.Second, want: nil, last: 1},
+				{commit: []int64{4}, elapsed: 5 * time.Second, want: []int64{3, 4}, last: 4},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &journal{}
+			b := NewBroker(slog.New(slog.NewTextHandler(io.Discard, nil)), store, testConfig)
+			now := time.Unix(1700000000, 0)
+			b.now = func() time.Time { return now }
+
+			ctx := context.Background()
+			if err := b.poll(ctx); err != nil {
+				t.Fatalf("poll() error = %v", err)
+			}
+			sub, _, err := b.Subscribe(ctx, Filter{})
+			if err != nil {
+				t.Fatalf("Subscribe() error = %v", err)
+			}
+
+			for i, st := range tt.steps {
+				store.commit(st.commit...)
+				now = now.Add(st.elapsed)
+
+				if err := b.poll(ctx); err != nil {
+					t.Fatalf("step %d: poll() error = %v", i, err)
+				}
+				if got := received(sub); !sameIDs(got, st.want) {
+					t.Errorf("step %d: delivered %v, want %v", i, got, st.want)
+				}
+				// до last поток полон, с него продолжает новая подписка и Last-Event-ID
+				if _, last, _ := b.Subscribe(ctx, Filter{}); last != st.last {
+					t.Errorf("step %d: last = %d, want %d", i, last, st.last)
+				}
+			}
+		})
+	}
+}
+
+func TestBrokerResumeAfterOutOfOrderCommit(t *testing.T) {
+	store := &journal{}
+	b := NewBroker(slog.New(slog.NewTextHandler(io.Discard, nil)), store, testConfig)
+
+	ctx := context.Background()
+	if err := b.poll(ctx); err != nil {
+		t.Fatalf("poll() error = %v", err)
+	}
+
+	// клиент видел событие 1 и отключился, пока 3 закоммичено раньше 2
+	store.commit(1)
+	_ = b.poll(ctx)
+	store.commit(3)
+	_ = b.poll(ctx)
+	store.commit(2)
+	_ = b.poll(ctx)
+
+	sink := &recordSink{}
+	ctx, cancel := context.WithCancel(ctx)
+	sink.done = cancel
+	sink.stopAt = 2
+
+	if err := b.Follow(ctx, Filter{}, 1, true, sink); err != nil {
+		t.Fatalf("Follow() error = %v", err)
+	}
+	if !sameIDs(sink.ids, []int64{2, 3}) {
+		t.Errorf("resumed with %v, want [2 3]", sink.ids)
+	}
+}
+
+// recordSink запоминает события и отменяет поток после stopAt событий
+type recordSink struct {
+	ids    []int64
+	stopAt int
+	done   func()
+}
+
+func (s *recordSink) Send(e Event) error {
+	s.ids = append(s.ids, e.ID)
+	if len(s.ids) == s.stopAt {
+		s.done()
+	}
+	return nil
+}
+
+func (s *recordSink) Reset() error     { return nil }
+func (s *recordSink) Heartbeat() error { return nil }
@@ -0,0 +1,56 @@
+package events
+
+import (
+	"RestApi_v1/internal/config/internal/config"
+	"RestApi_v1/internal/config/internal/lib/logger/sl"
+	"context"
+	"log/slog"
+	"time"
+)
+
+type Trimmer interface {
+	TrimSongEvents(ctx context.Context, keep int, before time.Time) (int64, error)
+}
+
+// Cleaner фоновый воркер, ограничивающий журнал событий по размеру и возрасту
+type Cleaner struct {
+	log     *slog.Logger
+	trimmer Trimmer
+	cfg     config.Events
+}
+
+func NewCleaner(log *slog.Logger, trimmer Trimmer, cfg config.Events) *Cleaner {
+	return &Cleaner{
+		log:     log.With(slog.String("component", "events/cleaner")),
+		trimmer: trimmer,
+		cfg:     cfg,
+	}
+}
+
+func (c *Cleaner) Name() string {
+	return "events-cleaner"
+}
+
+func (c *Cleaner) Run(ctx context.Context) error {
+	ticker := time.NewTicker(c.cfg.TrimInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		deleted, err := c.trimmer.TrimSongEvents(ctx, c.cfg.LogSize, time.Now().Add(-c.cfg.Retention))
+		if err != nil {
+			if ctx.Err() == nil {
+				c.log.Error("failed to trim song events", sl.Err(err))
+			}
+			continue
+		}
+		if deleted > 0 {
+			c.log.Debug("song events trimmed", slog.Int64("deleted", deleted))
+		}
+	}
+}
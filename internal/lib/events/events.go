@@ -0,0 +1,114 @@
+package events
+
+import (
+	"RestApi_v1/internal/config/internal/models"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrLagging подписчик не успевал забирать события и отключен, ему нужно переподключиться с Last-Event-ID
+	ErrLagging = errors.New("subscriber is too slow")
+	// ErrClosed сервис останавливается
+	ErrClosed = errors.New("event broker is closed")
+
+	ErrInvalidFilter = errors.New("invalid filter")
+)
+
+// Event событие для клиента. ID монотонно растет, по нему клиент продолжает поток.
+type Event struct {
+	ID     int64       `json:"id"`
+	Op     string      `json:"op"` // INSERT, UPDATE или DELETE
+	SongID int64       `json:"song_id"`
+	Song   models.Song `json:"song"` // после изменения, для DELETE - последнее состояние
+	At     time.Time   `json:"at"`
+}
+
+func newEvent(e models.SongEvent) Event {
+	return Event{
+		ID:     e.ID,
+		Op:     e.Op,
+		SongID: e.SongID,
+		Song:   e.Song,
+		At:     e.CreatedAt,
+	}
+}
+
+// Sink отправляет события клиенту в формате транспорта (SSE, WebSocket)
+type Sink interface {
+	Send(event Event) error
+	// Reset сообщает, что часть событий после Last-Event-ID уже удалена из журнала
+	// и клиенту нужно перечитать данные целиком
+	Reset() error
+	Heartbeat() error
+}
+
+// Filter отбор событий, пустые поля не фильтруют
+type Filter struct {
+	Groups []string // без учета регистра
+	Ops    []string
+}
+
+// ParseFilter читает фильтр из параметров group и op, оба можно повторять
+func ParseFilter(q url.Values) (Filter, error) {
+	var f Filter
+
+	for _, g := range q["group"] {
+		if g = strings.TrimSpace(g); g != "" {
+			f.Groups = append(f.Groups, g)
+		}
+	}
+
+	for _, op := range q["op"] {
+		op = strings.ToUpper(strings.TrimSpace(op))
+		switch op {
+		case models.SongInserted, models.SongUpdated, models.SongDeleted:
+			f.Ops = append(f.Ops, op)
+		default:
+			return f, ErrInvalidFilter
+		}
+	}
+
+	return f, nil
+}
+
+func (f Filter) Match(e models.SongEvent) bool {
+	if len(f.Ops) > 0 && !containsFold(f.Ops, e.Op) {
+		return false
+	}
+	if len(f.Groups) > 0 && !containsFold(f.Groups, e.Group) {
+		return false
+	}
+	return true
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// LastEventID читает, с какого события продолжать: заголовок Last-Event-ID (его шлет EventSource
+// при переподключении) или параметр last_event_id. false - клиент начинает с новых событий.
+func LastEventID(r *http.Request) (int64, bool, error) {
+	v := r.Header.Get("Last-Event-ID")
+	if v == "" {
+		v = r.URL.Query().Get("last_event_id")
+	}
+	if v == "" {
+		return 0, false, nil
+	}
+
+	id, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || id < 0 {
+		return 0, false, ErrInvalidFilter
+	}
+	return id, true, nil
+}
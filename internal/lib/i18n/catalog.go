@@ -0,0 +1,114 @@
+package i18n
+
+import (
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	enTranslations "github.com/go-playground/validator/v10/translations/en"
+	ruTranslations "github.com/go-playground/validator/v10/translations/ru"
+)
+
+// Ключи сообщений API. Английский текст совпадает с ключом.
+const (
+	MsgInvalidRequest     = "invalid request"
+	MsgEmptyRequest       = "empty request"
+	MsgFailedToDecode     = "failed to decode request"
+	MsgValidationError    = "validation error"
+	MsgNotFound           = "not found"
+	MsgInternalError      = "internal server error"
+	MsgSongExists         = "song already exists"
+	MsgFailedToAddSong    = "failed to add song"
+	MsgFailedToUpdateSong = "failed to update song"
+	MsgUnauthorized       = "unauthorized"
+	MsgForbidden          = "forbidden"
+	MsgRateLimited        = "rate limit exceeded, retry in {0} seconds"
+	MsgSpecMismatch       = "request does not match API specification"
+	MsgUnsupportedMedia   = "unsupported content type"
+	MsgNotAcceptable      = "response format is not supported, available: {0}"
+	MsgPayloadTooLarge    = "request body is larger than {0} bytes"
+	MsgImportRejected     = "import is not applied: {0} of {1} rows failed"
+	MsgJobNotFinished     = "job has no result yet, status: {0}"
+	MsgIdempotencyReused  = "Idempotency-Key was already used with a different request"
+	MsgIdempotencyBusy    = "request with this Idempotency-Key is still in progress"
+)
+
+type catalog struct {
+	registerDefaults func(v *validator.Validate, trans ut.Translator) error
+	// messages сообщения API по ключам Msg*
+	messages map[string]string
+	// validation свои сообщения для тегов валидатора, поверх стандартных переводов.
+	// Ключ "тег.number" или "тег.items" - вариант для числовых полей или списков и словарей.
+	validation map[string]string
+}
+
+var catalogs = map[string]catalog{
+	LangEN: {
+		registerDefaults: enTranslations.RegisterDefaultTranslations,
+		messages: map[string]string{
+			MsgInvalidRequest:     "invalid request",
+			MsgEmptyRequest:       "empty request",
+			MsgFailedToDecode:     "failed to decode request",
+			MsgValidationError:    "validation error",
+			MsgNotFound:           "not found",
+			MsgInternalError:      "internal server error",
+			MsgSongExists:         "song already exists",
+			MsgFailedToAddSong:    "failed to add song",
+			MsgFailedToUpdateSong: "failed to update song",
+			MsgUnauthorized:       "unauthorized",
+			MsgForbidden:          "forbidden",
+			MsgRateLimited:        "rate limit exceeded, retry in {0} seconds",
+			MsgSpecMismatch:       "request does not match API specification",
+			MsgUnsupportedMedia:   "unsupported content type",
+			MsgNotAcceptable:      "response format is not supported, available: {0}",
+			MsgPayloadTooLarge:    "request body is larger than {0} bytes",
+			MsgImportRejected:     "import is not applied: {0} of {1} rows failed",
+			MsgJobNotFinished:     "job has no result yet, status: {0}",
+			MsgIdempotencyReused:  "Idempotency-Key was already used with a different request",
+			MsgIdempotencyBusy:    "request with this Idempotency-Key is still in progress",
+		},
+		validation: map[string]string{
+			"required":   "field {0} is a required field",
+			"max":        "field {0} must be at most {1} characters long",
+			"max.number": "field {0} must be {1} or less",
+			"max.items":  "number of items in field {0} must be at most {1}",
+			"url":        "field {0} must be a valid URL",
+			"http_url":   "field {0} must be an http or https URL",
+			"datetime":   "field {0} must be a date in format {1}",
+			"songdate":   "field {0} must be a date in format 2006-01-02 or 02.01.2006",
+		},
+	},
+	LangRU: {
+		registerDefaults: ruTranslations.RegisterDefaultTranslations,
+		messages: map[string]string{
+			MsgInvalidRequest:     "некорректный запрос",
+			MsgEmptyRequest:       "пустой запрос",
+			MsgFailedToDecode:     "не удалось разобрать запрос",
+			MsgValidationError:    "ошибка валидации",
+			MsgNotFound:           "не найдено",
+			MsgInternalError:      "внутренняя ошибка сервера",
+			MsgSongExists:         "песня уже существует",
+			MsgFailedToAddSong:    "не удалось добавить песню",
+			MsgFailedToUpdateSong: "не удалось обновить песню",
+			MsgUnauthorized:       "требуется аутентификация",
+			MsgForbidden:          "доступ запрещен",
+			MsgRateLimited:        "превышен лимит запросов, повторите через {0} с",
+			MsgSpecMismatch:       "запрос не соответствует спецификации API",
+			MsgUnsupportedMedia:   "неподдерживаемый тип содержимого",
+			MsgNotAcceptable:      "формат ответа не поддерживается, доступны: {0}",
+			MsgPayloadTooLarge:    "тело запроса больше {0} байт",
+			MsgImportRejected:     "загрузка не применена: ошибки в {0} из {1} строк",
+			MsgJobNotFinished:     "у задачи еще нет результата, статус: {0}",
+			MsgIdempotencyReused:  "Idempotency-Key уже использован с другим запросом",
+			MsgIdempotencyBusy:    "запрос с этим Idempotency-Key еще выполняется",
+		},
+		validation: map[string]string{
+			"required":   "поле {0} обязательно для заполнения",
+			"max":        "поле {0} должно быть не длиннее {1} символов",
+			"max.number": "поле {0} должно быть не больше {1}",
+			"max.items":  "число элементов в поле {0} должно быть не больше {1}",
+			"url":        "поле {0} должно быть корректным URL",
+			"http_url":   "поле {0} должно быть URL с http или https",
+			"datetime":   "поле {0} должно быть датой в формате {1}",
+			"songdate":   "поле {0} должно быть датой в формате 2006-01-02 или 02.01.2006",
+		},
+	},
+}
@@ -0,0 +1,159 @@
+package i18n
+
+import (
+	"RestApi_v1/internal/config/internal/lib/validate"
+	"fmt"
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/ru"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	"golang.org/x/text/language"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+const (
+	LangEN = "en"
+	LangRU = "ru"
+
+	// DefaultLang язык для неизвестных и неуказанных локалей
+	DefaultLang = LangEN
+)
+
+// Translator переводит сообщения API и ошибки валидации на один язык
+type Translator struct {
+	lang  string
+	trans ut.Translator
+}
+
+// порядок важен: первый тег - язык по умолчанию для matcher
+var supported = []language.Tag{language.English, language.Russian}
+
+var (
+	matcher     = language.NewMatcher(supported)
+	translators = mustSetup(validate.Validator())
+)
+
+func mustSetup(v *validator.Validate) map[string]*Translator {
+	uni := ut.New(en.New(), en.New(), ru.New())
+
+	result := make(map[string]*Translator)
+
+	for lang, c := range catalogs {
+		trans, _ := uni.GetTranslator(lang)
+
+		if err := c.registerDefaults(v, trans); err != nil {
+			panic(fmt.Sprintf("i18n: failed to register %s validator translations: %s", lang, err))
+		}
+
+		for key, text := range c.messages {
+			if err := trans.Add(key, text, true); err != nil {
+				panic(fmt.Sprintf("i18n: failed to add %s message %q: %s", lang, key, err))
+			}
+		}
+
+		for tag, text := range c.validation {
+			var err error
+			if strings.Contains(tag, ".") {
+				// вариант по виду поля, его выбирает перевод основного тега
+				err = trans.Add(tag, text, true)
+			} else {
+				err = registerValidation(v, trans, tag, text)
+			}
+			if err != nil {
+				panic(fmt.Sprintf("i18n: failed to add %s validation message %q: %s", lang, tag, err))
+			}
+		}
+
+		result[lang] = &Translator{lang: lang, trans: trans}
+	}
+
+	return result
+}
+
+// registerValidation заменяет сообщение валидатора для тега своим из каталога.
+// В тексте {0} - имя поля, {1} - параметр тега. Если в каталоге есть вариант
+// для вида поля (см. kindSuffix), берется он.
+func registerValidation(v *validator.Validate, trans ut.Translator, tag string, text string) error {
+	return v.RegisterTranslation(tag, trans,
+		func(t ut.Translator) error {
+			return t.Add(tag, text, true)
+		},
+		func(t ut.Translator, fe validator.FieldError) string {
+			if suffix := kindSuffix(fe.Kind()); suffix != "" {
+				if msg, err := t.T(tag+suffix, fe.Field(), fe.Param()); err == nil {
+					return msg
+				}
+			}
+			msg, err := t.T(tag, fe.Field(), fe.Param())
+			if err != nil {
+				return fe.Error()
+			}
+			return msg
+		},
+	)
+}
+
+// kindSuffix суффикс варианта сообщения: у max для строки лимит в символах,
+// для числа - само значение, для списка и словаря - число элементов
+func kindSuffix(kind reflect.Kind) string {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return ".number"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return ".items"
+	}
+	return ""
+}
+
+// Get возвращает переводчик для языка, для неизвестного - английский
+func Get(lang string) *Translator {
+	if t, ok := translators[lang]; ok {
+		return t
+	}
+	return translators[DefaultLang]
+}
+
+// FromRequest выбирает язык по заголовку Accept-Language
+func FromRequest(r *http.Request) *Translator {
+	return Get(Match(r.Header.Get("Accept-Language")))
+}
+
+// Match выбирает лучший поддерживаемый язык из значения Accept-Language
+func Match(acceptLanguage string) string {
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return DefaultLang
+	}
+
+	_, idx, confidence := matcher.Match(tags...)
+	if confidence == language.No {
+		return DefaultLang
+	}
+
+	base, _ := supported[idx].Base()
+	return base.String()
+}
+
+// Lang код языка переводчика
+func (t *Translator) Lang() string {
+	return t.lang
+}
+
+// T переводит сообщение из каталога, {0}, {1}... заменяются на params.
+// Если перевода нет, возвращается сам ключ.
+func (t *Translator) T(key string, params ...string) string {
+	msg, err := t.trans.T(key, params...)
+	if err != nil {
+		return key
+	}
+	return msg
+}
+
+// Validation переводчик для validator.FieldError.Translate
+func (t *Translator) Validation() ut.Translator {
+	return t.trans
+}
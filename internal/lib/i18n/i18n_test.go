@@ -0,0 +1,120 @@
+package i18n
+
+import (
+	"RestApi_v1/internal/config/internal/lib/validate"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+)
+
+type maxRequest struct {
+	Title string            `json:"title" validate:"max=3"`
+	Year  int               `json:"year" validate:"max=2030"`
+	Tags  []string          `json:"tags" validate:"max=2"`
+	Meta  map[string]string `json:"meta" validate:"max=1"`
+}
+
+func TestMaxDependsOnKind(t *testing.T) {
+	req := maxRequest{
+		Title: "Hysteria",
+		Year:  3000,
+		Tags:  []string{"rock", "alt", "indie"},
+		Meta:  map[string]string{"a": "1", "b": "2"},
+	}
+
+	tests := []struct {
+		lang  string
+		field string
+		want  string
+	}{
+		{lang: LangEN, field: "title", want: "field title must be at most 3 characters long"},
+		{lang: LangEN, field: "year", want: "field year must be 2030 or less"},
+		{lang: LangEN, field: "tags", want: "number of items in field tags must be at most 2"},
+		{lang: LangEN, field: "meta", want: "number of items in field meta must be at most 1"},
+		{lang: LangRU, field: "title", want: "поле title должно быть не длиннее 3 символов"},
+		{lang: LangRU, field: "year", want: "поле year должно быть не больше 2030"},
+		{lang: LangRU, field: "tags", want: "число элементов в поле tags должно быть не больше 2"},
+		{lang: LangRU, field: "meta", want: "число элементов в поле meta должно быть не больше 1"},
+	}
+
+	var errs validator.ValidationErrors
+	if err := validate.Struct(req); !errors.As(err, &errs) {
+		t.Fatalf("Struct() error = %v, want validation errors", err)
+	}
+	got := make(map[string]map[string]string)
+	for _, lang := range []string{LangEN, LangRU} {
+		got[lang] = make(map[string]string)
+		for _, fe := range errs {
+			got[lang][fe.Field()] = fe.Translate(Get(lang).Validation())
+		}
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.lang+" "+tt.field, func(t *testing.T) {
+			if msg := got[tt.lang][tt.field]; msg != tt.want {
+				t.Errorf("message = %q, want %q", msg, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		name           string
+		acceptLanguage string
+		want           string
+	}{
+		{name: "missing", want: LangEN},
+		{name: "english", acceptLanguage: "en", want: LangEN},
+		{name: "russian", acceptLanguage: "ru", want: LangRU},
+		{name: "russian region", acceptLanguage: "ru-RU", want: LangRU},
+		{name: "english region", acceptLanguage: "en-GB", want: LangEN},
+		{name: "case insensitive", acceptLanguage: "RU-ru", want: LangRU},
+		{name: "higher q wins", acceptLanguage: "en;q=0.5, ru-RU;q=0.9", want: LangRU},
+		{name: "order without q", acceptLanguage: "ru-RU, ru;q=0.9, en-US;q=0.8, en;q=0.7", want: LangRU},
+		{name: "unsupported skipped", acceptLanguage: "de-DE, ru;q=0.8", want: LangRU},
+		{name: "wildcard", acceptLanguage: "*", want: LangEN},
+		{name: "unknown falls back to english", acceptLanguage: "de-DE, fr;q=0.9", want: LangEN},
+		{name: "malformed falls back to english", acceptLanguage: "ru;q=abc;;", want: LangEN},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Match(tt.acceptLanguage); got != tt.want {
+				t.Errorf("Match(%q) = %q, want %q", tt.acceptLanguage, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFromRequestFallback(t *testing.T) {
+	tests := []struct {
+		name           string
+		acceptLanguage string
+		want           string
+	}{
+		{name: "missing", want: LangEN},
+		{name: "unknown", acceptLanguage: "ja-JP", want: LangEN},
+		{name: "russian", acceptLanguage: "ru-RU,ru;q=0.9", want: LangRU},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.acceptLanguage != "" {
+				r.Header.Set("Accept-Language", tt.acceptLanguage)
+			}
+			if got := FromRequest(r).Lang(); got != tt.want {
+				t.Errorf("FromRequest().Lang() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// неизвестный код языка, например из параметров задачи, тоже дает английский
+	if got := Get("de").Lang(); got != LangEN {
+		t.Errorf("Get(%q).Lang() = %q, want %q", "de", got, LangEN)
+	}
+}
@@ -0,0 +1,144 @@
+package jobs
+
+import (
+	"RestApi_v1/internal/config/internal/lib/validate"
+	"RestApi_v1/internal/config/internal/models"
+	"RestApi_v1/internal/config/internal/storage"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	ContentTypeNDJSON = "application/x-ndjson"
+
+	// exportChunk сколько строк выгрузки сохраняется между контрольными точками
+	exportChunk = 500
+)
+
+// ExportParams фильтр выгрузки, как у GET /songs/export
+type ExportParams struct {
+	Group string `json:"group,omitempty"`
+	Song  string `json:"song,omitempty"`
+	From  string `json:"from,omitempty"`
+	To    string `json:"to,omitempty"`
+}
+
+// Filter проверяет даты и собирает фильтр для хранилища
+func (p ExportParams) Filter() (storage.SongFilter, error) {
+	filter := storage.SongFilter{
+		Group: p.Group,
+		Song:  p.Song,
+	}
+
+	if p.From != "" {
+		t, err := validate.ParseDate(p.From)
+		if err != nil {
+			return filter, err
+		}
+		filter.ReleasedFrom = &t
+	}
+	if p.To != "" {
+		t, err := validate.ParseDate(p.To)
+		if err != nil {
+			return filter, err
+		}
+		filter.ReleasedTo = &t
+	}
+
+	return filter, nil
+}
+
+type exportCheckpoint struct {
+	LastID int64 `json:"last_id"`
+}
+
+type SongExporter interface {
+	CountSongs(ctx context.Context, filter storage.SongFilter) (int, error)
+	ExportSongs(ctx context.Context, filter storage.SongFilter, batchSize int, fn func(models.Song) error) error
+}
+
+// ExportHandler выгружает песни в NDJSON. Результат копится в задаче частями,
+// контрольная точка - id последней выгруженной песни.
+type ExportHandler struct {
+	exporter SongExporter
+}
+
+func NewExportHandler(exporter SongExporter) *ExportHandler {
+	return &ExportHandler{exporter: exporter}
+}
+
+func (h *ExportHandler) Run(ctx context.Context, job models.Job, cp *Checkpointer) (storage.JobProgress, error) {
+	var params ExportParams
+	if err := json.Unmarshal(job.Params, &params); err != nil {
+		return storage.JobProgress{}, Permanent(fmt.Errorf("invalid export params: %w", err))
+	}
+
+	filter, err := params.Filter()
+	if err != nil {
+		return storage.JobProgress{}, Permanent(err)
+	}
+
+	var state exportCheckpoint
+	if len(job.Checkpoint) > 0 {
+		if err := json.Unmarshal(job.Checkpoint, &state); err != nil {
+			return storage.JobProgress{}, Permanent(fmt.Errorf("invalid export checkpoint: %w", err))
+		}
+	}
+
+	total := job.Total
+	if job.Processed == 0 {
+		if total, err = h.exporter.CountSongs(ctx, filter); err != nil {
+			return storage.JobProgress{}, err
+		}
+	}
+
+	var (
+		processed = job.Processed
+		buf       bytes.Buffer
+		enc       = json.NewEncoder(&buf)
+	)
+
+	progress := func() (storage.JobProgress, error) {
+		checkpoint, err := json.Marshal(state)
+		if err != nil {
+			return storage.JobProgress{}, err
+		}
+		return storage.JobProgress{
+			Processed:  processed,
+			Total:      max(total, processed),
+			Checkpoint: checkpoint,
+			Result:     buf.Bytes(),
+			ResultType: ContentTypeNDJSON,
+		}, nil
+	}
+
+	filter.AfterID = state.LastID
+	err = h.exporter.ExportSongs(ctx, filter, exportChunk, func(song models.Song) error {
+		if err := enc.Encode(song); err != nil {
+			return err
+		}
+		state.LastID = song.ID
+		processed++
+
+		if processed%exportChunk != 0 {
+			return nil
+		}
+
+		p, err := progress()
+		if err != nil {
+			return err
+		}
+		if err := cp.Save(ctx, p); err != nil {
+			return err
+		}
+		buf.Reset()
+		return nil
+	})
+	if err != nil {
+		return storage.JobProgress{}, err
+	}
+
+	return progress()
+}
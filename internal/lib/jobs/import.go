@@ -0,0 +1,140 @@
+package jobs
+
+import (
+	"RestApi_v1/internal/config/internal/lib/i18n"
+	"RestApi_v1/internal/config/internal/lib/songimport"
+	"RestApi_v1/internal/config/internal/models"
+	"RestApi_v1/internal/config/internal/storage"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// importChunk сколько строк best_effort-загрузки сохраняется между контрольными точками
+const importChunk = 500
+
+// ImportParams параметры задачи загрузки, входной файл хранится частями при задаче
+type ImportParams struct {
+	Mode songimport.Mode `json:"mode"`
+	// Lang язык сообщений в отчете
+	Lang string `json:"lang"`
+}
+
+type importCheckpoint struct {
+	Report songimport.Report `json:"report"`
+}
+
+// ImportHandler загружает песни из payload задачи. Результат - отчет songimport.Report в JSON.
+// best_effort-загрузка идет частями с контрольной точкой после каждой,
+// atomic - одной транзакцией и при прерывании начинается заново.
+type ImportHandler struct {
+	importer songimport.Importer
+	payloads PayloadReader
+}
+
+// PayloadReader отдает входной файл задачи по частям
+type PayloadReader interface {
+	JobPayload(ctx context.Context, id int64, fn func(chunk []byte) error) error
+}
+
+func NewImportHandler(importer songimport.Importer, payloads PayloadReader) *ImportHandler {
+	return &ImportHandler{importer: importer, payloads: payloads}
+}
+
+func (h *ImportHandler) Run(ctx context.Context, job models.Job, cp *Checkpointer) (storage.JobProgress, error) {
+	var params ImportParams
+	if err := json.Unmarshal(job.Params, &params); err != nil {
+		return storage.JobProgress{}, Permanent(fmt.Errorf("invalid import params: %w", err))
+	}
+
+	records, err := h.decode(ctx, job)
+	if err != nil {
+		return storage.JobProgress{}, err
+	}
+
+	t := i18n.Get(params.Lang)
+
+	if params.Mode == songimport.ModeAtomic {
+		report, err := songimport.Run(ctx, h.importer, records, params.Mode, t)
+		if err != nil {
+			return storage.JobProgress{}, err
+		}
+		return importResult(report, len(records))
+	}
+
+	report := songimport.Report{Mode: params.Mode}
+	if len(job.Checkpoint) > 0 {
+		var state importCheckpoint
+		if err := json.Unmarshal(job.Checkpoint, &state); err != nil {
+			return storage.JobProgress{}, Permanent(fmt.Errorf("invalid import checkpoint: %w", err))
+		}
+		report = state.Report
+	}
+
+	for start := job.Processed; start < len(records); start += importChunk {
+		end := min(start+importChunk, len(records))
+
+		part, err := songimport.Run(ctx, h.importer, records[start:end], params.Mode, t)
+		if err != nil {
+			return storage.JobProgress{}, err
+		}
+		report.Merge(part)
+
+		if end == len(records) {
+			break
+		}
+
+		state, err := json.Marshal(importCheckpoint{Report: report})
+		if err != nil {
+			return storage.JobProgress{}, err
+		}
+		err = cp.Save(ctx, storage.JobProgress{Processed: end, Total: len(records), Checkpoint: state})
+		if err != nil {
+			return storage.JobProgress{}, err
+		}
+	}
+
+	return importResult(report, len(records))
+}
+
+// decode разбирает входной файл, читая его из хранилища потоком.
+// Битый файл - постоянная ошибка, сбой чтения из хранилища - повод повторить попытку.
+func (h *ImportHandler) decode(ctx context.Context, job models.Job) ([]songimport.Record, error) {
+	pr, pw := io.Pipe()
+	read := make(chan error, 1)
+	go func() {
+		err := h.payloads.JobPayload(ctx, job.ID, func(chunk []byte) error {
+			_, err := pw.Write(chunk)
+			return err
+		})
+		pw.CloseWithError(err)
+		read <- err
+	}()
+
+	records, err := songimport.Decode(pr, job.PayloadType)
+	// разбор мог закончиться раньше файла, закрытие отпускает читающую горутину
+	pr.Close()
+	if readErr := <-read; readErr != nil && !errors.Is(readErr, io.ErrClosedPipe) {
+		return nil, readErr
+	}
+	if err != nil {
+		return nil, Permanent(err)
+	}
+	return records, nil
+}
+
+func importResult(report songimport.Report, total int) (storage.JobProgress, error) {
+	result, err := json.Marshal(report)
+	if err != nil {
+		return storage.JobProgress{}, err
+	}
+
+	return storage.JobProgress{
+		Processed:  total,
+		Total:      total,
+		Result:     result,
+		ResultType: songimport.ContentTypeJSON,
+	}, nil
+}
@@ -0,0 +1,100 @@
+package jobs
+
+import (
+	"RestApi_v1/internal/config/internal/lib/songimport"
+	"RestApi_v1/internal/config/internal/models"
+	"RestApi_v1/internal/config/internal/storage"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+// payloads входной файл задачи частями, err возвращается после всех частей
+type payloads struct {
+	chunks []string
+	err    error
+}
+
+func (p payloads) JobPayload(_ context.Context, _ int64, fn func(chunk []byte) error) error {
+	for _, chunk := range p.chunks {
+		if err := fn([]byte(chunk)); err != nil {
+			return err
+		}
+	}
+	return p.err
+}
+
+type importer struct{}
+
+func (importer) ImportSongs(_ context.Context, songs []storage.ImportSong, _ bool) ([]storage.ImportResult, bool, error) {
+	results := make([]storage.ImportResult, 0, len(songs))
+	for i, song := range songs {
+		results = append(results, storage.ImportResult{Row: song.Row, ID: int64(i + 1), Created: true})
+	}
+	return results, true, nil
+}
+
+func TestImportHandler(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		payload     payloads
+		created     int
+		wantErr     bool
+		permanent   bool
+	}{
+		{
+			name:        "payload split across chunks",
+			contentType: songimport.ContentTypeJSON,
+			payload:     payloads{chunks: []string{`[{"song":"a","gr`, `oup":"g"},{"song":"b"}]`}},
+			created:     2,
+		},
+		{
+			name:        "trailing data after the array is ignored",
+			contentType: songimport.ContentTypeJSON,
+			payload:     payloads{chunks: []string{`[{"song":"a"}]`, ` garbage`}},
+			created:     1,
+		},
+		{
+			name:        "broken payload is permanent",
+			contentType: songimport.ContentTypeJSON,
+			payload:     payloads{chunks: []string{`{{`}},
+			wantErr:     true,
+			permanent:   true,
+		},
+		{
+			name:        "storage failure is retried",
+			contentType: songimport.ContentTypeNDJSON,
+			payload:     payloads{chunks: []string{"{\"song\":\"a\"}\n"}, err: errors.New("db is down")},
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params, _ := json.Marshal(ImportParams{Mode: songimport.ModeBestEffort})
+			job := models.Job{ID: 1, Params: params, PayloadType: tt.contentType}
+
+			progress, err := NewImportHandler(importer{}, tt.payload).Run(context.Background(), job, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var permanent permanentError
+			if errors.As(err, &permanent) != tt.permanent {
+				t.Errorf("Run() error = %v, permanent %v", err, tt.permanent)
+			}
+			if err != nil {
+				return
+			}
+
+			var report songimport.Report
+			if err := json.Unmarshal(progress.Result, &report); err != nil {
+				t.Fatalf("result is not a report: %v", err)
+			}
+			if report.Created != tt.created {
+				t.Errorf("created %d, want %d", report.Created, tt.created)
+			}
+		})
+	}
+}
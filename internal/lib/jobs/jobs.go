@@ -0,0 +1,245 @@
+package jobs
+
+import (
+	"RestApi_v1/internal/config/internal/config"
+	"RestApi_v1/internal/config/internal/lib/logger/sl"
+	"RestApi_v1/internal/config/internal/models"
+	"RestApi_v1/internal/config/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Виды задач
+const (
+	KindImport = "import"
+	KindExport = "export"
+)
+
+// finishTimeout на запись итога задачи, когда контекст задачи уже может быть отменен
+const finishTimeout = 3 * time.Second
+
+// ErrInterrupted задача остановлена на контрольной точке, потому что сервис останавливается
+var ErrInterrupted = errors.New("job interrupted")
+
+// Store очередь задач
+type Store interface {
+	ClaimJob(ctx context.Context, lease time.Duration) (models.Job, error)
+	CheckpointJob(ctx context.Context, id int64, attempt int, progress storage.JobProgress, lease time.Duration) error
+	CompleteJob(ctx context.Context, id int64, attempt int, progress storage.JobProgress) error
+	FailJob(ctx context.Context, id int64, attempt int, reason string, retryAt *time.Time) error
+	ReleaseJob(ctx context.Context, id int64, attempt int) error
+}
+
+// Handler выполняет задачи одного вида. Долгая задача сохраняет прогресс через cp.Save
+// и при повторном запуске продолжает с job.Processed и job.Checkpoint.
+// Возвращает итоговый прогресс, Result в нем дописывается к уже сохраненному.
+type Handler interface {
+	Run(ctx context.Context, job models.Job, cp *Checkpointer) (storage.JobProgress, error)
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent помечает ошибку, после которой повторять задачу бессмысленно (например, битые входные данные)
+func Permanent(err error) error {
+	return permanentError{err: err}
+}
+
+// Checkpointer сохраняет прогресс задачи и продлевает аренду
+type Checkpointer struct {
+	store    Store
+	job      models.Job
+	lease    time.Duration
+	stopping <-chan struct{}
+}
+
+// Save сохраняет прогресс. Если сервис останавливается, после сохранения возвращает ErrInterrupted,
+// и задача должна сразу завершиться с этой ошибкой.
+func (c *Checkpointer) Save(ctx context.Context, progress storage.JobProgress) error {
+	if err := c.store.CheckpointJob(ctx, c.job.ID, c.job.Attempts, progress, c.lease); err != nil {
+		return err
+	}
+
+	select {
+	case <-c.stopping:
+		return ErrInterrupted
+	default:
+		return nil
+	}
+}
+
+// Runner пул воркеров, разбирающих очередь задач
+type Runner struct {
+	log      *slog.Logger
+	store    Store
+	cfg      config.Jobs
+	handlers map[string]Handler
+}
+
+func NewRunner(log *slog.Logger, store Store, cfg config.Jobs, handlers map[string]Handler) *Runner {
+	return &Runner{
+		log:      log.With(slog.String("component", "jobs")),
+		store:    store,
+		cfg:      cfg,
+		handlers: handlers,
+	}
+}
+
+func (r *Runner) Name() string {
+	return "jobs"
+}
+
+// Run запускает cfg.Workers воркеров и ждет отмены ctx. После отмены воркеры доводят задачу
+// до ближайшей контрольной точки и возвращают ее в очередь. Если за StopTimeout задача
+// до нее не дошла, текущий шаг отменяется и задача продолжится с последней сохраненной точки.
+func (r *Runner) Run(ctx context.Context) error {
+	// задачи не отменяются вместе с ctx, чтобы успеть сохранить прогресс
+	jobCtx, cancelJobs := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelJobs()
+
+	go func() {
+		<-ctx.Done()
+		select {
+		case <-time.After(r.cfg.StopTimeout):
+			cancelJobs()
+		case <-jobCtx.Done():
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < r.cfg.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.work(ctx, jobCtx)
+		}()
+	}
+	wg.Wait()
+
+	return nil
+}
+
+func (r *Runner) work(ctx context.Context, jobCtx context.Context) {
+	for ctx.Err() == nil {
+		job, err := r.store.ClaimJob(ctx, r.cfg.Lease)
+		if errors.Is(err, storage.ErrJobNotFound) {
+			sleep(ctx, r.cfg.PollInterval)
+			continue
+		}
+		if err != nil {
+			if ctx.Err() == nil {
+				r.log.Error("failed to claim job", sl.Err(err))
+			}
+			sleep(ctx, r.cfg.PollInterval)
+			continue
+		}
+
+		r.execute(ctx.Done(), jobCtx, job)
+	}
+}
+
+func (r *Runner) execute(stopping <-chan struct{}, ctx context.Context, job models.Job) {
+	log := r.log.With(
+		slog.Int64("job_id", job.ID),
+		slog.String("kind", job.Kind),
+		slog.Int("attempt", job.Attempts),
+	)
+
+	handler, ok := r.handlers[job.Kind]
+	if !ok {
+		r.fail(ctx, log, job, Permanent(fmt.Errorf("unknown job kind %q", job.Kind)))
+		return
+	}
+
+	log.Info("job started", slog.Int("processed", job.Processed))
+
+	progress, err := r.run(ctx, handler, job, &Checkpointer{
+		store:    r.store,
+		job:      job,
+		lease:    r.cfg.Lease,
+		stopping: stopping,
+	})
+
+	finishCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), finishTimeout)
+	defer cancel()
+
+	switch {
+	case err == nil:
+		if err := r.store.CompleteJob(finishCtx, job.ID, job.Attempts, progress); err != nil {
+			log.Error("failed to complete job", sl.Err(err))
+			return
+		}
+		log.Info("job succeeded", slog.Int("processed", progress.Processed))
+	case errors.Is(err, storage.ErrJobLost):
+		log.Warn("job lease lost, another worker took it over")
+	case errors.Is(err, ErrInterrupted) || ctx.Err() != nil:
+		if err := r.store.ReleaseJob(finishCtx, job.ID, job.Attempts); err != nil {
+			log.Error("failed to return interrupted job to queue", sl.Err(err))
+			return
+		}
+		log.Info("job interrupted, progress saved")
+	default:
+		r.fail(finishCtx, log, job, err)
+	}
+}
+
+// run вызывает обработчик, паника в нем считается ошибкой попытки
+func (r *Runner) run(ctx context.Context, handler Handler, job models.Job, cp *Checkpointer) (progress storage.JobProgress, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("job panicked: %v", p)
+		}
+	}()
+
+	return handler.Run(ctx, job, cp)
+}
+
+// fail возвращает задачу в очередь с задержкой или завершает ее, если попытки кончились
+func (r *Runner) fail(ctx context.Context, log *slog.Logger, job models.Job, jobErr error) {
+	var (
+		retryAt   *time.Time
+		permanent permanentError
+	)
+	if !errors.As(jobErr, &permanent) && job.Attempts < job.MaxAttempts {
+		t := time.Now().Add(r.backoff(job.Attempts))
+		retryAt = &t
+	}
+
+	if err := r.store.FailJob(ctx, job.ID, job.Attempts, jobErr.Error(), retryAt); err != nil {
+		log.Error("failed to record job failure", sl.Err(err))
+		return
+	}
+
+	if retryAt != nil {
+		log.Warn("job attempt failed, will retry", sl.Err(jobErr), slog.Time("retry_at", *retryAt))
+		return
+	}
+	log.Error("job failed", sl.Err(jobErr))
+}
+
+// backoff задержка перед следующей попыткой: RetryBackoff, удваиваясь до MaxBackoff
+func (r *Runner) backoff(attempt int) time.Duration {
+	d := r.cfg.RetryBackoff
+	for i := 1; i < attempt && d < r.cfg.MaxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, r.cfg.MaxBackoff)
+}
+
+func sleep(ctx context.Context, d time.Duration) {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+	case <-t.C:
+	}
+}
@@ -0,0 +1,293 @@
+package jobs
+
+import (
+	"RestApi_v1/internal/config/internal/config"
+	"RestApi_v1/internal/config/internal/models"
+	"RestApi_v1/internal/config/internal/storage"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeStore очередь из заранее заданных задач, запоминает, чем закончилась каждая
+type fakeStore struct {
+	mu       sync.Mutex
+	queue    []models.Job
+	finished chan string
+
+	checkpoints int
+	retryAt     *time.Time
+	reason      string
+}
+
+func newFakeStore(jobs ...models.Job) *fakeStore {
+	return &fakeStore{queue: jobs, finished: make(chan string, 16)}
+}
+
+func (s *fakeStore) ClaimJob(_ context.Context, _ time.Duration) (models.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.queue) == 0 {
+		return models.Job{}, storage.ErrJobNotFound
+	}
+	job := s.queue[0]
+	s.queue = s.queue[1:]
+	job.Attempts++
+	return job, nil
+}
+
+func (s *fakeStore) CheckpointJob(_ context.Context, _ int64, _ int, _ storage.JobProgress, _ time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.checkpoints++
+	return nil
+}
+
+func (s *fakeStore) CompleteJob(_ context.Context, _ int64, _ int, _ storage.JobProgress) error {
+	s.finished <- "complete"
+	return nil
+}
+
+func (s *fakeStore) FailJob(_ context.Context, _ int64, _ int, reason string, retryAt *time.Time) error {
+	s.mu.Lock()
+	s.reason, s.retryAt = reason, retryAt
+	s.mu.Unlock()
+
+	if retryAt != nil {
+		s.finished <- "retry"
+	} else {
+		s.finished <- "fail"
+	}
+	return nil
+}
+
+func (s *fakeStore) ReleaseJob(_ context.Context, _ int64, _ int) error {
+	s.finished <- "release"
+	return nil
+}
+
+type handlerFunc func(ctx context.Context, job models.Job, cp *Checkpointer) (storage.JobProgress, error)
+
+func (f handlerFunc) Run(ctx context.Context, job models.Job, cp *Checkpointer) (storage.JobProgress, error) {
+	return f(ctx, job, cp)
+}
+
+var testConfig = config.Jobs{
+	Workers:      1,
+	PollInterval: 10 * time.Millisecond,
+	Lease:        time.Minute,
+	RetryBackoff: time.Second,
+	MaxBackoff:   10 * time.Second,
+	StopTimeout:  time.Second,
+}
+
+func newRunner(store Store, handler Handler) *Runner {
+	return NewRunner(slog.New(slog.NewTextHandler(io.Discard, nil)), store, testConfig, map[string]Handler{"test": handler})
+}
+
+func TestExecute(t *testing.T) {
+	failing := handlerFunc(func(context.Context, models.Job, *Checkpointer) (storage.JobProgress, error) {
+		return storage.JobProgress{}, errors.New("db is down")
+	})
+
+	tests := []struct {
+		name     string
+		kind     string
+		attempts int // номер текущей попытки
+		handler  handlerFunc
+		stopping bool
+		want     string
+		retry    bool
+	}{
+		{
+			name: "success",
+			handler: func(context.Context, models.Job, *Checkpointer) (storage.JobProgress, error) {
+				return storage.JobProgress{Processed: 1, Total: 1}, nil
+			},
+			want: "complete",
+		},
+		{
+			name:     "error is retried",
+			attempts: 1,
+			handler:  failing,
+			want:     "retry",
+		},
+		{
+			name:     "last attempt fails the job",
+			attempts: 3,
+			handler:  failing,
+			want:     "fail",
+		},
+		{
+			name: "permanent error is not retried",
+			handler: func(context.Context, models.Job, *Checkpointer) (storage.JobProgress, error) {
+				return storage.JobProgress{}, Permanent(errors.New("broken payload"))
+			},
+			want: "fail",
+		},
+		{
+			name: "panic is a failed attempt",
+			handler: func(context.Context, models.Job, *Checkpointer) (storage.JobProgress, error) {
+				panic("boom")
+			},
+			want: "retry",
+		},
+		{
+			name: "unknown kind",
+			kind: "unknown",
+			want: "fail",
+		},
+		{
+			name:     "stop at checkpoint releases the job",
+			stopping: true,
+			handler: func(ctx context.Context, _ models.Job, cp *Checkpointer) (storage.JobProgress, error) {
+				return storage.JobProgress{}, cp.Save(ctx, storage.JobProgress{Processed: 1, Total: 2})
+			},
+			want: "release",
+		},
+		{
+			name: "lost lease is left to the new owner",
+			handler: func(context.Context, models.Job, *Checkpointer) (storage.JobProgress, error) {
+				return storage.JobProgress{}, storage.ErrJobLost
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newFakeStore()
+			r := newRunner(store, tt.handler)
+
+			kind := tt.kind
+			if kind == "" {
+				kind = "test"
+			}
+			job := models.Job{ID: 1, Kind: kind, Attempts: max(tt.attempts, 1), MaxAttempts: 3}
+
+			stopping := make(chan struct{})
+			if tt.stopping {
+				close(stopping)
+			}
+			r.execute(stopping, context.Background(), job)
+
+			got := ""
+			select {
+			case got = <-store.finished:
+			default:
+			}
+			if got != tt.want {
+				t.Errorf("job finished with %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecuteRetryAt(t *testing.T) {
+	store := newFakeStore()
+	r := newRunner(store, handlerFunc(func(context.Context, models.Job, *Checkpointer) (storage.JobProgress, error) {
+		return storage.JobProgress{}, errors.New("db is down")
+	}))
+
+	before := time.Now()
+	r.execute(make(chan struct{}), context.Background(), models.Job{ID: 1, Kind: "test", Attempts: 2, MaxAttempts: 5})
+
+	if store.retryAt == nil {
+		t.Fatal("job is not retried")
+	}
+	if d := store.retryAt.Sub(before); d < 2*time.Second || d > 3*time.Second {
+		t.Errorf("retry in %s, want about %s", d, 2*time.Second)
+	}
+	if store.reason != "db is down" {
+		t.Errorf("reason = %q, want %q", store.reason, "db is down")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	r := newRunner(newFakeStore(), nil)
+
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{attempt: 1, want: time.Second},
+		{attempt: 2, want: 2 * time.Second},
+		{attempt: 3, want: 4 * time.Second},
+		{attempt: 4, want: 8 * time.Second},
+		{attempt: 5, want: 10 * time.Second},
+		{attempt: 50, want: 10 * time.Second},
+	}
+
+	for _, tt := range tests {
+		if got := r.backoff(tt.attempt); got != tt.want {
+			t.Errorf("backoff(%d) = %s, want %s", tt.attempt, got, tt.want)
+		}
+	}
+}
+
+func TestRunReleasesJobOnStop(t *testing.T) {
+	started := make(chan struct{})
+	store := newFakeStore(models.Job{ID: 1, Kind: "test", MaxAttempts: 3})
+	r := newRunner(store, handlerFunc(func(ctx context.Context, _ models.Job, cp *Checkpointer) (storage.JobProgress, error) {
+		close(started)
+		for {
+			if err := cp.Save(ctx, storage.JobProgress{}); err != nil {
+				return storage.JobProgress{}, err
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- r.Run(ctx) }()
+
+	<-started
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run() did not stop")
+	}
+
+	if got := <-store.finished; got != "release" {
+		t.Errorf("job finished with %q, want %q", got, "release")
+	}
+}
+
+func TestRunCancelsStuckJob(t *testing.T) {
+	started := make(chan struct{})
+	store := newFakeStore(models.Job{ID: 1, Kind: "test", MaxAttempts: 3})
+	r := newRunner(store, handlerFunc(func(ctx context.Context, _ models.Job, _ *Checkpointer) (storage.JobProgress, error) {
+		close(started)
+		// шаг без контрольных точек, прерывается только отменой контекста
+		<-ctx.Done()
+		return storage.JobProgress{}, ctx.Err()
+	}))
+	r.cfg.StopTimeout = 20 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- r.Run(ctx) }()
+
+	<-started
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run() did not stop")
+	}
+
+	if got := <-store.finished; got != "release" {
+		t.Errorf("job finished with %q, want %q", got, "release")
+	}
+}
@@ -0,0 +1,67 @@
+package jobs
+
+import (
+	"RestApi_v1/internal/config/internal/lib/auth"
+	"RestApi_v1/internal/config/internal/models"
+	"fmt"
+	"time"
+)
+
+// Status состояние задачи в ответах API
+type Status struct {
+	ID          int64   `json:"id"`
+	Kind        string  `json:"kind"`
+	Status      string  `json:"status"`
+	Attempts    int     `json:"attempts"`
+	MaxAttempts int     `json:"max_attempts"`
+	Processed   int     `json:"processed"`
+	Total       int     `json:"total"`
+	Progress    float64 `json:"progress"` // от 0 до 1
+	// Error ошибка последней попытки
+	Error      string     `json:"error,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	// NextRunAt когда будет следующая попытка, если задача ждет повтора
+	NextRunAt *time.Time `json:"next_run_at,omitempty"`
+	ResultURL string     `json:"result_url,omitempty"`
+}
+
+func StatusOf(job models.Job) Status {
+	s := Status{
+		ID:          job.ID,
+		Kind:        job.Kind,
+		Status:      job.Status,
+		Attempts:    job.Attempts,
+		MaxAttempts: job.MaxAttempts,
+		Processed:   job.Processed,
+		Total:       job.Total,
+		Error:       job.Error,
+		CreatedAt:   job.CreatedAt,
+	}
+
+	switch {
+	case job.Status == models.JobSucceeded:
+		s.Progress = 1
+		s.ResultURL = fmt.Sprintf("/jobs/%d/result", job.ID)
+	case job.Total > 0:
+		s.Progress = float64(job.Processed) / float64(job.Total)
+	}
+
+	if job.StartedAt.Valid {
+		s.StartedAt = &job.StartedAt.Time
+	}
+	if job.FinishedAt.Valid {
+		s.FinishedAt = &job.FinishedAt.Time
+	}
+	if job.Status == models.JobQueued && job.Attempts > 0 {
+		s.NextRunAt = &job.RunAt
+	}
+
+	return s
+}
+
+// Visible задачу видит тот, кто ее создал, и администратор
+func Visible(job models.Job, principal auth.Principal) bool {
+	return principal.Role.Allows(auth.RoleAdmin) || job.CreatedBy == principal.String()
+}
@@ -0,0 +1,37 @@
+package slogctx
+
+import (
+	"RestApi_v1/internal/config/internal/lib/tracing"
+	"context"
+	"log/slog"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// Handler дописывает к каждой записи trace_id и request_id из контекста,
+// поэтому код логирует через *Context и не передает их сам.
+type Handler struct {
+	slog.Handler
+}
+
+func NewHandler(h slog.Handler) *Handler {
+	return &Handler{Handler: h}
+}
+
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	if id := tracing.TraceID(ctx); id != "" {
+		r.AddAttrs(slog.String("trace_id", id))
+	}
+	if id := middleware.GetReqID(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &Handler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *Handler) WithGroup(name string) slog.Handler {
+	return &Handler{Handler: h.Handler.WithGroup(name)}
+}
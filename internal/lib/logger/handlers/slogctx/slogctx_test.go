@@ -0,0 +1,66 @@
+package slogctx
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestHandler(t *testing.T) {
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	traced := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceID,
+		SpanID:  spanID,
+	}))
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		want    []string
+		without []string
+	}{
+		{
+			name:    "empty context adds nothing",
+			ctx:     context.Background(),
+			without: []string{"trace_id", "request_id"},
+		},
+		{
+			name:    "request id",
+			ctx:     context.WithValue(context.Background(), middleware.RequestIDKey, "host/req-1"),
+			want:    []string{"request_id=host/req-1"},
+			without: []string{"trace_id"},
+		},
+		{
+			name: "trace and request id",
+			ctx:  context.WithValue(traced, middleware.RequestIDKey, "host/req-1"),
+			want: []string{"trace_id=4bf92f3577b34da6a3ce929d0e0e4736", "request_id=host/req-1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			log := slog.New(NewHandler(slog.NewTextHandler(&buf, nil))).With(slog.String("op", "test"))
+
+			log.InfoContext(tt.ctx, "message")
+
+			line := buf.String()
+			for _, want := range append(tt.want, "op=test") {
+				if !strings.Contains(line, want) {
+					t.Errorf("log line %q does not contain %q", line, want)
+				}
+			}
+			for _, key := range tt.without {
+				if strings.Contains(line, key+"=") {
+					t.Errorf("log line %q contains %q", line, key)
+				}
+			}
+		})
+	}
+}
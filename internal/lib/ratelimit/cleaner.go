@@ -0,0 +1,69 @@
+package ratelimit
+
+import (
+	"RestApi_v1/internal/config/internal/lib/logger/sl"
+	"context"
+	"log/slog"
+	"time"
+)
+
+type Purger interface {
+	PurgeRateLimits(ctx context.Context, idle time.Duration) (int64, error)
+}
+
+// Cleaner фоновый воркер, удаляющий из общего хранилища бакеты, которые не трогали дольше
+// самого длинного окна: такой бакет уже полон и ничем не отличается от нового.
+// Окно берется на каждый проход, чтобы учитывать перезагрузку конфига.
+type Cleaner struct {
+	log      *slog.Logger
+	purger   Purger
+	interval time.Duration
+	window   func() time.Duration
+}
+
+func NewCleaner(log *slog.Logger, purger Purger, interval time.Duration, window func() time.Duration) *Cleaner {
+	return &Cleaner{
+		log:      log.With(slog.String("component", "ratelimit/cleaner")),
+		purger:   purger,
+		interval: interval,
+		window:   window,
+	}
+}
+
+func (c *Cleaner) Name() string {
+	return "ratelimit-cleaner"
+}
+
+func (c *Cleaner) Run(ctx context.Context) error {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		c.purge(ctx)
+	}
+}
+
+func (c *Cleaner) purge(ctx context.Context) {
+	// без правил не знаем, сколько живет бакет, поэтому ничего не удаляем
+	window := c.window()
+	if window <= 0 {
+		return
+	}
+
+	deleted, err := c.purger.PurgeRateLimits(ctx, window)
+	if err != nil {
+		if ctx.Err() == nil {
+			c.log.Error("failed to purge rate limit buckets", sl.Err(err))
+		}
+		return
+	}
+	if deleted > 0 {
+		c.log.Debug("idle rate limit buckets purged", slog.Int64("deleted", deleted))
+	}
+}
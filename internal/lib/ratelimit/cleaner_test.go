@@ -0,0 +1,67 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+// purger запоминает, с каким окном его вызвали
+type purger struct {
+	calls int
+	idle  time.Duration
+	err   error
+}
+
+func (p *purger) PurgeRateLimits(_ context.Context, idle time.Duration) (int64, error) {
+	p.calls++
+	p.idle = idle
+	return 1, p.err
+}
+
+func TestCleanerPurge(t *testing.T) {
+	tests := []struct {
+		name   string
+		window time.Duration
+		err    error
+		calls  int
+	}{
+		{name: "purges with the longest window", window: time.Hour, calls: 1},
+		{name: "no rules purges nothing", window: 0, calls: 0},
+		{name: "store error is not fatal", window: time.Minute, err: errors.New("db is down"), calls: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &purger{err: tt.err}
+			c := NewCleaner(slog.New(slog.NewTextHandler(io.Discard, nil)), p, time.Minute, func() time.Duration { return tt.window })
+
+			c.purge(context.Background())
+
+			if p.calls != tt.calls {
+				t.Fatalf("purged %d times, want %d", p.calls, tt.calls)
+			}
+			if tt.calls > 0 && p.idle != tt.window {
+				t.Errorf("purged buckets idle for %s, want %s", p.idle, tt.window)
+			}
+		})
+	}
+}
+
+func TestCleanerRunStops(t *testing.T) {
+	p := &purger{}
+	c := NewCleaner(slog.New(slog.NewTextHandler(io.Discard, nil)), p, time.Millisecond, func() time.Duration { return time.Minute })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if err := c.Run(ctx); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if p.calls == 0 {
+		t.Error("Run() never purged")
+	}
+}
@@ -0,0 +1,167 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Rule бакет на Requests запросов, полностью восстанавливающийся за Window
+type Rule struct {
+	Requests int
+	Window   time.Duration
+}
+
+// rate скорость пополнения бакета, токенов в секунду
+func (r Rule) rate() float64 {
+	return float64(r.Requests) / r.Window.Seconds()
+}
+
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset время до полного восстановления бакета
+	Reset time.Duration
+	// RetryAfter время до появления следующего токена, если запрос отклонен
+	RetryAfter time.Duration
+}
+
+// Store хранилище бакетов. В одном процессе хватает MemoryStore,
+// для нескольких реплик нужна общая реализация, например Postgres.
+type Store interface {
+	Take(ctx context.Context, key string, rule Rule) (Result, error)
+}
+
+// Wait ждет, пока в бакете key появится токен, и забирает его.
+// Нулевое правило ничего не ограничивает.
+func Wait(ctx context.Context, store Store, key string, rule Rule) error {
+	if rule.Requests <= 0 || rule.Window <= 0 {
+		return nil
+	}
+
+	for {
+		res, err := store.Take(ctx, key, rule)
+		if err != nil {
+			return err
+		}
+		if res.Allowed {
+			return nil
+		}
+
+		t := time.NewTimer(res.RetryAfter)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// result считает заголовки по числу токенов после попытки взять токен
+func result(allowed bool, tokens float64, rule Rule) Result {
+	res := Result{
+		Allowed:   allowed,
+		Limit:     rule.Requests,
+		Remaining: int(math.Max(0, math.Floor(tokens))),
+		Reset:     seconds((float64(rule.Requests) - tokens) / rule.rate()),
+	}
+	if !allowed {
+		res.RetryAfter = seconds((1 - tokens) / rule.rate())
+	}
+	return res
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(math.Max(0, s) * float64(time.Second))
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	// fullAt когда бакет восстановится полностью и его можно забыть
+	fullAt time.Time
+}
+
+// MemoryStore token bucket в памяти процесса
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	now     func() time.Time
+
+	sweepEvery time.Duration
+	lastSweep  time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets:    make(map[string]*bucket),
+		now:        time.Now,
+		sweepEvery: time.Minute,
+	}
+}
+
+func (s *MemoryStore) Take(_ context.Context, key string, rule Rule) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(rule.Requests), updated: now}
+		s.buckets[key] = b
+	}
+
+	b.tokens = math.Min(float64(rule.Requests), b.tokens+now.Sub(b.updated).Seconds()*rule.rate())
+	b.updated = now
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+
+	res := result(allowed, b.tokens, rule)
+	b.fullAt = now.Add(res.Reset)
+
+	return res, nil
+}
+
+// sweep удаляет уже восстановившиеся бакеты, чтобы карта не росла бесконечно
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < s.sweepEvery {
+		return
+	}
+	s.lastSweep = now
+
+	for key, b := range s.buckets {
+		if now.After(b.fullAt) {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+// TokenTaker общее для всех реплик хранилище бакетов
+type TokenTaker interface {
+	TakeToken(ctx context.Context, key string, capacity float64, ratePerSecond float64) (allowed bool, tokens float64, err error)
+}
+
+// SharedStore бакеты во внешнем хранилище, чтобы реплики считали лимит вместе
+type SharedStore struct {
+	backend TokenTaker
+}
+
+func NewSharedStore(backend TokenTaker) *SharedStore {
+	return &SharedStore{backend: backend}
+}
+
+func (s *SharedStore) Take(ctx context.Context, key string, rule Rule) (Result, error) {
+	allowed, tokens, err := s.backend.TakeToken(ctx, key, float64(rule.Requests), rule.rate())
+	if err != nil {
+		return Result{}, err
+	}
+	return result(allowed, tokens, rule), nil
+}
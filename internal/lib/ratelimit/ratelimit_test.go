@@ -0,0 +1,173 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// clock ручные часы для MemoryStore
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time { return c.now }
+
+func newTestStore() (*MemoryStore, *clock) {
+	c := &clock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	s := NewMemoryStore()
+	s.now = c.Now
+	return s, c
+}
+
+func TestMemoryStoreTake(t *testing.T) {
+	rule := Rule{Requests: 2, Window: 10 * time.Second}
+
+	type step struct {
+		after time.Duration // сколько прошло с предыдущего запроса
+		want  Result
+	}
+
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "burst up to capacity",
+			steps: []step{
+				{want: Result{Allowed: true, Limit: 2, Remaining: 1, Reset: 5 * time.Second}},
+				{want: Result{Allowed: true, Limit: 2, Remaining: 0, Reset: 10 * time.Second}},
+				{want: Result{Allowed: false, Limit: 2, Remaining: 0, Reset: 10 * time.Second, RetryAfter: 5 * time.Second}},
+			},
+		},
+		{
+			name: "refill one token",
+			steps: []step{
+				{},
+				{},
+				{after: 5 * time.Second, want: Result{Allowed: true, Limit: 2, Remaining: 0, Reset: 10 * time.Second}},
+			},
+		},
+		{
+			name: "partial refill is not enough",
+			steps: []step{
+				{},
+				{},
+				{after: 3 * time.Second, want: Result{Allowed: false, Limit: 2, Remaining: 0, Reset: 7 * time.Second, RetryAfter: 2 * time.Second}},
+			},
+		},
+		{
+			name: "refill is capped by capacity",
+			steps: []step{
+				{},
+				{after: time.Hour, want: Result{Allowed: true, Limit: 2, Remaining: 1, Reset: 5 * time.Second}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, c := newTestStore()
+			for i, st := range tt.steps {
+				c.now = c.now.Add(st.after)
+				got, err := s.Take(context.Background(), "client", rule)
+				if err != nil {
+					t.Fatalf("step %d: Take() error = %v", i, err)
+				}
+				if st.want == (Result{}) {
+					continue
+				}
+				if !sameResult(got, st.want) {
+					t.Errorf("step %d: Take() = %+v, want %+v", i, got, st.want)
+				}
+			}
+		})
+	}
+}
+
+// sameResult сравнивает результаты, длительности - с точностью до миллисекунды из-за float
+func sameResult(got Result, want Result) bool {
+	near := func(a, b time.Duration) bool {
+		return (a - b).Abs() < time.Millisecond
+	}
+	return got.Allowed == want.Allowed && got.Limit == want.Limit && got.Remaining == want.Remaining &&
+		near(got.Reset, want.Reset) && near(got.RetryAfter, want.RetryAfter)
+}
+
+func TestMemoryStoreKeysAreIndependent(t *testing.T) {
+	s, _ := newTestStore()
+	rule := Rule{Requests: 1, Window: time.Minute}
+
+	if res, _ := s.Take(context.Background(), "a", rule); !res.Allowed {
+		t.Fatal("first request of a is rejected")
+	}
+	if res, _ := s.Take(context.Background(), "a", rule); res.Allowed {
+		t.Fatal("second request of a is allowed")
+	}
+	if res, _ := s.Take(context.Background(), "b", rule); !res.Allowed {
+		t.Fatal("request of b is limited by a")
+	}
+}
+
+func TestMemoryStoreSweep(t *testing.T) {
+	s, c := newTestStore()
+	rule := Rule{Requests: 1, Window: time.Second}
+
+	_, _ = s.Take(context.Background(), "a", rule)
+	c.now = c.now.Add(2 * time.Minute)
+	_, _ = s.Take(context.Background(), "b", rule)
+
+	if _, ok := s.buckets["a"]; ok {
+		t.Error("refilled bucket is not swept")
+	}
+	if _, ok := s.buckets["b"]; !ok {
+		t.Error("active bucket is swept")
+	}
+}
+
+// backend TokenTaker, запоминающий аргументы
+type backend struct {
+	capacity, rate float64
+	tokens         float64
+	allowed        bool
+}
+
+func (b *backend) TakeToken(_ context.Context, _ string, capacity float64, rate float64) (bool, float64, error) {
+	b.capacity, b.rate = capacity, rate
+	return b.allowed, b.tokens, nil
+}
+
+func TestSharedStoreTake(t *testing.T) {
+	b := &backend{allowed: false, tokens: 0.5}
+	res, err := NewSharedStore(b).Take(context.Background(), "client", Rule{Requests: 60, Window: time.Minute})
+	if err != nil {
+		t.Fatalf("Take() error = %v", err)
+	}
+
+	if b.capacity != 60 || b.rate != 1 {
+		t.Errorf("backend got capacity %v, rate %v, want 60 and 1", b.capacity, b.rate)
+	}
+	want := Result{Allowed: false, Limit: 60, Remaining: 0, Reset: 59500 * time.Millisecond, RetryAfter: 500 * time.Millisecond}
+	if !sameResult(res, want) {
+		t.Errorf("Take() = %+v, want %+v", res, want)
+	}
+}
+
+func TestWaitZeroRule(t *testing.T) {
+	// нулевое правило не должно обращаться к хранилищу вовсе
+	if err := Wait(context.Background(), nil, "key", Rule{}); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+}
+
+func TestWaitCanceled(t *testing.T) {
+	s := NewMemoryStore()
+	rule := Rule{Requests: 1, Window: time.Hour}
+	_, _ = s.Take(context.Background(), "key", rule)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := Wait(ctx, s, "key", rule); err != context.Canceled {
+		t.Fatalf("Wait() error = %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,65 @@
+package songcache
+
+import (
+	"RestApi_v1/internal/config/internal/lib/logger/sl"
+	"RestApi_v1/internal/config/internal/storage"
+	"context"
+	"log/slog"
+	"time"
+)
+
+const (
+	minReconnectDelay = time.Second
+	maxReconnectDelay = 30 * time.Second
+)
+
+type Listener interface {
+	ListenSongChanges(ctx context.Context, ready func(), fn func(storage.SongChange)) error
+}
+
+// Invalidator фоновый воркер, сбрасывающий кеш по уведомлениям Postgres об изменении песен.
+// Так до реплики доходят изменения, сделанные другими репликами, импортом и дозаполнением.
+type Invalidator struct {
+	log      *slog.Logger
+	cache    *Cache
+	listener Listener
+}
+
+func NewInvalidator(log *slog.Logger, cache *Cache, listener Listener) *Invalidator {
+	return &Invalidator{
+		log:      log.With(slog.String("component", "songcache/invalidator")),
+		cache:    cache,
+		listener: listener,
+	}
+}
+
+func (i *Invalidator) Name() string {
+	return "song-cache-invalidator"
+}
+
+func (i *Invalidator) Run(ctx context.Context) error {
+	delay := minReconnectDelay
+
+	for {
+		err := i.listener.ListenSongChanges(ctx, func() {
+			// пока подписки не было, уведомления терялись
+			i.cache.Purge()
+			delay = minReconnectDelay
+			i.log.Debug("listening for song changes")
+		}, func(change storage.SongChange) {
+			i.cache.Invalidate(ctx, int(change.ID))
+		})
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		i.log.Error("song changes subscription lost", slog.Duration("retry_in", delay), sl.Err(err))
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxReconnectDelay)
+	}
+}
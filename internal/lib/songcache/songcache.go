@@ -0,0 +1,259 @@
+package songcache
+
+import (
+	"RestApi_v1/internal/config/internal/config"
+	del "RestApi_v1/internal/config/internal/http-server/handlers/song/delete"
+	"RestApi_v1/internal/config/internal/http-server/handlers/song/get"
+	"RestApi_v1/internal/config/internal/http-server/handlers/song/save"
+	updateSong "RestApi_v1/internal/config/internal/http-server/handlers/song/updateSong"
+	"RestApi_v1/internal/config/internal/lib/cache"
+	"RestApi_v1/internal/config/internal/lib/logger/sl"
+	"RestApi_v1/internal/config/internal/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// generationStripes на сколько счетчиков поколений делятся id песен.
+// Совпадение счетчика у разных песен только изредка лишает кеша лишнюю песню.
+const generationStripes = 256
+
+// Store хранилище песен, которое оборачивает кеш
+type Store interface {
+	get.SongGetter
+	save.SongSaver
+	updateSong.SongUpdater
+	del.SongDelete
+}
+
+// Stats счетчики кеша с момента запуска
+type Stats struct {
+	Hits          uint64 `json:"hits"`        // из памяти процесса
+	SharedHits    uint64 `json:"shared_hits"` // из общего кеша
+	Misses        uint64 `json:"misses"`      // пошли в базу
+	Invalidations uint64 `json:"invalidations"`
+	Size          int    `json:"size"`
+}
+
+// Cache кеширует песни по id перед Store. Сначала смотрим в LRU процесса,
+// затем в общий backend (если задан), затем в Store.
+// Запись через Cache сбрасывает песню сразу, изменения с других реплик приходят через Invalidator.
+type Cache struct {
+	Store
+
+	log       *slog.Logger
+	local     *cache.LRU[int, models.Song]
+	shared    cache.Backend
+	sharedTTL time.Duration
+
+	// generations меняются при каждом сбросе песни, epoch - при сбросе всего кеша.
+	// lookup не оставляет в кеше песню, прочитанную до сброса, который случился во время чтения.
+	generations [generationStripes]atomic.Uint64
+	epoch       atomic.Uint64
+
+	hits          atomic.Uint64
+	sharedHits    atomic.Uint64
+	misses        atomic.Uint64
+	invalidations atomic.Uint64
+}
+
+// New оборачивает store кешем. shared может быть nil.
+func New(log *slog.Logger, store Store, shared cache.Backend, cfg config.Cache) *Cache {
+	return &Cache{
+		Store:     store,
+		log:       log.With(slog.String("component", "songcache")),
+		local:     cache.NewLRU[int, models.Song](cfg.Size, cfg.TTL),
+		shared:    shared,
+		sharedTTL: cfg.Redis.TTL,
+	}
+}
+
+// GetSongWithPagination отдает песню из кеша. По id песня одна, поэтому страница
+// вырезается в памяти из закешированной записи.
+func (c *Cache) GetSongWithPagination(ctx context.Context, id int, page int, pageSize int) ([]models.Song, error) {
+	// такие запросы база отклоняет сама, кешировать нечего
+	if page < 1 || pageSize < 0 {
+		return c.Store.GetSongWithPagination(ctx, id, page, pageSize)
+	}
+
+	song, ok, err := c.lookup(ctx, id)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	if page > 1 || pageSize == 0 {
+		return nil, nil
+	}
+	return []models.Song{song}, nil
+}
+
+func (c *Cache) lookup(ctx context.Context, id int) (models.Song, bool, error) {
+	if song, ok := c.local.Get(id); ok {
+		c.hits.Add(1)
+		return song, true, nil
+	}
+
+	gen := c.generation(id)
+
+	if song, ok := c.getShared(ctx, id); ok {
+		c.sharedHits.Add(1)
+		c.fill(ctx, id, gen, song, false)
+		return song, true, nil
+	}
+
+	c.misses.Add(1)
+
+	songs, err := c.Store.GetSongWithPagination(ctx, id, 1, 1)
+	if err != nil {
+		return models.Song{}, false, err
+	}
+	// отсутствие песни не кешируем: новую песню сразу должно быть видно
+	if len(songs) == 0 {
+		return models.Song{}, false, nil
+	}
+
+	song := songs[0]
+	c.fill(ctx, id, gen, song, true)
+
+	return song, true, nil
+}
+
+// fill кладет прочитанную песню в кеш, если с начала чтения ее не сбрасывали.
+// Поколение проверяется после записи: сброс между проверкой и записью иначе потерялся бы,
+// а так либо мы увидим новое поколение и удалим запись, либо сброс удалит ее сам.
+func (c *Cache) fill(ctx context.Context, id int, gen uint64, song models.Song, shared bool) {
+	if c.generation(id) != gen {
+		return
+	}
+
+	c.local.Set(id, song)
+	if shared {
+		c.setShared(ctx, id, song)
+	}
+
+	if c.generation(id) != gen {
+		c.local.Delete(id)
+		if shared {
+			c.deleteShared(ctx, id)
+		}
+	}
+}
+
+// generation текущее поколение песни id
+func (c *Cache) generation(id int) uint64 {
+	return c.epoch.Load() + c.generations[uint(id)%generationStripes].Load()
+}
+
+func (c *Cache) SaveSong(ctx context.Context, song string, group string, text string, date string, link string) (int64, error) {
+	id, err := c.Store.SaveSong(ctx, song, group, text, date, link)
+	if err == nil {
+		c.Invalidate(ctx, int(id))
+	}
+	return id, err
+}
+
+func (c *Cache) UpdateSong(ctx context.Context, id int, song string, group string, text string, date string, link string) (string, error) {
+	res, err := c.Store.UpdateSong(ctx, id, song, group, text, date, link)
+	// при ошибке запись могла частично пройти, сбрасываем в любом случае
+	c.Invalidate(ctx, id)
+	return res, err
+}
+
+// DeleteSong удаляет по названию, поэтому сбрасываем все закешированные песни с ним.
+// Песни, которых нет в памяти процесса, из общего кеша уберет Invalidator по уведомлению.
+func (c *Cache) DeleteSong(ctx context.Context, songName string) (string, error) {
+	res, err := c.Store.DeleteSong(ctx, songName)
+
+	// id удаленной песни может быть неизвестен, поэтому отменяем все чтения, идущие сейчас
+	c.epoch.Add(1)
+	ids := c.local.DeleteFunc(func(_ int, song models.Song) bool {
+		return song.Song == songName
+	})
+	c.invalidations.Add(uint64(len(ids)))
+	c.deleteShared(ctx, ids...)
+
+	return res, err
+}
+
+// Invalidate убирает песню из памяти процесса и из общего кеша
+func (c *Cache) Invalidate(ctx context.Context, id int) {
+	c.invalidations.Add(1)
+	c.generations[uint(id)%generationStripes].Add(1)
+	c.local.Delete(id)
+	c.deleteShared(ctx, id)
+}
+
+// Purge очищает кеш процесса. Общий кеш не трогаем: его записи живут не дольше Redis.TTL.
+func (c *Cache) Purge() {
+	c.epoch.Add(1)
+	c.local.Purge()
+}
+
+func (c *Cache) Stats() Stats {
+	return Stats{
+		Hits:          c.hits.Load(),
+		SharedHits:    c.sharedHits.Load(),
+		Misses:        c.misses.Load(),
+		Invalidations: c.invalidations.Load(),
+		Size:          c.local.Len(),
+	}
+}
+
+// ошибки общего кеша не ломают запрос: в худшем случае сходим в базу
+
+func (c *Cache) getShared(ctx context.Context, id int) (models.Song, bool) {
+	var song models.Song
+	if c.shared == nil {
+		return song, false
+	}
+
+	data, err := c.shared.Get(ctx, sharedKey(id))
+	if err != nil {
+		if !errors.Is(err, cache.ErrMiss) {
+			c.log.Warn("failed to read shared cache", slog.Int("id", id), sl.Err(err))
+		}
+		return song, false
+	}
+
+	if err := json.Unmarshal(data, &song); err != nil {
+		c.log.Warn("broken shared cache entry", slog.Int("id", id), sl.Err(err))
+		return song, false
+	}
+	return song, true
+}
+
+func (c *Cache) setShared(ctx context.Context, id int, song models.Song) {
+	if c.shared == nil {
+		return
+	}
+
+	data, err := json.Marshal(song)
+	if err != nil {
+		return
+	}
+	if err := c.shared.Set(ctx, sharedKey(id), data, c.sharedTTL); err != nil {
+		c.log.Warn("failed to write shared cache", slog.Int("id", id), sl.Err(err))
+	}
+}
+
+func (c *Cache) deleteShared(ctx context.Context, ids ...int) {
+	if c.shared == nil || len(ids) == 0 {
+		return
+	}
+
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = sharedKey(id)
+	}
+	if err := c.shared.Delete(ctx, keys...); err != nil {
+		c.log.Warn("failed to invalidate shared cache", slog.Any("ids", ids), sl.Err(err))
+	}
+}
+
+func sharedKey(id int) string {
+	return "song:" + strconv.Itoa(id)
+}
@@ -0,0 +1,259 @@
+package songcache
+
+import (
+	"RestApi_v1/internal/config/internal/config"
+	"RestApi_v1/internal/config/internal/lib/cache"
+	"RestApi_v1/internal/config/internal/models"
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeStore песни в памяти, считает чтения. Если задан read, чтение ждет, пока его не отпустят.
+type fakeStore struct {
+	mu    sync.Mutex
+	songs map[int]models.Song
+	reads int
+
+	read    chan struct{}
+	release chan struct{}
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{songs: map[int]models.Song{1: {ID: 1, Song: "Hysteria", Group: "Muse"}}}
+}
+
+func (s *fakeStore) GetSongWithPagination(_ context.Context, id int, _ int, _ int) ([]models.Song, error) {
+	s.mu.Lock()
+	s.reads++
+	song, ok := s.songs[id]
+	s.mu.Unlock()
+
+	if s.read != nil {
+		s.read <- struct{}{}
+		<-s.release
+	}
+	if !ok {
+		return nil, nil
+	}
+	return []models.Song{song}, nil
+}
+
+func (s *fakeStore) SaveSong(_ context.Context, song string, group string, _ string, _ string, _ string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := len(s.songs) + 1
+	s.songs[id] = models.Song{ID: int64(id), Song: song, Group: group}
+	return int64(id), nil
+}
+
+func (s *fakeStore) UpdateSong(_ context.Context, id int, song string, group string, _ string, _ string, _ string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.songs[id] = models.Song{ID: int64(id), Song: song, Group: group}
+	return "updated", nil
+}
+
+func (s *fakeStore) DeleteSong(_ context.Context, name string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, song := range s.songs {
+		if song.Song == name {
+			delete(s.songs, id)
+		}
+	}
+	return "deleted", nil
+}
+
+// memoryBackend общий кеш в памяти
+type memoryBackend struct {
+	mu   sync.Mutex
+	data map[string][]byte
+}
+
+func newMemoryBackend() *memoryBackend {
+	return &memoryBackend{data: make(map[string][]byte)}
+}
+
+func (b *memoryBackend) Get(_ context.Context, key string) ([]byte, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	data, ok := b.data[key]
+	if !ok {
+		return nil, cache.ErrMiss
+	}
+	return data, nil
+}
+
+func (b *memoryBackend) Set(_ context.Context, key string, value []byte, _ time.Duration) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.data[key] = value
+	return nil
+}
+
+func (b *memoryBackend) Delete(_ context.Context, keys ...string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, key := range keys {
+		delete(b.data, key)
+	}
+	return nil
+}
+
+func (b *memoryBackend) Close() error { return nil }
+
+var testConfig = config.Cache{Enabled: true, Size: 16, TTL: time.Minute, Redis: config.CacheRedis{TTL: time.Minute}}
+
+func newCache(store Store, shared cache.Backend) *Cache {
+	return New(slog.New(slog.NewTextHandler(io.Discard, nil)), store, shared, testConfig)
+}
+
+func getSong(t *testing.T, c *Cache, id int) (models.Song, bool) {
+	t.Helper()
+
+	songs, err := c.GetSongWithPagination(context.Background(), id, 1, 1)
+	if err != nil {
+		t.Fatalf("GetSongWithPagination() error = %v", err)
+	}
+	if len(songs) == 0 {
+		return models.Song{}, false
+	}
+	return songs[0], true
+}
+
+func TestCache(t *testing.T) {
+	tests := []struct {
+		name  string
+		write func(c *Cache)
+		id    int
+		want  string // пустая строка - песни нет
+		reads int    // чтений из базы после двух запросов
+	}{
+		{
+			name:  "repeat is served from cache",
+			id:    1,
+			want:  "Hysteria",
+			reads: 1,
+		},
+		{
+			name:  "missing song is not cached",
+			id:    2,
+			reads: 2,
+		},
+		{
+			name: "update invalidates",
+			write: func(c *Cache) {
+				_, _ = c.UpdateSong(context.Background(), 1, "Uprising", "Muse", "", "", "")
+			},
+			id:    1,
+			want:  "Uprising",
+			reads: 2,
+		},
+		{
+			name: "delete invalidates by name",
+			write: func(c *Cache) {
+				_, _ = c.DeleteSong(context.Background(), "Hysteria")
+			},
+			id:    1,
+			reads: 2,
+		},
+		{
+			name: "save invalidates the new id",
+			write: func(c *Cache) {
+				_, _ = c.SaveSong(context.Background(), "Uprising", "Muse", "", "", "")
+			},
+			id:    2,
+			want:  "Uprising",
+			reads: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newFakeStore()
+			c := newCache(store, newMemoryBackend())
+
+			getSong(t, c, tt.id)
+			if tt.write != nil {
+				tt.write(c)
+			}
+			song, ok := getSong(t, c, tt.id)
+
+			if ok != (tt.want != "") || song.Song != tt.want {
+				t.Errorf("song = %q, %v, want %q", song.Song, ok, tt.want)
+			}
+			if store.reads != tt.reads {
+				t.Errorf("store read %d times, want %d", store.reads, tt.reads)
+			}
+		})
+	}
+}
+
+func TestCacheShared(t *testing.T) {
+	store, shared := newFakeStore(), newMemoryBackend()
+
+	getSong(t, newCache(store, shared), 1)
+
+	// другая реплика берет песню из общего кеша
+	other := newCache(store, shared)
+	if song, ok := getSong(t, other, 1); !ok || song.Song != "Hysteria" {
+		t.Fatalf("song = %q, %v, want %q", song.Song, ok, "Hysteria")
+	}
+	if stats := other.Stats(); stats.SharedHits != 1 || stats.Misses != 0 {
+		t.Errorf("stats = %+v, want one shared hit", stats)
+	}
+
+	other.Invalidate(context.Background(), 1)
+	if _, err := shared.Get(context.Background(), sharedKey(1)); err != cache.ErrMiss {
+		t.Errorf("shared entry is not invalidated, err = %v", err)
+	}
+}
+
+// Песня, прочитанная из базы до сброса, не должна попасть в кеш после него.
+func TestCacheInvalidateDuringLookup(t *testing.T) {
+	tests := []struct {
+		name       string
+		invalidate func(c *Cache)
+	}{
+		{name: "invalidate", invalidate: func(c *Cache) { c.Invalidate(context.Background(), 1) }},
+		{name: "purge", invalidate: func(c *Cache) { c.Purge() }},
+		{name: "delete by name", invalidate: func(c *Cache) { _, _ = c.DeleteSong(context.Background(), "Hysteria") }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, shared := newFakeStore(), newMemoryBackend()
+			store.read, store.release = make(chan struct{}), make(chan struct{})
+			c := newCache(store, shared)
+
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				_, _ = c.GetSongWithPagination(context.Background(), 1, 1, 1)
+			}()
+
+			// чтение уже получило старую песню, но еще не положило ее в кеш
+			<-store.read
+			tt.invalidate(c)
+			close(store.release)
+			<-done
+
+			if c.Stats().Size != 0 {
+				t.Error("stale song is left in the local cache")
+			}
+			if _, err := shared.Get(context.Background(), sharedKey(1)); err != cache.ErrMiss {
+				t.Errorf("stale song is left in the shared cache, err = %v", err)
+			}
+		})
+	}
+}
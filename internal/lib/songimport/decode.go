@@ -0,0 +1,165 @@
+package songimport
+
+import (
+	"RestApi_v1/internal/config/internal/http-server/handlers/song/save"
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+)
+
+const (
+	ContentTypeJSON   = "application/json"
+	ContentTypeNDJSON = "application/x-ndjson"
+	ContentTypeCSV    = "text/csv"
+)
+
+var ErrUnsupportedFormat = errors.New("unsupported import format")
+
+// Record строка входных данных. Если строку не удалось разобрать, Err не пустой.
+type Record struct {
+	Row     int
+	Request save.Request
+	Err     error
+}
+
+// csvColumns имена колонок CSV: как в JSON запроса и как в CSV-выгрузке песен
+var csvColumns = map[string]string{
+	"song":         "song",
+	"group":        "group",
+	"text_song":    "text_song",
+	"text":         "text_song",
+	"date_song":    "date_song",
+	"release_date": "date_song",
+	"link_song":    "link_song",
+	"link":         "link_song",
+}
+
+// MediaType проверяет, что формат поддерживается, и возвращает его без параметров
+func MediaType(contentType string) (string, error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", fmt.Errorf("%w: %q", ErrUnsupportedFormat, contentType)
+	}
+
+	switch mediaType {
+	case ContentTypeJSON, ContentTypeNDJSON, ContentTypeCSV:
+		return mediaType, nil
+	}
+	return "", fmt.Errorf("%w: %q", ErrUnsupportedFormat, mediaType)
+}
+
+// Decode разбирает NDJSON, JSON-массив или CSV с заголовком. Строки нумеруются с 1.
+func Decode(r io.Reader, contentType string) ([]Record, error) {
+	mediaType, err := MediaType(contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	switch mediaType {
+	case ContentTypeJSON:
+		return decodeJSON(r)
+	case ContentTypeNDJSON:
+		return decodeNDJSON(r)
+	default:
+		return decodeCSV(r)
+	}
+}
+
+func decodeJSON(r io.Reader) ([]Record, error) {
+	var items []json.RawMessage
+	if err := json.NewDecoder(r).Decode(&items); err != nil {
+		return nil, fmt.Errorf("failed to decode JSON array: %w", err)
+	}
+
+	records := make([]Record, 0, len(items))
+	for i, item := range items {
+		rec := Record{Row: i + 1}
+		rec.Err = json.Unmarshal(item, &rec.Request)
+		records = append(records, rec)
+	}
+	return records, nil
+}
+
+func decodeNDJSON(r io.Reader) ([]Record, error) {
+	scanner := bufio.NewScanner(r)
+	// текст песни может быть длинным
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+
+	var records []Record
+	row := 0
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		row++
+
+		rec := Record{Row: row}
+		rec.Err = json.Unmarshal(line, &rec.Request)
+		records = append(records, rec)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read NDJSON: %w", err)
+	}
+	return records, nil
+}
+
+func decodeCSV(r io.Reader) ([]Record, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	columns := make([]string, len(header))
+	for i, name := range header {
+		columns[i] = csvColumns[strings.ToLower(strings.TrimSpace(name))]
+	}
+
+	var records []Record
+	for row := 1; ; row++ {
+		fields, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		rec := Record{Row: row}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return nil, fmt.Errorf("failed to read CSV: %w", err)
+			}
+			rec.Err = err
+			records = append(records, rec)
+			continue
+		}
+
+		for i, value := range fields {
+			if i >= len(columns) {
+				break
+			}
+			switch columns[i] {
+			case "song":
+				rec.Request.Song = value
+			case "group":
+				rec.Request.Group = value
+			case "text_song":
+				rec.Request.TextSong = value
+			case "date_song":
+				rec.Request.DateSong = value
+			case "link_song":
+				rec.Request.LinkSong = value
+			}
+		}
+		records = append(records, rec)
+	}
+	return records, nil
+}
@@ -0,0 +1,177 @@
+package songimport
+
+import (
+	"RestApi_v1/internal/config/internal/lib/i18n"
+	"RestApi_v1/internal/config/internal/lib/validate"
+	"RestApi_v1/internal/config/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"strconv"
+	"strings"
+)
+
+type Mode string
+
+const (
+	// ModeAtomic сохраняет все строки или ни одной
+	ModeAtomic Mode = "atomic"
+	// ModeBestEffort сохраняет все корректные строки, остальные только попадают в отчет
+	ModeBestEffort Mode = "best_effort"
+)
+
+const (
+	StatusCreated   = "created"
+	StatusDuplicate = "duplicate"
+	StatusInvalid   = "invalid"
+	// StatusSkipped корректная строка, не сохраненная из-за отката atomic-загрузки
+	StatusSkipped = "skipped"
+)
+
+func ParseMode(s string) (Mode, error) {
+	switch Mode(s) {
+	case "":
+		return ModeBestEffort, nil
+	case ModeAtomic, ModeBestEffort:
+		return Mode(s), nil
+	}
+	return "", fmt.Errorf("unknown import mode %q", s)
+}
+
+// Importer хранилище, умеющее сохранять песни пачкой
+type Importer interface {
+	ImportSongs(ctx context.Context, songs []storage.ImportSong, atomic bool) ([]storage.ImportResult, bool, error)
+}
+
+type RowResult struct {
+	Row    int    `json:"row"`
+	Status string `json:"status"`
+	ID     int64  `json:"id,omitempty"`
+	Song   string `json:"song,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+type Report struct {
+	Mode       Mode        `json:"mode"`
+	Committed  bool        `json:"committed"`
+	Total      int         `json:"total"`
+	Created    int         `json:"created"`
+	Duplicates int         `json:"duplicates"`
+	Invalid    int         `json:"invalid"`
+	Message    string      `json:"message,omitempty"`
+	Rows       []RowResult `json:"rows"`
+}
+
+// Merge добавляет к отчету итоги следующей части загрузки
+func (r *Report) Merge(part Report) {
+	r.Committed = r.Committed || part.Committed
+	r.Total += part.Total
+	r.Created += part.Created
+	r.Duplicates += part.Duplicates
+	r.Invalid += part.Invalid
+	r.Rows = append(r.Rows, part.Rows...)
+}
+
+// Run проверяет строки по правилам save.Request и сохраняет корректные.
+// Сообщения об ошибках в отчете на языке переводчика t.
+func Run(ctx context.Context, importer Importer, records []Record, mode Mode, t *i18n.Translator) (Report, error) {
+	report := Report{
+		Mode:  mode,
+		Total: len(records),
+		Rows:  make([]RowResult, len(records)),
+	}
+
+	var songs []storage.ImportSong
+	index := make(map[int]int, len(records))
+
+	for i, rec := range records {
+		index[rec.Row] = i
+		report.Rows[i] = RowResult{Row: rec.Row, Song: rec.Request.Song}
+
+		if msg := invalid(rec, t); msg != "" {
+			report.Rows[i].Status = StatusInvalid
+			report.Rows[i].Error = msg
+			report.Invalid++
+			continue
+		}
+
+		date, _ := validate.NormalizeDate(rec.Request.DateSong)
+		songs = append(songs, storage.ImportSong{
+			Row:   rec.Row,
+			Song:  rec.Request.Song,
+			Group: rec.Request.Group,
+			Text:  rec.Request.TextSong,
+			Date:  date,
+			Link:  rec.Request.LinkSong,
+		})
+	}
+
+	// в atomic-режиме одна плохая строка отменяет всю загрузку, в базу не ходим
+	if len(songs) == 0 || (mode == ModeAtomic && report.Invalid > 0) {
+		markSkipped(&report, mode, t)
+		return report, nil
+	}
+
+	results, committed, err := importer.ImportSongs(ctx, songs, mode == ModeAtomic)
+	if err != nil {
+		return report, err
+	}
+	report.Committed = committed
+
+	for _, res := range results {
+		row := &report.Rows[index[res.Row]]
+		switch {
+		case res.Created && committed:
+			row.Status = StatusCreated
+			row.ID = res.ID
+			report.Created++
+		case res.Created:
+			row.Status = StatusSkipped
+		default:
+			row.Status = StatusDuplicate
+			row.Error = t.T(i18n.MsgSongExists)
+			report.Duplicates++
+		}
+	}
+	if !committed {
+		markSkipped(&report, mode, t)
+	}
+
+	return report, nil
+}
+
+// markSkipped помечает несохраненные строки и объясняет откат atomic-загрузки
+func markSkipped(report *Report, mode Mode, t *i18n.Translator) {
+	for i := range report.Rows {
+		if report.Rows[i].Status == "" {
+			report.Rows[i].Status = StatusSkipped
+		}
+	}
+	if mode == ModeAtomic {
+		failed := report.Invalid + report.Duplicates
+		report.Message = t.T(i18n.MsgImportRejected, strconv.Itoa(failed), strconv.Itoa(report.Total))
+	}
+}
+
+func invalid(rec Record, t *i18n.Translator) string {
+	if rec.Err != nil {
+		return t.T(i18n.MsgFailedToDecode) + ": " + rec.Err.Error()
+	}
+
+	err := validate.Struct(rec.Request)
+	if err == nil {
+		return ""
+	}
+
+	var validateErrs validator.ValidationErrors
+	if !errors.As(err, &validateErrs) {
+		return t.T(i18n.MsgValidationError)
+	}
+
+	msgs := make([]string, 0, len(validateErrs))
+	for _, fe := range validateErrs {
+		msgs = append(msgs, fe.Translate(t.Validation()))
+	}
+	return strings.Join(msgs, ", ")
+}
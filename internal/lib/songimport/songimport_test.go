@@ -0,0 +1,242 @@
+package songimport
+
+import (
+	"RestApi_v1/internal/config/internal/lib/i18n"
+	"RestApi_v1/internal/config/internal/storage"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		songs       []string // пустая строка - строка с ошибкой разбора
+		wantErr     bool
+	}{
+		{
+			name:        "ndjson skips blank lines",
+			contentType: ContentTypeNDJSON,
+			body:        "{\"song\":\"a\"}\n\n{\"song\":\"b\",\"group\":\"g\"}\n",
+			songs:       []string{"a", "b"},
+		},
+		{
+			name:        "ndjson broken line",
+			contentType: ContentTypeNDJSON + "; charset=utf-8",
+			body:        "{\"song\":\"a\"}\n{\"song\":\n{\"song\":\"c\"}\n",
+			songs:       []string{"a", "", "c"},
+		},
+		{
+			name:        "json array",
+			contentType: ContentTypeJSON,
+			body:        `[{"song":"a"},{"song":1},{"song":"c"}]`,
+			songs:       []string{"a", "", "c"},
+		},
+		{
+			name:        "json is not an array",
+			contentType: ContentTypeJSON,
+			body:        `{"song":"a"}`,
+			wantErr:     true,
+		},
+		{
+			name:        "csv with export column names",
+			contentType: ContentTypeCSV,
+			body:        "Song,group,release_date\na,g,2006-01-02\nb,g\n",
+			songs:       []string{"a", "b"},
+		},
+		{
+			name:        "csv broken quote",
+			contentType: ContentTypeCSV,
+			body:        "song\na\n\"b\n",
+			songs:       []string{"a", ""},
+		},
+		{
+			name:        "unsupported type",
+			contentType: "text/plain",
+			body:        "a",
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := Decode(strings.NewReader(tt.body), tt.contentType)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Decode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(records) != len(tt.songs) {
+				t.Fatalf("Decode() returned %d records, want %d", len(records), len(tt.songs))
+			}
+			for i, rec := range records {
+				if rec.Row != i+1 {
+					t.Errorf("record %d row = %d, want %d", i, rec.Row, i+1)
+				}
+				if tt.songs[i] == "" {
+					if rec.Err == nil {
+						t.Errorf("record %d: want parse error", i)
+					}
+					continue
+				}
+				if rec.Err != nil || rec.Request.Song != tt.songs[i] {
+					t.Errorf("record %d = %q (err %v), want %q", i, rec.Request.Song, rec.Err, tt.songs[i])
+				}
+			}
+		})
+	}
+}
+
+// fakeImporter ведет себя как storage.postgres.ImportSongs: существующее название - дубликат,
+// в atomic-режиме любой дубликат откатывает пачку
+type fakeImporter struct {
+	existing map[string]bool
+	calls    int
+}
+
+func (f *fakeImporter) ImportSongs(_ context.Context, songs []storage.ImportSong, atomic bool) ([]storage.ImportResult, bool, error) {
+	f.calls++
+
+	seen := make(map[string]bool)
+	results := make([]storage.ImportResult, 0, len(songs))
+	duplicates := 0
+	for i, song := range songs {
+		if f.existing[song.Song] || seen[song.Song] {
+			duplicates++
+			results = append(results, storage.ImportResult{Row: song.Row})
+			continue
+		}
+		seen[song.Song] = true
+		results = append(results, storage.ImportResult{Row: song.Row, ID: int64(100 + i), Created: true})
+	}
+
+	if atomic && duplicates > 0 {
+		return results, false, nil
+	}
+	for song := range seen {
+		f.existing[song] = true
+	}
+	return results, true, nil
+}
+
+func TestRun(t *testing.T) {
+	const body = `{"song":"new"}
+{"song":"old"}
+{"song":"","group":"g"}
+{"song":"other","date_song":"2006-01-02"}
+`
+
+	tests := []struct {
+		name      string
+		mode      Mode
+		body      string
+		statuses  []string
+		committed bool
+		calls     int
+		message   bool
+	}{
+		{
+			name:      "best effort saves valid rows",
+			mode:      ModeBestEffort,
+			body:      body,
+			statuses:  []string{StatusCreated, StatusDuplicate, StatusInvalid, StatusCreated},
+			committed: true,
+			calls:     1,
+		},
+		{
+			name:     "atomic with invalid row does not touch storage",
+			mode:     ModeAtomic,
+			body:     body,
+			statuses: []string{StatusSkipped, StatusSkipped, StatusInvalid, StatusSkipped},
+			message:  true,
+		},
+		{
+			name:     "atomic with duplicate is rolled back",
+			mode:     ModeAtomic,
+			body:     "{\"song\":\"new\"}\n{\"song\":\"old\"}\n",
+			statuses: []string{StatusSkipped, StatusDuplicate},
+			calls:    1,
+			message:  true,
+		},
+		{
+			name:      "atomic without errors",
+			mode:      ModeAtomic,
+			body:      "{\"song\":\"new\"}\n{\"song\":\"other\"}\n",
+			statuses:  []string{StatusCreated, StatusCreated},
+			committed: true,
+			calls:     1,
+		},
+		{
+			name:      "duplicate title inside the file",
+			mode:      ModeBestEffort,
+			body:      "{\"song\":\"new\"}\n{\"song\":\"new\"}\n",
+			statuses:  []string{StatusCreated, StatusDuplicate},
+			committed: true,
+			calls:     1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := Decode(strings.NewReader(tt.body), ContentTypeNDJSON)
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+
+			importer := &fakeImporter{existing: map[string]bool{"old": true}}
+			report, err := Run(context.Background(), importer, records, tt.mode, i18n.Get(i18n.DefaultLang))
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+
+			if report.Committed != tt.committed {
+				t.Errorf("Committed = %v, want %v", report.Committed, tt.committed)
+			}
+			if importer.calls != tt.calls {
+				t.Errorf("ImportSongs called %d times, want %d", importer.calls, tt.calls)
+			}
+			if (report.Message != "") != tt.message {
+				t.Errorf("Message = %q, want message %v", report.Message, tt.message)
+			}
+			if len(report.Rows) != len(tt.statuses) {
+				t.Fatalf("got %d rows, want %d", len(report.Rows), len(tt.statuses))
+			}
+
+			created := 0
+			for i, row := range report.Rows {
+				if row.Status != tt.statuses[i] {
+					t.Errorf("row %d status = %q, want %q", row.Row, row.Status, tt.statuses[i])
+				}
+				if row.Status == StatusCreated {
+					created++
+					if row.ID == 0 {
+						t.Errorf("row %d: created without id", row.Row)
+					}
+				}
+			}
+			if report.Created != created {
+				t.Errorf("Created = %d, want %d", report.Created, created)
+			}
+		})
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Mode
+		wantErr bool
+	}{
+		{in: "", want: ModeBestEffort},
+		{in: "atomic", want: ModeAtomic},
+		{in: "best_effort", want: ModeBestEffort},
+		{in: "all", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseMode(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseMode(%q) = %q, %v, want %q, wantErr %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
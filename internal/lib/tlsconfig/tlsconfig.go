@@ -0,0 +1,150 @@
+package tlsconfig
+
+import (
+	"RestApi_v1/internal/config/internal/config"
+	"RestApi_v1/internal/config/internal/lib/logger/sl"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// reloadDebounce сертификат и ключ обычно обновляются парой, перечитываем после последнего изменения
+const reloadDebounce = 500 * time.Millisecond
+
+var versions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// New настройки TLS сервера: сертификат из certs, минимальная версия и, если задан CA, проверка клиентов
+func New(cfg config.TLS, certs *CertReloader) (*tls.Config, error) {
+	const op = "lib.tlsconfig.New"
+
+	minVersion, ok := versions[cfg.MinVersion]
+	if !ok {
+		return nil, fmt.Errorf("%s: unknown min version %q", op, cfg.MinVersion)
+	}
+
+	tlsCfg := &tls.Config{
+		MinVersion:     minVersion,
+		GetCertificate: certs.GetCertificate,
+	}
+
+	if cfg.ClientCAFile != "" {
+		pem, err := os.ReadFile(cfg.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("%s: failed to read client CA: %w", op, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("%s: no certificates in %s", op, cfg.ClientCAFile)
+		}
+
+		tlsCfg.ClientCAs = pool
+		tlsCfg.ClientAuth = tls.VerifyClientCertIfGiven
+		if cfg.RequireClientCert {
+			tlsCfg.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+	}
+
+	return tlsCfg, nil
+}
+
+// CertReloader отдает серверу текущий сертификат и перечитывает его при изменении файлов.
+// Если новая пара не читается, остается прежний сертификат.
+type CertReloader struct {
+	log      *slog.Logger
+	certFile string
+	keyFile  string
+	cert     atomic.Pointer[tls.Certificate]
+}
+
+func NewCertReloader(log *slog.Logger, certFile string, keyFile string) (*CertReloader, error) {
+	const op = "lib.tlsconfig.NewCertReloader"
+
+	r := &CertReloader{
+		log:      log.With(slog.String("component", "tls")),
+		certFile: certFile,
+		keyFile:  keyFile,
+	}
+	if _, err := r.load(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return r, nil
+}
+
+func (r *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return r.cert.Load(), nil
+}
+
+func (r *CertReloader) Name() string {
+	return "tls-reload"
+}
+
+// Run следит за каталогами сертификата и ключа: cert-manager и секреты Kubernetes
+// подменяют файлы через symlink, поэтому реагируем на любое изменение рядом с ними
+func (r *CertReloader) Run(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to watch certificate: %w", err)
+	}
+	defer watcher.Close()
+
+	for _, dir := range []string{filepath.Dir(r.certFile), filepath.Dir(r.keyFile)} {
+		if err := watcher.Add(dir); err != nil {
+			return fmt.Errorf("failed to watch certificate: %w", err)
+		}
+	}
+
+	debounce := time.NewTimer(time.Hour)
+	debounce.Stop()
+	defer debounce.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-watcher.Events:
+			debounce.Reset(reloadDebounce)
+		case <-debounce.C:
+			changed, err := r.load()
+			if err != nil {
+				r.log.Error("failed to reload certificate, keeping the previous one", sl.Err(err))
+				continue
+			}
+			if changed {
+				r.log.Info("certificate reloaded", slog.Time("not_after", r.cert.Load().Leaf.NotAfter))
+			}
+		case err := <-watcher.Errors:
+			r.log.Error("certificate watcher failed", sl.Err(err))
+		}
+	}
+}
+
+// load читает пару и сообщает, отличается ли сертификат от текущего
+func (r *CertReloader) load() (bool, error) {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return false, fmt.Errorf("failed to load certificate: %w", err)
+	}
+	if cert.Leaf == nil {
+		if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+			return false, fmt.Errorf("failed to parse certificate: %w", err)
+		}
+	}
+
+	if prev := r.cert.Load(); prev != nil && bytes.Equal(prev.Certificate[0], cert.Certificate[0]) {
+		return false, nil
+	}
+	r.cert.Store(&cert)
+	return true, nil
+}
@@ -0,0 +1,81 @@
+package validate
+
+import (
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Форматы даты выхода песни, которые принимает API
+const (
+	DateLayoutISO = "2006-01-02"
+	DateLayoutRU  = "02.01.2006"
+)
+
+// TagSongDate тег для даты в одном из поддерживаемых форматов
+const TagSongDate = "songdate"
+
+var dateLayouts = []string{DateLayoutISO, DateLayoutRU}
+
+// валидатор кеширует разобранные структуры, поэтому создаем его один раз
+var v = New()
+
+// New создает валидатор с нашими правилами
+func New() *validator.Validate {
+	validate := validator.New(validator.WithRequiredStructEnabled())
+
+	// в сообщениях об ошибках поля называются так же, как в JSON
+	validate.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name := strings.SplitN(f.Tag.Get("json"), ",", 2)[0]
+		if name == "" || name == "-" {
+			return f.Name
+		}
+		return name
+	})
+
+	if err := validate.RegisterValidation(TagSongDate, songDate); err != nil {
+		panic(fmt.Sprintf("failed to register %s validation: %s", TagSongDate, err))
+	}
+
+	return validate
+}
+
+// Validator возвращает общий экземпляр валидатора
+func Validator() *validator.Validate {
+	return v
+}
+
+// Struct проверяет структуру общим валидатором
+func Struct(s any) error {
+	return v.Struct(s)
+}
+
+func songDate(fl validator.FieldLevel) bool {
+	_, err := ParseDate(fl.Field().String())
+	return err == nil
+}
+
+// ParseDate разбирает дату в формате 2006-01-02 или 02.01.2006
+func ParseDate(s string) (time.Time, error) {
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("date %q does not match %s or %s", s, DateLayoutISO, DateLayoutRU)
+}
+
+// NormalizeDate приводит дату к формату 2006-01-02, пустая строка остается пустой
+func NormalizeDate(s string) (string, error) {
+	if s == "" {
+		return "", nil
+	}
+
+	t, err := ParseDate(s)
+	if err != nil {
+		return "", err
+	}
+	return t.Format(DateLayoutISO), nil
+}
@@ -0,0 +1,240 @@
+package webhooks
+
+import (
+	"RestApi_v1/internal/config/internal/config"
+	"RestApi_v1/internal/config/internal/lib/logger/sl"
+	"RestApi_v1/internal/config/internal/models"
+	"RestApi_v1/internal/config/internal/storage"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "RestApi_v1/lib/webhooks"
+
+// purgeInterval как часто чистить старые события и доставки
+const purgeInterval = time.Hour
+
+// maxErrorBody сколько байт ответа подписчика сохранять в last_error
+const maxErrorBody = 512
+
+type Store interface {
+	FanOutWebhooks(ctx context.Context, limit int, payload func(models.WebhookEvent) ([]byte, error)) (int, error)
+	ClaimWebhookDelivery(ctx context.Context, lease time.Duration) (models.WebhookDelivery, error)
+	FinishWebhookAttempt(ctx context.Context, id int64, attempt int, result storage.DeliveryAttempt) error
+	PurgeWebhooks(ctx context.Context, before time.Time) (int64, error)
+}
+
+// Dispatcher разбирает outbox в доставки по подпискам и отправляет их подписанными POST-запросами.
+// Доставки берутся из базы с арендой, поэтому воркеры нескольких экземпляров не отправляют одно и то же.
+type Dispatcher struct {
+	log    *slog.Logger
+	store  Store
+	client *http.Client
+	cfg    config.Webhooks
+}
+
+func NewDispatcher(log *slog.Logger, store Store, cfg config.Webhooks) *Dispatcher {
+	return &Dispatcher{
+		log:   log.With(slog.String("component", "webhooks")),
+		store: store,
+		client: &http.Client{
+			Timeout: cfg.Timeout,
+			// редирект считаем ошибкой: подписчик должен указать итоговый адрес
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		cfg: cfg,
+	}
+}
+
+func (d *Dispatcher) Name() string {
+	return "webhooks"
+}
+
+func (d *Dispatcher) Run(ctx context.Context) error {
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		d.fanOut(ctx)
+	}()
+
+	for i := 0; i < d.cfg.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.work(ctx)
+		}()
+	}
+
+	wg.Wait()
+	return nil
+}
+
+// fanOut превращает события outbox в доставки и периодически чистит старые записи
+func (d *Dispatcher) fanOut(ctx context.Context) {
+	lastPurge := time.Now()
+
+	for ctx.Err() == nil {
+		n, err := d.store.FanOutWebhooks(ctx, d.cfg.BatchSize, BuildPayload)
+		if err != nil && ctx.Err() == nil {
+			d.log.Error("failed to fan out webhook events", sl.Err(err))
+		}
+		if n > 0 {
+			d.log.Debug("webhook events fanned out", slog.Int("events", n))
+		}
+
+		if time.Since(lastPurge) >= purgeInterval {
+			lastPurge = time.Now()
+			deleted, err := d.store.PurgeWebhooks(ctx, time.Now().Add(-d.cfg.Retention))
+			if err != nil && ctx.Err() == nil {
+				d.log.Error("failed to purge webhook log", sl.Err(err))
+			}
+			if deleted > 0 {
+				d.log.Debug("webhook log purged", slog.Int64("deleted", deleted))
+			}
+		}
+
+		// полная пачка - в outbox, скорее всего, есть еще
+		if err == nil && n == d.cfg.BatchSize {
+			continue
+		}
+		sleep(ctx, d.cfg.PollInterval)
+	}
+}
+
+func (d *Dispatcher) work(ctx context.Context) {
+	for ctx.Err() == nil {
+		delivery, err := d.store.ClaimWebhookDelivery(ctx, d.cfg.Lease)
+		if errors.Is(err, storage.ErrDeliveryNotFound) {
+			sleep(ctx, d.cfg.PollInterval)
+			continue
+		}
+		if err != nil {
+			if ctx.Err() == nil {
+				d.log.Error("failed to claim webhook delivery", sl.Err(err))
+			}
+			sleep(ctx, d.cfg.PollInterval)
+			continue
+		}
+
+		d.deliver(ctx, delivery)
+	}
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, delivery models.WebhookDelivery) {
+	log := d.log.With(
+		slog.Int64("delivery_id", delivery.ID),
+		slog.Int64("subscription_id", delivery.SubscriptionID),
+		slog.String("event", delivery.EventType),
+		slog.Int("attempt", delivery.Attempts),
+	)
+
+	ctx, span := otel.Tracer(tracerName).Start(ctx, "webhooks.deliver",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			attribute.Int64("webhook.delivery_id", delivery.ID),
+			attribute.String("webhook.event", delivery.EventType),
+			attribute.Int("webhook.attempt", delivery.Attempts),
+		),
+	)
+	defer span.End()
+
+	result := d.send(ctx, delivery)
+
+	if !result.Delivered {
+		span.SetStatus(codes.Error, result.Error)
+		if delivery.Attempts < d.cfg.MaxAttempts {
+			retryAt := time.Now().Add(d.backoff(delivery.Attempts))
+			result.RetryAt = &retryAt
+		}
+	}
+
+	// итог записываем и при остановке сервиса, иначе доставка дождется конца аренды
+	finishCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+
+	err := d.store.FinishWebhookAttempt(finishCtx, delivery.ID, delivery.Attempts, result)
+	switch {
+	case errors.Is(err, storage.ErrDeliveryNotFound):
+		log.Warn("webhook delivery lease lost")
+	case err != nil:
+		log.Error("failed to record webhook attempt", sl.Err(err))
+	case result.Delivered:
+		log.Info("webhook delivered", slog.Int("status", result.StatusCode))
+	case result.RetryAt != nil:
+		log.Info("webhook delivery failed, will retry",
+			slog.Int("status", result.StatusCode), slog.String("error", result.Error), slog.Time("retry_at", *result.RetryAt))
+	default:
+		log.Warn("webhook delivery is dead",
+			slog.Int("status", result.StatusCode), slog.String("error", result.Error))
+	}
+}
+
+// send отправляет доставку. Успех - любой 2xx, остальное повторяется.
+func (d *Dispatcher) send(ctx context.Context, delivery models.WebhookDelivery) storage.DeliveryAttempt {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return storage.DeliveryAttempt{Error: err.Error()}
+	}
+
+	now := time.Now()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "restApi_v1-webhooks")
+	req.Header.Set(HeaderID, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set(HeaderSignature, Sign(delivery.Secret, now, delivery.Payload))
+
+	res, err := d.client.Do(req)
+	if err != nil {
+		return storage.DeliveryAttempt{Error: err.Error()}
+	}
+	defer res.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(res.Body, maxErrorBody))
+	// дочитываем, чтобы соединение вернулось в пул
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+
+	if res.StatusCode >= 200 && res.StatusCode < 300 {
+		return storage.DeliveryAttempt{StatusCode: res.StatusCode, Delivered: true}
+	}
+	return storage.DeliveryAttempt{
+		StatusCode: res.StatusCode,
+		Error:      fmt.Sprintf("unexpected status %d: %s", res.StatusCode, bytes.TrimSpace(body)),
+	}
+}
+
+// backoff задержка перед следующей попыткой: RetryBackoff, удваиваясь до MaxBackoff
+func (d *Dispatcher) backoff(attempt int) time.Duration {
+	delay := d.cfg.RetryBackoff
+	for i := 1; i < attempt && delay < d.cfg.MaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, d.cfg.MaxBackoff)
+}
+
+func sleep(ctx context.Context, d time.Duration) {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+	case <-t.C:
+	}
+}
@@ -0,0 +1,68 @@
+package webhooks
+
+import (
+	"RestApi_v1/internal/config/internal/models"
+	"time"
+)
+
+// Subscription подписка в ответах API. Секрет показывается только при создании.
+type Subscription struct {
+	ID        int64     `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Secret    string    `json:"secret,omitempty"`
+	CreatedBy string    `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func SubscriptionOf(sub models.WebhookSubscription, withSecret bool) Subscription {
+	s := Subscription{
+		ID:        sub.ID,
+		URL:       sub.URL,
+		Events:    sub.Events,
+		CreatedBy: sub.CreatedBy,
+		CreatedAt: sub.CreatedAt,
+	}
+	if withSecret {
+		s.Secret = sub.Secret
+	}
+	return s
+}
+
+// Delivery запись журнала доставок
+type Delivery struct {
+	ID             int64      `json:"id"`
+	EventID        int64      `json:"event_id"`
+	Event          string     `json:"event"`
+	Status         string     `json:"status" enums:"pending,delivered,dead"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at,omitempty"` // только для pending
+	LastStatusCode int        `json:"last_status_code,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	LastAttemptAt  *time.Time `json:"last_attempt_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+}
+
+func DeliveryOf(d models.WebhookDelivery) Delivery {
+	v := Delivery{
+		ID:             d.ID,
+		EventID:        d.OutboxID,
+		Event:          d.EventType,
+		Status:         d.Status,
+		Attempts:       d.Attempts,
+		LastStatusCode: d.LastStatusCode,
+		LastError:      d.LastError,
+		CreatedAt:      d.CreatedAt,
+	}
+	if d.Status == models.DeliveryPending {
+		v.NextAttemptAt = &d.NextAttemptAt
+	}
+	if d.LastAttemptAt.Valid {
+		v.LastAttemptAt = &d.LastAttemptAt.Time
+	}
+	if d.DeliveredAt.Valid {
+		v.DeliveredAt = &d.DeliveredAt.Time
+	}
+	return v
+}
@@ -0,0 +1,92 @@
+package webhooks
+
+import (
+	"RestApi_v1/internal/config/internal/models"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+// Заголовки запроса к подписчику
+const (
+	HeaderID        = "X-Webhook-Id" // id доставки, одинаковый во всех повторах
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	// HeaderSignature sha256=<hex HMAC-SHA256(secret, timestamp + "." + body)>
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// EventTypes события, на которые можно подписаться
+var EventTypes = []string{models.WebhookSongCreated, models.WebhookSongUpdated, models.WebhookSongDeleted}
+
+func ValidEvent(eventType string) bool {
+	for _, t := range EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Payload тело запроса к подписчику
+type Payload struct {
+	ID        int64     `json:"id"` // id события, по нему подписчик отбрасывает повторы
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      Song      `json:"data"`
+}
+
+// Song песня в теле вебхука: для song.deleted - состояние до удаления
+type Song struct {
+	ID          int64     `json:"id"`
+	Song        string    `json:"song"`
+	Group       string    `json:"group"`
+	Text        string    `json:"text,omitempty"`
+	ReleaseDate string    `json:"release_date,omitempty"` // 2006-01-02
+	Link        string    `json:"link,omitempty"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// BuildPayload тело вебхука для события outbox. Оно сохраняется в доставке, так что повторы отправляют те же байты.
+func BuildPayload(event models.WebhookEvent) ([]byte, error) {
+	song := Song{
+		ID:        event.Song.ID,
+		Song:      event.Song.Song,
+		Group:     event.Song.Group,
+		Text:      event.Song.Text,
+		Link:      event.Song.Link,
+		UpdatedAt: event.Song.UpdatedAt,
+	}
+	if event.Song.ReleaseDate.Valid {
+		song.ReleaseDate = event.Song.ReleaseDate.Time.Format("2006-01-02")
+	}
+
+	return json.Marshal(Payload{
+		ID:        event.ID,
+		Type:      event.Type,
+		CreatedAt: event.CreatedAt,
+		Data:      song,
+	})
+}
+
+// Sign подпись тела для заголовка X-Webhook-Signature. Время входит в подпись, чтобы запрос нельзя было повторить позже.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// NewSecret случайный секрет для подписки, если клиент не задал свой
+func NewSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,173 @@
+package webhooks
+
+import (
+	"RestApi_v1/internal/config/internal/config"
+	"RestApi_v1/internal/config/internal/models"
+	"RestApi_v1/internal/config/internal/storage"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSign(t *testing.T) {
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp time.Time
+		body      string
+		want      string
+	}{
+		{
+			name:      "known vector",
+			secret:    "whsec_test",
+			timestamp: time.Unix(1700000000, 0),
+			body:      `{"id":1}`,
+			want:      "sha256=2f441ba4b3b2d50d28a9ab9d9fd8880376ecd1eb5d0435401553f5d8d0a5dcf8",
+		},
+		{
+			name:      "empty secret and body",
+			timestamp: time.Unix(0, 0),
+			want:      "sha256=b849d5a581847b281957065739df36df2463d1977ea8d6e1e4e6cf33fadc68c3",
+		},
+		{
+			name:      "only seconds are signed",
+			secret:    "whsec_test",
+			timestamp: time.Unix(1700000000, 999),
+			body:      `{"id":1}`,
+			want:      "sha256=2f441ba4b3b2d50d28a9ab9d9fd8880376ecd1eb5d0435401553f5d8d0a5dcf8",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Sign(tt.secret, tt.timestamp, []byte(tt.body)); got != tt.want {
+				t.Errorf("Sign() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSignDiffers(t *testing.T) {
+	ts := time.Unix(1700000000, 0)
+	base := Sign("secret", ts, []byte("body"))
+
+	for name, got := range map[string]string{
+		"secret":    Sign("other", ts, []byte("body")),
+		"timestamp": Sign("secret", ts.Add(time.Second), []byte("body")),
+		"body":      Sign("secret", ts, []byte("body!")),
+	} {
+		if got == base {
+			t.Errorf("signature does not depend on %s", name)
+		}
+	}
+}
+
+var testConfig = config.Webhooks{
+	Timeout:      time.Second,
+	MaxAttempts:  3,
+	RetryBackoff: 10 * time.Second,
+	MaxBackoff:   time.Minute,
+}
+
+func TestBackoff(t *testing.T) {
+	d := NewDispatcher(slog.New(slog.NewTextHandler(io.Discard, nil)), nil, testConfig)
+
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{attempt: 0, want: 10 * time.Second},
+		{attempt: 1, want: 10 * time.Second},
+		{attempt: 2, want: 20 * time.Second},
+		{attempt: 3, want: 40 * time.Second},
+		{attempt: 4, want: time.Minute},
+		{attempt: 100, want: time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := d.backoff(tt.attempt); got != tt.want {
+			t.Errorf("backoff(%d) = %s, want %s", tt.attempt, got, tt.want)
+		}
+	}
+}
+
+// attemptStore запоминает итог последней попытки
+type attemptStore struct {
+	Store
+	result *storage.DeliveryAttempt
+}
+
+func (s *attemptStore) FinishWebhookAttempt(_ context.Context, _ int64, _ int, result storage.DeliveryAttempt) error {
+	s.result = &result
+	return nil
+}
+
+func TestDeliver(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		attempt   int
+		delivered bool
+		retry     bool
+	}{
+		{name: "2xx is delivered", status: http.StatusNoContent, attempt: 1, delivered: true},
+		{name: "5xx is retried", status: http.StatusBadGateway, attempt: 1, retry: true},
+		{name: "redirect is not followed", status: http.StatusFound, attempt: 2, retry: true},
+		{name: "last attempt is dead", status: http.StatusInternalServerError, attempt: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			const secret = "whsec_test"
+			payload := []byte(`{"id":7,"type":"song.created"}`)
+
+			var signatureOK bool
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				ts, _ := strconv.ParseInt(r.Header.Get(HeaderTimestamp), 10, 64)
+				signatureOK = r.Header.Get(HeaderSignature) == Sign(secret, time.Unix(ts, 0), body) &&
+					r.Header.Get(HeaderEvent) == models.WebhookSongCreated && r.Header.Get(HeaderID) == "5"
+				if tt.status == http.StatusFound {
+					w.Header().Set("Location", "/elsewhere")
+				}
+				w.WriteHeader(tt.status)
+				_, _ = io.WriteString(w, "  upstream said no  ")
+			}))
+			defer srv.Close()
+
+			store := &attemptStore{}
+			d := NewDispatcher(slog.New(slog.NewTextHandler(io.Discard, nil)), store, testConfig)
+			d.deliver(context.Background(), models.WebhookDelivery{
+				ID:        5,
+				EventType: models.WebhookSongCreated,
+				Payload:   payload,
+				Attempts:  tt.attempt,
+				URL:       srv.URL,
+				Secret:    secret,
+			})
+
+			if !signatureOK {
+				t.Error("subscriber got a request with a wrong signature or headers")
+			}
+			if store.result == nil {
+				t.Fatal("attempt is not recorded")
+			}
+			res := store.result
+			if res.Delivered != tt.delivered || res.StatusCode != tt.status {
+				t.Errorf("attempt = %+v, want delivered %v with status %d", res, tt.delivered, tt.status)
+			}
+			if (res.RetryAt != nil) != tt.retry {
+				t.Errorf("retry at %v, want retry %v", res.RetryAt, tt.retry)
+			}
+			if !tt.delivered && !strings.HasSuffix(res.Error, ": upstream said no") {
+				t.Errorf("error = %q, want the trimmed response body", res.Error)
+			}
+		})
+	}
+}
@@ -0,0 +1,14 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+type APIKey struct {
+	ID        int64        `db:"id"`
+	Name      string       `db:"name"`
+	Role      string       `db:"role"`
+	CreatedAt time.Time    `db:"created_at"`
+	RevokedAt sql.NullTime `db:"revoked_at"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// Операции над песней в журнале событий
+const (
+	SongInserted = "INSERT"
+	SongUpdated  = "UPDATE"
+	SongDeleted  = "DELETE"
+)
+
+// SongEvent запись журнала изменений песен. Song - состояние после изменения, для удаления - последнее.
+type SongEvent struct {
+	ID        int64     `db:"id"`
+	Op        string    `db:"op"`
+	SongID    int64     `db:"song_id"`
+	Group     string    `db:"song_group"`
+	Song      Song      `db:"snapshot"`
+	CreatedAt time.Time `db:"created_at"`
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Статусы фоновой задачи
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+)
+
+type Job struct {
+	ID          int64        `db:"id"`
+	Kind        string       `db:"kind"`
+	Status      string       `db:"status"`
+	Params      []byte       `db:"params"`
+	PayloadType string       `db:"payload_type"`
+	Attempts    int          `db:"attempts"`
+	MaxAttempts int          `db:"max_attempts"`
+	Processed   int          `db:"processed"`
+	Total       int          `db:"total"`
+	Checkpoint  []byte       `db:"checkpoint"`
+	ResultType  string       `db:"result_type"`
+	ResultSize  int64        `db:"result_size"`
+	Error       string       `db:"error"`
+	CreatedBy   string       `db:"created_by"`
+	RunAt       time.Time    `db:"run_at"`
+	CreatedAt   time.Time    `db:"created_at"`
+	UpdatedAt   time.Time    `db:"updated_at"`
+	StartedAt   sql.NullTime `db:"started_at"`
+	FinishedAt  sql.NullTime `db:"finished_at"`
+}
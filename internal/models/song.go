@@ -2,6 +2,9 @@ package models
 
 import (
 	"database/sql"
+	"encoding/xml"
+	"strconv"
+	"time"
 )
 
 type Song struct {
@@ -11,4 +14,44 @@ type Song struct {
 	Text        string       `db:"text"`
 	ReleaseDate sql.NullTime `db:"release_date"`
 	Link        string       `db:"link"`
+	UpdatedAt   time.Time    `db:"updated_at"`
+}
+
+// Songs список песен, умеет выдавать себя в CSV и XML
+type Songs []Song
+
+var songsCSVHeader = []string{"id", "song", "group", "text", "release_date", "link"}
+
+// MarshalCSV таблица с заголовком, дата в формате 2006-01-02
+func (s Songs) MarshalCSV() ([]string, [][]string) {
+	records := make([][]string, 0, len(s))
+	for _, song := range s {
+		date := ""
+		if song.ReleaseDate.Valid {
+			date = song.ReleaseDate.Time.Format("2006-01-02")
+		}
+		records = append(records, []string{
+			strconv.FormatInt(song.ID, 10),
+			song.Song,
+			song.Group,
+			song.Text,
+			date,
+			song.Link,
+		})
+	}
+	return songsCSVHeader, records
+}
+
+// MarshalXML оборачивает список в корневой элемент <songs>
+func (s Songs) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start.Name = xml.Name{Local: "songs"}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	for _, song := range s {
+		if err := e.EncodeElement(song, xml.StartElement{Name: xml.Name{Local: "song"}}); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
 }
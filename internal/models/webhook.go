@@ -0,0 +1,57 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Типы событий для подписчиков вебхуков
+const (
+	WebhookSongCreated = "song.created"
+	WebhookSongUpdated = "song.updated"
+	WebhookSongDeleted = "song.deleted"
+)
+
+// Статусы доставки вебхука
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryDead      = "dead" // попытки кончились, повторить можно только вручную
+)
+
+type WebhookSubscription struct {
+	ID        int64     `db:"id"`
+	URL       string    `db:"url"`
+	Events    []string  `db:"events"`
+	Secret    string    `db:"secret"`
+	CreatedBy string    `db:"created_by"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// WebhookEvent запись outbox, сделанная в одной транзакции с изменением песни
+type WebhookEvent struct {
+	ID        int64     `db:"id"`
+	Type      string    `db:"event_type"`
+	SongID    int64     `db:"song_id"`
+	Song      Song      `db:"song"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// WebhookDelivery отправка события одному подписчику. URL и Secret берутся из подписки при захвате доставки.
+type WebhookDelivery struct {
+	ID             int64        `db:"id"`
+	SubscriptionID int64        `db:"subscription_id"`
+	OutboxID       int64        `db:"outbox_id"`
+	EventType      string       `db:"event_type"`
+	Payload        []byte       `db:"payload"`
+	Status         string       `db:"status"`
+	Attempts       int          `db:"attempts"`
+	NextAttemptAt  time.Time    `db:"next_attempt_at"`
+	LastStatusCode int          `db:"last_status_code"`
+	LastError      string       `db:"last_error"`
+	LastAttemptAt  sql.NullTime `db:"last_attempt_at"`
+	CreatedAt      time.Time    `db:"created_at"`
+	DeliveredAt    sql.NullTime `db:"delivered_at"`
+	URL            string
+	Secret         string
+}
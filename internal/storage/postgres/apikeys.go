@@ -0,0 +1,93 @@
+package postgres
+
+import (
+	"RestApi_v1/internal/config/internal/models"
+	"RestApi_v1/internal/config/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v4"
+)
+
+// CreateAPIKey сохраняет хеш нового API-ключа
+func (s *Storage) CreateAPIKey(ctx context.Context, name string, keyHash string, role string) (id int64, err error) {
+	const op = "storage.postgres.CreateAPIKey"
+
+	query := `INSERT INTO api_keys (name, key_hash, role) VALUES ($1, $2, $3) RETURNING id`
+
+	ctx, span := startSpan(ctx, "CreateAPIKey", query)
+	defer func() { endSpan(span, err) }()
+
+	if err = s.db.QueryRow(ctx, query, name, keyHash, role).Scan(&id); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return id, nil
+}
+
+// APIKeyByHash ищет действующий (не отозванный) ключ по хешу
+func (s *Storage) APIKeyByHash(ctx context.Context, keyHash string) (key models.APIKey, err error) {
+	const op = "storage.postgres.APIKeyByHash"
+
+	query := `SELECT id, name, role, created_at, revoked_at
+			  FROM api_keys WHERE key_hash = $1 AND revoked_at IS NULL`
+
+	ctx, span := startSpan(ctx, "APIKeyByHash", query)
+	defer func() { endSpan(span, err) }()
+
+	err = s.db.QueryRow(ctx, query, keyHash).Scan(&key.ID, &key.Name, &key.Role, &key.CreatedAt, &key.RevokedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return key, storage.ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return key, fmt.Errorf("%s: %w", op, err)
+	}
+	return key, nil
+}
+
+// ListAPIKeys возвращает все ключи, включая отозванные
+func (s *Storage) ListAPIKeys(ctx context.Context) (keys []models.APIKey, err error) {
+	const op = "storage.postgres.ListAPIKeys"
+
+	query := `SELECT id, name, role, created_at, revoked_at FROM api_keys ORDER BY id`
+
+	ctx, span := startSpan(ctx, "ListAPIKeys", query)
+	defer func() { endSpan(span, err) }()
+
+	rows, err := s.db.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var key models.APIKey
+		if err = rows.Scan(&key.ID, &key.Name, &key.Role, &key.CreatedAt, &key.RevokedAt); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		keys = append(keys, key)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return keys, nil
+}
+
+// RevokeAPIKey отзывает ключ, после чего он перестает проходить аутентификацию
+func (s *Storage) RevokeAPIKey(ctx context.Context, id int64) (err error) {
+	const op = "storage.postgres.RevokeAPIKey"
+
+	query := `UPDATE api_keys SET revoked_at = now() WHERE id = $1 AND revoked_at IS NULL`
+
+	ctx, span := startSpan(ctx, "RevokeAPIKey", query)
+	defer func() { endSpan(span, err) }()
+
+	tag, err := s.db.Exec(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return storage.ErrAPIKeyNotFound
+	}
+	return nil
+}
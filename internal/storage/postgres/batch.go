@@ -0,0 +1,81 @@
+package postgres
+
+import (
+	"RestApi_v1/internal/config/internal/models"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// SongsByIDs песни по списку id одним запросом, отсутствующих id в ответе нет
+func (s *Storage) SongsByIDs(ctx context.Context, ids []int64) (songs []models.Song, err error) {
+	const op = "storage.postgres.SongsByIDs"
+
+	query := `SELECT id, song, nameGroup, text, release_date, link, updated_at FROM songs WHERE id = ANY($1)`
+
+	ctx, span := startSpan(ctx, "SongsByIDs", query)
+	defer func() { endSpan(span, err) }()
+
+	rows, err := s.db.Query(ctx, query, ids)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	songs, err = scanSongs(rows)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return songs, nil
+}
+
+// SongsByGroups первые perGroup песен каждой группы в порядке id. Группы сравниваются без учета регистра.
+func (s *Storage) SongsByGroups(ctx context.Context, groups []string, perGroup int) (songs []models.Song, err error) {
+	const op = "storage.postgres.SongsByGroups"
+
+	query := `
+		SELECT id, song, nameGroup, text, release_date, link, updated_at
+		FROM (
+			SELECT *, row_number() OVER (PARTITION BY lower(nameGroup) ORDER BY id) AS rn
+			FROM songs
+			WHERE lower(nameGroup) = ANY($1)
+		) s
+		WHERE rn <= $2
+		ORDER BY id
+	`
+
+	ctx, span := startSpan(ctx, "SongsByGroups", query)
+	defer func() { endSpan(span, err) }()
+
+	lower := make([]string, len(groups))
+	for i, g := range groups {
+		lower[i] = strings.ToLower(g)
+	}
+
+	rows, err := s.db.Query(ctx, query, lower, perGroup)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	songs, err = scanSongs(rows)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return songs, nil
+}
+
+// scanSongs читает все строки с колонками песни и закрывает rows
+func scanSongs(rows pgx.Rows) ([]models.Song, error) {
+	defer rows.Close()
+
+	var songs []models.Song
+	for rows.Next() {
+		var song models.Song
+		if err := rows.Scan(&song.ID, &song.Song, &song.Group, &song.Text, &song.ReleaseDate, &song.Link, &song.UpdatedAt); err != nil {
+			return nil, err
+		}
+		songs = append(songs, song)
+	}
+	return songs, rows.Err()
+}
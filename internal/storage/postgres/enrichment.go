@@ -0,0 +1,110 @@
+package postgres
+
+import (
+	"RestApi_v1/internal/config/internal/models"
+	"RestApi_v1/internal/config/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v4"
+	"time"
+)
+
+// IncompleteSongs песни без текста, даты или ссылки. Уже проверенные берутся снова,
+// только если проверка была раньше recheckBefore, а неудачная - раньше retryBefore.
+func (s *Storage) IncompleteSongs(ctx context.Context, limit int, recheckBefore time.Time, retryBefore time.Time) (songs []models.Song, err error) {
+	const op = "storage.postgres.IncompleteSongs"
+
+	query := `
+		SELECT s.id, s.song, coalesce(s.nameGroup, ''), coalesce(s.text, ''), s.release_date, coalesce(s.link, ''), s.updated_at
+		FROM songs s
+		LEFT JOIN song_enrichment e ON e.song_id = s.id
+		WHERE (coalesce(s.text, '') = '' OR s.release_date IS NULL OR coalesce(s.link, '') = '')
+		  AND (e.song_id IS NULL
+		       OR (e.status = 'failed' AND e.checked_at < $3)
+		       OR (e.status <> 'failed' AND e.checked_at < $2))
+		ORDER BY e.checked_at NULLS FIRST, s.id
+		LIMIT $1;
+	`
+
+	ctx, span := startSpan(ctx, "IncompleteSongs", query)
+	defer func() { endSpan(span, err) }()
+
+	rows, err := s.db.Query(ctx, query, limit, recheckBefore, retryBefore)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var song models.Song
+		if err = rows.Scan(&song.ID, &song.Song, &song.Group, &song.Text, &song.ReleaseDate, &song.Link, &song.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		songs = append(songs, song)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return songs, nil
+}
+
+// FillSong записывает данные только в пустые поля песни, заполненное вручную не перетирается.
+// В той же транзакции ставится вебхук song.updated.
+func (s *Storage) FillSong(ctx context.Context, id int64, details storage.SongDetails) (err error) {
+	const op = "storage.postgres.FillSong"
+
+	query := `
+		UPDATE songs SET
+			text = CASE WHEN coalesce(text, '') = '' AND $2 <> '' THEN $2 ELSE text END,
+			release_date = coalesce(release_date, $3::date),
+			link = CASE WHEN coalesce(link, '') = '' AND $4 <> '' THEN $4 ELSE link END
+		WHERE id = $1;
+	`
+
+	ctx, span := startSpan(ctx, "FillSong", query)
+	defer func() { endSpan(span, err) }()
+
+	err = s.db.BeginFunc(ctx, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, query, id, details.Text, nullIfEmpty(details.Date), details.Link)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return storage.ErrSongNotFound
+		}
+		return enqueueSongWebhook(ctx, tx, models.WebhookSongUpdated, id)
+	})
+	if errors.Is(err, storage.ErrSongNotFound) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// RecordEnrichment сохраняет итог проверки песни
+func (s *Storage) RecordEnrichment(ctx context.Context, songID int64, outcome storage.EnrichmentOutcome) (err error) {
+	const op = "storage.postgres.RecordEnrichment"
+
+	query := `
+		INSERT INTO song_enrichment (song_id, status, filled, error, attempts, checked_at)
+		VALUES ($1, $2, $3, $4, 1, now())
+		ON CONFLICT (song_id) DO UPDATE SET
+			status = EXCLUDED.status,
+			filled = EXCLUDED.filled,
+			error = EXCLUDED.error,
+			attempts = song_enrichment.attempts + 1,
+			checked_at = EXCLUDED.checked_at;
+	`
+
+	ctx, span := startSpan(ctx, "RecordEnrichment", query)
+	defer func() { endSpan(span, err) }()
+
+	if _, err = s.db.Exec(ctx, query, songID, outcome.Status, outcome.Filled, outcome.Error); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package postgres
+
+import (
+	"RestApi_v1/internal/config/internal/models"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// songSnapshot строка songs в виде to_jsonb, как ее пишет триггер songs_events
+type songSnapshot struct {
+	ID          int64     `json:"id"`
+	Song        *string   `json:"song"`
+	Group       *string   `json:"namegroup"`
+	Text        *string   `json:"text"`
+	ReleaseDate *string   `json:"release_date"`
+	Link        *string   `json:"link"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func (s songSnapshot) song() models.Song {
+	song := models.Song{
+		ID:        s.ID,
+		Song:      deref(s.Song),
+		Group:     deref(s.Group),
+		Text:      deref(s.Text),
+		Link:      deref(s.Link),
+		UpdatedAt: s.UpdatedAt,
+	}
+	if s.ReleaseDate != nil {
+		if t, err := time.Parse("2006-01-02", *s.ReleaseDate); err == nil {
+			song.ReleaseDate = sql.NullTime{Time: t, Valid: true}
+		}
+	}
+	return song
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// SongEventsAfter возвращает до limit событий журнала с id больше afterID по возрастанию id
+func (s *Storage) SongEventsAfter(ctx context.Context, afterID int64, limit int) (events []models.SongEvent, err error) {
+	const op = "storage.postgres.SongEventsAfter"
+
+	query := `SELECT id, op, song_id, coalesce(song_group, ''), snapshot, created_at
+			  FROM song_events WHERE id > $1 ORDER BY id LIMIT $2`
+
+	ctx, span := startSpan(ctx, "SongEventsAfter", query)
+	defer func() { endSpan(span, err) }()
+
+	rows, err := s.db.Query(ctx, query, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			event    models.SongEvent
+			snapshot []byte
+		)
+		if err = rows.Scan(&event.ID, &event.Op, &event.SongID, &event.Group, &snapshot, &event.CreatedAt); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		var row songSnapshot
+		if err = json.Unmarshal(snapshot, &row); err != nil {
+			return nil, fmt.Errorf("%s: event %d: %w", op, event.ID, err)
+		}
+		event.Song = row.song()
+
+		events = append(events, event)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return events, nil
+}
+
+// SongEventBounds id первого и последнего событий в журнале, 0 и 0 для пустого журнала
+func (s *Storage) SongEventBounds(ctx context.Context) (first int64, last int64, err error) {
+	const op = "storage.postgres.SongEventBounds"
+
+	query := `SELECT coalesce(min(id), 0), coalesce(max(id), 0) FROM song_events`
+
+	ctx, span := startSpan(ctx, "SongEventBounds", query)
+	defer func() { endSpan(span, err) }()
+
+	if err = s.db.QueryRow(ctx, query).Scan(&first, &last); err != nil {
+		return 0, 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return first, last, nil
+}
+
+// TrimSongEvents оставляет в журнале не больше keep последних событий и не старше before
+func (s *Storage) TrimSongEvents(ctx context.Context, keep int, before time.Time) (deleted int64, err error) {
+	const op = "storage.postgres.TrimSongEvents"
+
+	query := `DELETE FROM song_events
+			  WHERE created_at < $2
+			     OR id <= (SELECT max(id) FROM song_events) - $1`
+
+	ctx, span := startSpan(ctx, "TrimSongEvents", query)
+	defer func() { endSpan(span, err) }()
+
+	tag, err := s.db.Exec(ctx, query, keep, before)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return tag.RowsAffected(), nil
+}
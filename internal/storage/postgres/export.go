@@ -0,0 +1,151 @@
+package postgres
+
+import (
+	"RestApi_v1/internal/config/internal/models"
+	"RestApi_v1/internal/config/internal/storage"
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v4"
+	"strings"
+)
+
+// songFilterSQL собирает WHERE по фильтру, параметры нумеруются с $1
+func songFilterSQL(filter storage.SongFilter) (string, []any) {
+	var (
+		conds []string
+		args  []any
+	)
+
+	add := func(cond string, arg any) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+
+	if filter.Group != "" {
+		add("lower(nameGroup) = lower($%d)", filter.Group)
+	}
+	if filter.Song != "" {
+		add(`song ILIKE $%d ESCAPE '\'`, likeContains(filter.Song))
+	}
+	if filter.Query != "" {
+		add(`(song ILIKE $%[1]d ESCAPE '\' OR nameGroup ILIKE $%[1]d ESCAPE '\' OR text ILIKE $%[1]d ESCAPE '\')`, likeContains(filter.Query))
+	}
+	if filter.ReleasedFrom != nil {
+		add("release_date >= $%d", *filter.ReleasedFrom)
+	}
+	if filter.ReleasedTo != nil {
+		add("release_date <= $%d", *filter.ReleasedTo)
+	}
+	if filter.AfterID > 0 {
+		add("id > $%d", filter.AfterID)
+	}
+
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// likeContains шаблон ILIKE "содержит s": %, _ и \ из фильтра ищутся как обычные символы
+func likeContains(s string) string {
+	return "%" + likeEscaper.Replace(s) + "%"
+}
+
+// CountSongs число песен по фильтру
+func (s *Storage) CountSongs(ctx context.Context, filter storage.SongFilter) (count int, err error) {
+	const op = "storage.postgres.CountSongs"
+
+	where, args := songFilterSQL(filter)
+	query := `SELECT count(*) FROM songs` + where
+
+	ctx, span := startSpan(ctx, "CountSongs", query)
+	defer func() { endSpan(span, err) }()
+
+	if err = s.db.QueryRow(ctx, query, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return count, nil
+}
+
+// ListSongs страница песен по фильтру в порядке id, следующая страница - с AfterID последней песни
+func (s *Storage) ListSongs(ctx context.Context, filter storage.SongFilter, limit int) (songs []models.Song, err error) {
+	const op = "storage.postgres.ListSongs"
+
+	where, args := songFilterSQL(filter)
+	query := `SELECT id, song, nameGroup, text, release_date, link, updated_at FROM songs` + where +
+		fmt.Sprintf(` ORDER BY id LIMIT %d`, limit)
+
+	ctx, span := startSpan(ctx, "ListSongs", query)
+	defer func() { endSpan(span, err) }()
+
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	songs, err = scanSongs(rows)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return songs, nil
+}
+
+// ExportSongs читает песни по фильтру через серверный курсор порциями по batchSize
+// и передает каждую в fn. Ошибка fn или отмена ctx прерывают выгрузку.
+func (s *Storage) ExportSongs(ctx context.Context, filter storage.SongFilter, batchSize int, fn func(models.Song) error) (err error) {
+	const op = "storage.postgres.ExportSongs"
+
+	where, args := songFilterSQL(filter)
+	query := `SELECT id, song, nameGroup, text, release_date, link, updated_at FROM songs` + where + ` ORDER BY id`
+
+	ctx, span := startSpan(ctx, "ExportSongs", query)
+	defer func() { endSpan(span, err) }()
+
+	// курсор живет только внутри транзакции
+	err = s.db.BeginFunc(ctx, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, "DECLARE songs_export NO SCROLL CURSOR FOR "+query, args...); err != nil {
+			return fmt.Errorf("failed to declare cursor: %w", err)
+		}
+
+		fetch := fmt.Sprintf("FETCH FORWARD %d FROM songs_export", batchSize)
+
+		for {
+			n, err := fetchSongs(ctx, tx, fetch, fn)
+			if err != nil {
+				return err
+			}
+			if n < batchSize {
+				return nil
+			}
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func fetchSongs(ctx context.Context, tx pgx.Tx, fetch string, fn func(models.Song) error) (int, error) {
+	rows, err := tx.Query(ctx, fetch)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch from cursor: %w", err)
+	}
+	defer rows.Close()
+
+	n := 0
+	for rows.Next() {
+		var song models.Song
+		if err := rows.Scan(&song.ID, &song.Song, &song.Group, &song.Text, &song.ReleaseDate, &song.Link, &song.UpdatedAt); err != nil {
+			return n, err
+		}
+		n++
+
+		if err := fn(song); err != nil {
+			return n, err
+		}
+	}
+
+	return n, rows.Err()
+}
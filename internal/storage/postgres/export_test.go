@@ -0,0 +1,50 @@
+package postgres
+
+import (
+	"RestApi_v1/internal/config/internal/storage"
+	"reflect"
+	"testing"
+)
+
+func TestSongFilterSQL(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter storage.SongFilter
+		where  string
+		args   []any
+	}{
+		{
+			name: "empty filter",
+		},
+		{
+			name:   "plain substring",
+			filter: storage.SongFilter{Song: "hyst"},
+			where:  ` WHERE song ILIKE $1 ESCAPE '\'`,
+			args:   []any{"%hyst%"},
+		},
+		{
+			name:   "wildcards are literal",
+			filter: storage.SongFilter{Song: `100%_a\b`},
+			where:  ` WHERE song ILIKE $1 ESCAPE '\'`,
+			args:   []any{`%100\%\_a\\b%`},
+		},
+		{
+			name:   "query matches any column with one parameter",
+			filter: storage.SongFilter{Group: "Muse", Query: "_"},
+			where:  ` WHERE lower(nameGroup) = lower($1) AND (song ILIKE $2 ESCAPE '\' OR nameGroup ILIKE $2 ESCAPE '\' OR text ILIKE $2 ESCAPE '\')`,
+			args:   []any{"Muse", `%\_%`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			where, args := songFilterSQL(tt.filter)
+			if where != tt.where {
+				t.Errorf("where = %q, want %q", where, tt.where)
+			}
+			if !reflect.DeepEqual(args, tt.args) {
+				t.Errorf("args = %#v, want %#v", args, tt.args)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package postgres
+
+import (
+	"RestApi_v1/internal/config/internal/storage"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/jackc/pgx/v4"
+	"time"
+)
+
+// ReserveIdempotencyKey занимает ключ под новый запрос. Если ключ уже занят и не истек,
+// возвращает сохраненную запись и reserved=false.
+func (s *Storage) ReserveIdempotencyKey(ctx context.Context, scope string, key string, requestHash string, ttl time.Duration) (record storage.IdempotencyRecord, reserved bool, err error) {
+	const op = "storage.postgres.ReserveIdempotencyKey"
+
+	insert := `
+		INSERT INTO idempotency_keys (scope, key, request_hash, expires_at)
+		VALUES ($1, $2, $3, now() + $4 * interval '1 second')
+		ON CONFLICT (scope, key) DO NOTHING;
+	`
+
+	ctx, span := startSpan(ctx, "ReserveIdempotencyKey", insert)
+	defer func() { endSpan(span, err) }()
+
+	err = s.db.BeginFunc(ctx, func(tx pgx.Tx) error {
+		// истекший ключ можно использовать заново
+		_, err := tx.Exec(ctx, `DELETE FROM idempotency_keys WHERE scope = $1 AND key = $2 AND expires_at < now()`, scope, key)
+		if err != nil {
+			return err
+		}
+
+		tag, err := tx.Exec(ctx, insert, scope, key, requestHash, ttl.Seconds())
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 1 {
+			reserved = true
+			record = storage.IdempotencyRecord{RequestHash: requestHash}
+			return nil
+		}
+
+		var header []byte
+		err = tx.QueryRow(ctx, `
+			SELECT request_hash, completed, status, header, body
+			FROM idempotency_keys WHERE scope = $1 AND key = $2
+		`, scope, key).Scan(&record.RequestHash, &record.Completed, &record.Status, &header, &record.Body)
+		if err != nil {
+			return err
+		}
+		if len(header) > 0 {
+			return json.Unmarshal(header, &record.Header)
+		}
+		return nil
+	})
+	if err != nil {
+		return record, false, fmt.Errorf("%s: %w", op, err)
+	}
+	return record, reserved, nil
+}
+
+// CompleteIdempotencyKey сохраняет ответ на запрос, чтобы отдавать его на повторы
+func (s *Storage) CompleteIdempotencyKey(ctx context.Context, scope string, key string, status int, header map[string][]string, body []byte) (err error) {
+	const op = "storage.postgres.CompleteIdempotencyKey"
+
+	query := `UPDATE idempotency_keys SET completed = true, status = $3, header = $4, body = $5
+			  WHERE scope = $1 AND key = $2`
+
+	ctx, span := startSpan(ctx, "CompleteIdempotencyKey", query)
+	defer func() { endSpan(span, err) }()
+
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err = s.db.Exec(ctx, query, scope, key, status, headerJSON, body); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// DeleteIdempotencyKey освобождает ключ, если запрос не удался и его можно повторить
+func (s *Storage) DeleteIdempotencyKey(ctx context.Context, scope string, key string) (err error) {
+	const op = "storage.postgres.DeleteIdempotencyKey"
+
+	query := `DELETE FROM idempotency_keys WHERE scope = $1 AND key = $2`
+
+	ctx, span := startSpan(ctx, "DeleteIdempotencyKey", query)
+	defer func() { endSpan(span, err) }()
+
+	if _, err = s.db.Exec(ctx, query, scope, key); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// PurgeIdempotencyKeys удаляет истекшие ключи
+func (s *Storage) PurgeIdempotencyKeys(ctx context.Context) (deleted int64, err error) {
+	const op = "storage.postgres.PurgeIdempotencyKeys"
+
+	query := `DELETE FROM idempotency_keys WHERE expires_at < now()`
+
+	ctx, span := startSpan(ctx, "PurgeIdempotencyKeys", query)
+	defer func() { endSpan(span, err) }()
+
+	tag, err := s.db.Exec(ctx, query)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,114 @@
+package postgres
+
+import (
+	"RestApi_v1/internal/config/internal/models"
+	"RestApi_v1/internal/config/internal/storage"
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v4"
+)
+
+// ImportSongs загружает пачку песен через COPY во временную таблицу и один INSERT ... ON CONFLICT.
+// Песни с уже существующим названием не вставляются и возвращаются с Created=false.
+// При atomic=true любой дубликат откатывает всю пачку, committed покажет, сохранено ли что-то.
+// На каждую созданную песню в той же транзакции ставится вебхук song.created.
+func (s *Storage) ImportSongs(ctx context.Context, songs []storage.ImportSong, atomic bool) (results []storage.ImportResult, committed bool, err error) {
+	const op = "storage.postgres.ImportSongs"
+
+	insert := `
+		INSERT INTO songs (song, nameGroup, text, release_date, link)
+		SELECT song, nameGroup, text, release_date, link FROM songs_import ORDER BY row_num
+		ON CONFLICT (song) DO NOTHING
+		RETURNING id, song;
+	`
+
+	ctx, span := startSpan(ctx, "ImportSongs", insert)
+	defer func() { endSpan(span, err) }()
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	_, err = tx.Exec(ctx, `
+		CREATE TEMP TABLE songs_import (
+			row_num INTEGER,
+			song VARCHAR(100),
+			nameGroup VARCHAR(50),
+			text VARCHAR,
+			release_date DATE,
+			link VARCHAR(255)
+		) ON COMMIT DROP;
+	`)
+	if err != nil {
+		return nil, false, fmt.Errorf("%s: failed to create temp table: %w", op, err)
+	}
+
+	_, err = tx.CopyFrom(ctx,
+		pgx.Identifier{"songs_import"},
+		[]string{"row_num", "song", "namegroup", "text", "release_date", "link"},
+		pgx.CopyFromSlice(len(songs), func(i int) ([]any, error) {
+			song := songs[i]
+			return []any{song.Row, song.Song, song.Group, song.Text, nullIfEmpty(song.Date), song.Link}, nil
+		}),
+	)
+	if err != nil {
+		return nil, false, fmt.Errorf("%s: failed to copy songs: %w", op, err)
+	}
+
+	rows, err := tx.Query(ctx, insert)
+	if err != nil {
+		return nil, false, fmt.Errorf("%s: failed to insert songs: %w", op, err)
+	}
+
+	created := make(map[string]int64)
+	for rows.Next() {
+		var (
+			id   int64
+			song string
+		)
+		if err := rows.Scan(&id, &song); err != nil {
+			rows.Close()
+			return nil, false, fmt.Errorf("%s: %w", op, err)
+		}
+		created[song] = id
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, false, fmt.Errorf("%s: failed to insert songs: %w", op, err)
+	}
+
+	// одно и то же название может встретиться в пачке несколько раз - создана только первая строка
+	results = make([]storage.ImportResult, 0, len(songs))
+	duplicates := 0
+	for _, song := range songs {
+		id, ok := created[song.Song]
+		if ok {
+			delete(created, song.Song)
+			results = append(results, storage.ImportResult{Row: song.Row, ID: id, Created: true})
+			continue
+		}
+		duplicates++
+		results = append(results, storage.ImportResult{Row: song.Row})
+	}
+
+	if atomic && duplicates > 0 {
+		return results, false, nil
+	}
+
+	ids := make([]int64, 0, len(results)-duplicates)
+	for _, result := range results {
+		if result.Created {
+			ids = append(ids, result.ID)
+		}
+	}
+	if err := enqueueSongWebhooks(ctx, tx, models.WebhookSongCreated, ids); err != nil {
+		return nil, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, false, fmt.Errorf("%s: failed to commit: %w", op, err)
+	}
+	return results, true, nil
+}
@@ -0,0 +1,332 @@
+package postgres
+
+import (
+	"RestApi_v1/internal/config/internal/models"
+	"RestApi_v1/internal/config/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v4"
+	"io"
+	"time"
+)
+
+// jobChunkSize размер части входного файла задачи, которая пишется одной строкой job_payload_chunks
+const jobChunkSize = 1 << 20
+
+// CreateJob ставит задачу в очередь. Входной файл читается из job.Payload частями
+// по jobChunkSize в той же транзакции, целиком в памяти он не собирается.
+func (s *Storage) CreateJob(ctx context.Context, job storage.NewJob) (id int64, err error) {
+	const op = "storage.postgres.CreateJob"
+
+	query := `INSERT INTO jobs (kind, params, payload_type, max_attempts, created_by)
+			  VALUES ($1, $2, $3, $4, $5) RETURNING id`
+
+	ctx, span := startSpan(ctx, "CreateJob", query)
+	defer func() { endSpan(span, err) }()
+
+	err = s.db.BeginFunc(ctx, func(tx pgx.Tx) error {
+		if err := tx.QueryRow(ctx, query, job.Kind, job.Params, job.PayloadType, job.MaxAttempts, job.CreatedBy).Scan(&id); err != nil {
+			return err
+		}
+		if job.Payload == nil {
+			return nil
+		}
+
+		buf := make([]byte, jobChunkSize)
+		for seq := 0; ; seq++ {
+			n, err := io.ReadFull(job.Payload, buf)
+			if n > 0 {
+				if _, err := tx.Exec(ctx, "INSERT INTO job_payload_chunks (job_id, seq, data) VALUES ($1, $2, $3)", id, seq, buf[:n]); err != nil {
+					return fmt.Errorf("failed to save payload: %w", err)
+				}
+			}
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("failed to read payload: %w", err)
+			}
+		}
+	})
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return id, nil
+}
+
+// JobByID возвращает состояние задачи без входных данных и результата
+func (s *Storage) JobByID(ctx context.Context, id int64) (job models.Job, err error) {
+	const op = "storage.postgres.JobByID"
+
+	query := `SELECT id, kind, status, params, payload_type, attempts, max_attempts, processed, total,
+			  checkpoint, result_type, result_size, error, created_by, run_at, created_at, updated_at, started_at, finished_at
+			  FROM jobs WHERE id = $1`
+
+	ctx, span := startSpan(ctx, "JobByID", query)
+	defer func() { endSpan(span, err) }()
+
+	err = s.db.QueryRow(ctx, query, id).Scan(
+		&job.ID, &job.Kind, &job.Status, &job.Params, &job.PayloadType, &job.Attempts, &job.MaxAttempts,
+		&job.Processed, &job.Total, &job.Checkpoint, &job.ResultType, &job.ResultSize, &job.Error, &job.CreatedBy,
+		&job.RunAt, &job.CreatedAt, &job.UpdatedAt, &job.StartedAt, &job.FinishedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return job, storage.ErrJobNotFound
+	}
+	if err != nil {
+		return job, fmt.Errorf("%s: %w", op, err)
+	}
+	return job, nil
+}
+
+// JobResult отдает результат задачи в fn по частям в порядке записи.
+// Части читаются курсором, так что в памяти одновременно держится только одна.
+func (s *Storage) JobResult(ctx context.Context, id int64, fn func(chunk []byte) error) (err error) {
+	const op = "storage.postgres.JobResult"
+
+	query := `SELECT data FROM job_result_chunks WHERE job_id = $1 ORDER BY seq`
+
+	ctx, span := startSpan(ctx, "JobResult", query)
+	defer func() { endSpan(span, err) }()
+
+	if err = s.readChunks(ctx, "job_result", query, id, fn); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// JobPayload отдает входной файл задачи в fn по частям, так же как JobResult
+func (s *Storage) JobPayload(ctx context.Context, id int64, fn func(chunk []byte) error) (err error) {
+	const op = "storage.postgres.JobPayload"
+
+	query := `SELECT data FROM job_payload_chunks WHERE job_id = $1 ORDER BY seq`
+
+	ctx, span := startSpan(ctx, "JobPayload", query)
+	defer func() { endSpan(span, err) }()
+
+	if err = s.readChunks(ctx, "job_payload", query, id, fn); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// readChunks читает части курсором name по одной, курсор живет только внутри транзакции
+func (s *Storage) readChunks(ctx context.Context, name string, query string, id int64, fn func(chunk []byte) error) error {
+	return s.db.BeginFunc(ctx, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, "DECLARE "+name+" NO SCROLL CURSOR FOR "+query, id); err != nil {
+			return fmt.Errorf("failed to declare cursor: %w", err)
+		}
+
+		fetch := "FETCH NEXT FROM " + name
+		for {
+			var chunk []byte
+			err := tx.QueryRow(ctx, fetch).Scan(&chunk)
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("failed to fetch from cursor: %w", err)
+			}
+			if err := fn(chunk); err != nil {
+				return err
+			}
+		}
+	})
+}
+
+// appendResult дописывает часть результата задачи следующей по номеру строкой job_result_chunks
+func appendResult(ctx context.Context, tx pgx.Tx, id int64, chunk []byte) error {
+	if len(chunk) == 0 {
+		return nil
+	}
+
+	query := `INSERT INTO job_result_chunks (job_id, seq, data)
+			  SELECT $1, coalesce(max(seq) + 1, 0), $2 FROM job_result_chunks WHERE job_id = $1`
+
+	if _, err := tx.Exec(ctx, query, id, chunk); err != nil {
+		return fmt.Errorf("failed to save result: %w", err)
+	}
+	return nil
+}
+
+// ClaimJob забирает первую готовую к запуску задачу и берет ее в аренду на lease.
+// Задачи упавшего воркера с истекшей арендой забираются повторно.
+// Номер попытки (Attempts) дальше служит токеном: обновить задачу может только ее текущий владелец.
+// Если очередь пуста, возвращает storage.ErrJobNotFound.
+func (s *Storage) ClaimJob(ctx context.Context, lease time.Duration) (job models.Job, err error) {
+	const op = "storage.postgres.ClaimJob"
+
+	query := `
+		UPDATE jobs SET
+			status = 'running',
+			attempts = attempts + 1,
+			locked_until = now() + $1 * interval '1 second',
+			started_at = coalesce(started_at, now()),
+			updated_at = now()
+		WHERE id = (
+			SELECT id FROM jobs
+			WHERE (status = 'queued' AND run_at <= now())
+			   OR (status = 'running' AND locked_until < now())
+			ORDER BY run_at, id
+			FOR UPDATE SKIP LOCKED
+			LIMIT 1
+		)
+		RETURNING id, kind, status, params, payload_type, attempts, max_attempts,
+			processed, total, checkpoint, created_by, run_at, created_at, updated_at, started_at;
+	`
+
+	ctx, span := startSpan(ctx, "ClaimJob", query)
+	defer func() { endSpan(span, err) }()
+
+	err = s.db.QueryRow(ctx, query, lease.Seconds()).Scan(
+		&job.ID, &job.Kind, &job.Status, &job.Params, &job.PayloadType, &job.Attempts, &job.MaxAttempts,
+		&job.Processed, &job.Total, &job.Checkpoint, &job.CreatedBy, &job.RunAt, &job.CreatedAt, &job.UpdatedAt, &job.StartedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return job, storage.ErrJobNotFound
+	}
+	if err != nil {
+		return job, fmt.Errorf("%s: %w", op, err)
+	}
+	return job, nil
+}
+
+// CheckpointJob сохраняет прогресс задачи и продлевает аренду, часть результата пишется в той же транзакции
+func (s *Storage) CheckpointJob(ctx context.Context, id int64, attempt int, progress storage.JobProgress, lease time.Duration) (err error) {
+	const op = "storage.postgres.CheckpointJob"
+
+	query := `
+		UPDATE jobs SET
+			processed = $3,
+			total = $4,
+			checkpoint = coalesce($5::jsonb, checkpoint),
+			result_size = result_size + $6,
+			locked_until = now() + $7 * interval '1 second',
+			updated_at = now()
+		WHERE id = $1 AND attempts = $2 AND status = 'running';
+	`
+
+	ctx, span := startSpan(ctx, "CheckpointJob", query)
+	defer func() { endSpan(span, err) }()
+
+	err = s.db.BeginFunc(ctx, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, query, id, attempt, progress.Processed, progress.Total, progress.Checkpoint, len(progress.Result), lease.Seconds())
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return storage.ErrJobLost
+		}
+		return appendResult(ctx, tx, id, progress.Result)
+	})
+	if errors.Is(err, storage.ErrJobLost) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// CompleteJob сохраняет последний прогресс и завершает задачу успешно, входной файл больше не нужен и удаляется
+func (s *Storage) CompleteJob(ctx context.Context, id int64, attempt int, progress storage.JobProgress) (err error) {
+	const op = "storage.postgres.CompleteJob"
+
+	query := `
+		UPDATE jobs SET
+			status = 'succeeded',
+			processed = $3,
+			total = $4,
+			checkpoint = coalesce($5::jsonb, checkpoint),
+			result_size = result_size + $6,
+			result_type = $7,
+			error = '',
+			locked_until = NULL,
+			updated_at = now(),
+			finished_at = now()
+		WHERE id = $1 AND attempts = $2 AND status = 'running';
+	`
+
+	ctx, span := startSpan(ctx, "CompleteJob", query)
+	defer func() { endSpan(span, err) }()
+
+	err = s.db.BeginFunc(ctx, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, query, id, attempt, progress.Processed, progress.Total, progress.Checkpoint, len(progress.Result), progress.ResultType)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return storage.ErrJobLost
+		}
+		if err := appendResult(ctx, tx, id, progress.Result); err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, "DELETE FROM job_payload_chunks WHERE job_id = $1", id)
+		return err
+	})
+	if errors.Is(err, storage.ErrJobLost) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// FailJob записывает ошибку попытки. Если retryAt не nil, задача вернется в очередь к этому времени,
+// иначе завершится со статусом failed. Сохраненный прогресс не сбрасывается.
+func (s *Storage) FailJob(ctx context.Context, id int64, attempt int, reason string, retryAt *time.Time) (err error) {
+	const op = "storage.postgres.FailJob"
+
+	query := `
+		UPDATE jobs SET
+			status = CASE WHEN $4::timestamptz IS NULL THEN 'failed' ELSE 'queued' END,
+			run_at = coalesce($4::timestamptz, run_at),
+			finished_at = CASE WHEN $4::timestamptz IS NULL THEN now() END,
+			error = $3,
+			locked_until = NULL,
+			updated_at = now()
+		WHERE id = $1 AND attempts = $2 AND status = 'running';
+	`
+
+	ctx, span := startSpan(ctx, "FailJob", query)
+	defer func() { endSpan(span, err) }()
+
+	tag, err := s.db.Exec(ctx, query, id, attempt, reason, retryAt)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return storage.ErrJobLost
+	}
+	return nil
+}
+
+// ReleaseJob возвращает прерванную задачу в очередь с сохраненным прогрессом.
+// Прерывание при остановке сервиса попыткой не считается.
+func (s *Storage) ReleaseJob(ctx context.Context, id int64, attempt int) (err error) {
+	const op = "storage.postgres.ReleaseJob"
+
+	query := `
+		UPDATE jobs SET
+			status = 'queued',
+			attempts = attempts - 1,
+			run_at = now(),
+			locked_until = NULL,
+			updated_at = now()
+		WHERE id = $1 AND attempts = $2 AND status = 'running';
+	`
+
+	ctx, span := startSpan(ctx, "ReleaseJob", query)
+	defer func() { endSpan(span, err) }()
+
+	tag, err := s.db.Exec(ctx, query, id, attempt)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return storage.ErrJobLost
+	}
+	return nil
+}
@@ -0,0 +1,413 @@
+package postgres
+
+import (
+	"RestApi_v1/internal/config/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+)
+
+type migration struct {
+	version int
+	name    string
+	stmt    string
+}
+
+// migrations применяются по порядку, уже примененные повторно не выполняются.
+// Новые миграции добавляются только в конец списка.
+var migrations = []migration{
+	{
+		version: 1,
+		name:    "create songs table",
+		stmt: `
+			CREATE TABLE IF NOT EXISTS songs (
+				id SERIAL PRIMARY KEY,
+				nameGroup VARCHAR(50),
+				song VARCHAR(100) UNIQUE,
+				text VARCHAR,
+				release_date DATE,
+				link VARCHAR(255)
+			);
+		`,
+	},
+	{
+		version: 2,
+		name:    "create songs index",
+		stmt:    `CREATE INDEX IF NOT EXISTS idx_song ON songs(song);`,
+	},
+	{
+		version: 3,
+		name:    "create api_keys table",
+		stmt: `
+			CREATE TABLE IF NOT EXISTS api_keys (
+				id SERIAL PRIMARY KEY,
+				name VARCHAR(100) NOT NULL,
+				key_hash CHAR(64) NOT NULL UNIQUE,
+				role VARCHAR(16) NOT NULL,
+				created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+				revoked_at TIMESTAMPTZ
+			);
+		`,
+	},
+	{
+		version: 4,
+		name:    "create rate_limits table",
+		stmt: `
+			CREATE TABLE IF NOT EXISTS rate_limits (
+				key VARCHAR(255) PRIMARY KEY,
+				tokens DOUBLE PRECISION NOT NULL,
+				allowed BOOLEAN NOT NULL,
+				updated_at TIMESTAMPTZ NOT NULL
+			);
+		`,
+	},
+	{
+		version: 5,
+		name:    "create jobs tables",
+		stmt: `
+			CREATE TABLE IF NOT EXISTS jobs (
+				id BIGSERIAL PRIMARY KEY,
+				kind VARCHAR(32) NOT NULL,
+				status VARCHAR(16) NOT NULL DEFAULT 'queued',
+				params JSONB NOT NULL DEFAULT '{}',
+				payload_type VARCHAR(100) NOT NULL DEFAULT '',
+				attempts INTEGER NOT NULL DEFAULT 0,
+				max_attempts INTEGER NOT NULL,
+				processed INTEGER NOT NULL DEFAULT 0,
+				total INTEGER NOT NULL DEFAULT 0,
+				checkpoint JSONB,
+				result_type VARCHAR(100) NOT NULL DEFAULT '',
+				result_size BIGINT NOT NULL DEFAULT 0,
+				error TEXT NOT NULL DEFAULT '',
+				created_by VARCHAR(255) NOT NULL DEFAULT '',
+				run_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+				locked_until TIMESTAMPTZ,
+				created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+				updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+				started_at TIMESTAMPTZ,
+				finished_at TIMESTAMPTZ
+			);
+			CREATE TABLE IF NOT EXISTS job_payload_chunks (
+				job_id BIGINT NOT NULL REFERENCES jobs(id) ON DELETE CASCADE,
+				seq INTEGER NOT NULL,
+				data BYTEA NOT NULL,
+				PRIMARY KEY (job_id, seq)
+			);
+			CREATE TABLE IF NOT EXISTS job_result_chunks (
+				job_id BIGINT NOT NULL REFERENCES jobs(id) ON DELETE CASCADE,
+				seq INTEGER NOT NULL,
+				data BYTEA NOT NULL,
+				PRIMARY KEY (job_id, seq)
+			);
+		`,
+	},
+	{
+		version: 6,
+		name:    "create jobs queue index",
+		stmt:    `CREATE INDEX IF NOT EXISTS idx_jobs_queue ON jobs(run_at) WHERE status IN ('queued', 'running');`,
+	},
+	{
+		version: 7,
+		name:    "create song_enrichment table",
+		stmt: `
+			CREATE TABLE IF NOT EXISTS song_enrichment (
+				song_id INTEGER PRIMARY KEY REFERENCES songs(id) ON DELETE CASCADE,
+				status VARCHAR(16) NOT NULL,
+				filled VARCHAR(64) NOT NULL DEFAULT '',
+				error TEXT NOT NULL DEFAULT '',
+				attempts INTEGER NOT NULL DEFAULT 0,
+				checked_at TIMESTAMPTZ NOT NULL
+			);
+		`,
+	},
+	{
+		version: 8,
+		name:    "create idempotency_keys table",
+		stmt: `
+			CREATE TABLE IF NOT EXISTS idempotency_keys (
+				scope VARCHAR(512) NOT NULL,
+				key VARCHAR(255) NOT NULL,
+				request_hash CHAR(64) NOT NULL,
+				completed BOOLEAN NOT NULL DEFAULT false,
+				status INTEGER NOT NULL DEFAULT 0,
+				header JSONB,
+				body BYTEA,
+				created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+				expires_at TIMESTAMPTZ NOT NULL,
+				PRIMARY KEY (scope, key)
+			);
+		`,
+	},
+	{
+		version: 9,
+		name:    "create idempotency_keys expiry index",
+		stmt:    `CREATE INDEX IF NOT EXISTS idx_idempotency_expires ON idempotency_keys(expires_at);`,
+	},
+	{
+		version: 10,
+		name:    "create notify_song_change function",
+		stmt: `
+			CREATE OR REPLACE FUNCTION notify_song_change() RETURNS trigger AS $$
+			BEGIN
+				IF TG_OP = 'DELETE' THEN
+					PERFORM pg_notify('songs_changed', json_build_object('op', TG_OP, 'id', OLD.id, 'song', OLD.song)::text);
+					RETURN OLD;
+				END IF;
+				PERFORM pg_notify('songs_changed', json_build_object('op', TG_OP, 'id', NEW.id, 'song', NEW.song)::text);
+				RETURN NEW;
+			END;
+			$$ LANGUAGE plpgsql;
+		`,
+	},
+	{
+		version: 11,
+		name:    "create songs_changed trigger",
+		stmt: `
+			CREATE TRIGGER songs_changed
+			AFTER INSERT OR UPDATE OR DELETE ON songs
+			FOR EACH ROW EXECUTE FUNCTION notify_song_change();
+		`,
+	},
+	{
+		version: 12,
+		name:    "add songs.updated_at",
+		stmt:    `ALTER TABLE songs ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT now();`,
+	},
+	{
+		version: 13,
+		name:    "create touch_updated_at function",
+		stmt: `
+			CREATE OR REPLACE FUNCTION touch_updated_at() RETURNS trigger AS $$
+			BEGIN
+				NEW.updated_at = now();
+				RETURN NEW;
+			END;
+			$$ LANGUAGE plpgsql;
+		`,
+	},
+	{
+		version: 14,
+		name:    "create songs_touch_updated_at trigger",
+		stmt: `
+			CREATE TRIGGER songs_touch_updated_at
+			BEFORE UPDATE ON songs
+			FOR EACH ROW EXECUTE FUNCTION touch_updated_at();
+		`,
+	},
+	{
+		version: 15,
+		name:    "create song_events table",
+		stmt: `
+			CREATE TABLE IF NOT EXISTS song_events (
+				id BIGSERIAL PRIMARY KEY,
+				op VARCHAR(8) NOT NULL,
+				song_id BIGINT NOT NULL,
+				song_group VARCHAR(50),
+				snapshot JSONB NOT NULL,
+				created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+			);
+		`,
+	},
+	{
+		version: 16,
+		name:    "create record_song_event function",
+		stmt: `
+			CREATE OR REPLACE FUNCTION record_song_event() RETURNS trigger AS $$
+			DECLARE
+				event_id BIGINT;
+			BEGIN
+				IF TG_OP = 'DELETE' THEN
+					INSERT INTO song_events (op, song_id, song_group, snapshot)
+					VALUES (TG_OP, OLD.id, OLD.nameGroup, to_jsonb(OLD))
+					RETURNING id INTO event_id;
+				ELSE
+					INSERT INTO song_events (op, song_id, song_group, snapshot)
+					VALUES (TG_OP, NEW.id, NEW.nameGroup, to_jsonb(NEW))
+					RETURNING id INTO event_id;
+				END IF;
+				PERFORM pg_notify('song_events', event_id::text);
+				RETURN NULL;
+			END;
+			$$ LANGUAGE plpgsql;
+		`,
+	},
+	{
+		version: 17,
+		name:    "create songs_events trigger",
+		stmt: `
+			CREATE TRIGGER songs_events
+			AFTER INSERT OR UPDATE OR DELETE ON songs
+			FOR EACH ROW EXECUTE FUNCTION record_song_event();
+		`,
+	},
+	{
+		version: 18,
+		name:    "create webhook_subscriptions table",
+		stmt: `
+			CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+				id BIGSERIAL PRIMARY KEY,
+				url TEXT NOT NULL,
+				events TEXT[] NOT NULL,
+				secret TEXT NOT NULL,
+				created_by VARCHAR(255) NOT NULL DEFAULT '',
+				created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+			);
+		`,
+	},
+	{
+		version: 19,
+		name:    "create webhook_outbox table",
+		stmt: `
+			CREATE TABLE IF NOT EXISTS webhook_outbox (
+				id BIGSERIAL PRIMARY KEY,
+				event_type VARCHAR(32) NOT NULL,
+				song_id BIGINT NOT NULL,
+				song JSONB NOT NULL,
+				created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+				dispatched_at TIMESTAMPTZ
+			);
+		`,
+	},
+	{
+		version: 20,
+		name:    "create webhook_deliveries table",
+		stmt: `
+			CREATE TABLE IF NOT EXISTS webhook_deliveries (
+				id BIGSERIAL PRIMARY KEY,
+				subscription_id BIGINT NOT NULL REFERENCES webhook_subscriptions(id) ON DELETE CASCADE,
+				outbox_id BIGINT NOT NULL,
+				event_type VARCHAR(32) NOT NULL,
+				payload BYTEA NOT NULL,
+				status VARCHAR(16) NOT NULL DEFAULT 'pending',
+				attempts INT NOT NULL DEFAULT 0,
+				next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+				locked_until TIMESTAMPTZ,
+				last_status_code INT NOT NULL DEFAULT 0,
+				last_error TEXT NOT NULL DEFAULT '',
+				last_attempt_at TIMESTAMPTZ,
+				created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+				delivered_at TIMESTAMPTZ,
+				UNIQUE (subscription_id, outbox_id)
+			);
+		`,
+	},
+	{
+		version: 21,
+		name:    "create idx_webhook_outbox_pending",
+		stmt:    `CREATE INDEX IF NOT EXISTS idx_webhook_outbox_pending ON webhook_outbox(id) WHERE dispatched_at IS NULL;`,
+	},
+	{
+		version: 22,
+		name:    "create idx_webhook_deliveries_queue",
+		stmt:    `CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_queue ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';`,
+	},
+	{
+		version: 23,
+		name:    "create idx_songs_group",
+		// песни группы ищутся без учета регистра: фильтр group и GraphQL Group.songs
+		stmt: `CREATE INDEX IF NOT EXISTS idx_songs_group ON songs(lower(nameGroup), id);`,
+	},
+}
+
+func latestVersion() int {
+	if len(migrations) == 0 {
+		return 0
+	}
+	return migrations[len(migrations)-1].version
+}
+
+// migrationLockKey ключ pg_advisory_xact_lock, под которым реплики применяют миграции по очереди
+const migrationLockKey = 0x736f6e6773 // "songs"
+
+// Migrate применяет все недостающие миграции, каждую в своей транзакции.
+// Реплики стартуют одновременно, поэтому каждая миграция применяется под общей блокировкой,
+// а версия перечитывается уже под ней: опоздавшая реплика пропустит примененное другой.
+func (s *Storage) Migrate(ctx context.Context) error {
+	const op = "storage.postgres.Migrate"
+
+	status, err := s.MigrationStatus(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if status.Current >= status.Latest {
+		return nil
+	}
+
+	for _, m := range migrations {
+		if m.version <= status.Current {
+			continue
+		}
+
+		err := s.db.BeginFunc(ctx, func(tx pgx.Tx) error {
+			current, err := lockMigrations(ctx, tx)
+			if err != nil {
+				return err
+			}
+			if m.version <= current {
+				return nil
+			}
+
+			if _, err := tx.Exec(ctx, m.stmt); err != nil {
+				return fmt.Errorf("failed to apply migration %d (%s): %w", m.version, m.name, err)
+			}
+			if _, err := tx.Exec(ctx, "INSERT INTO schema_migrations (version, name) VALUES ($1, $2)", m.version, m.name); err != nil {
+				return fmt.Errorf("failed to apply migration %d (%s): %w", m.version, m.name, err)
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	return nil
+}
+
+// lockMigrations берет блокировку миграций до конца транзакции и возвращает версию схемы под ней
+func lockMigrations(ctx context.Context, tx pgx.Tx) (int, error) {
+	if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock($1)", migrationLockKey); err != nil {
+		return 0, fmt.Errorf("failed to take migration lock: %w", err)
+	}
+
+	_, err := tx.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		);
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	var current int
+	if err := tx.QueryRow(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&current); err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return current, nil
+}
+
+// MigrationStatus возвращает текущую версию схемы в базе
+func (s *Storage) MigrationStatus(ctx context.Context) (_ storage.MigrationStatus, err error) {
+	const op = "storage.postgres.MigrationStatus"
+
+	query := "SELECT COALESCE(MAX(version), 0) FROM schema_migrations"
+
+	ctx, span := startSpan(ctx, "MigrationStatus", query)
+	defer func() { endSpan(span, err) }()
+
+	status := storage.MigrationStatus{Latest: latestVersion()}
+	err = s.db.QueryRow(ctx, query).Scan(&status.Current)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == undefinedTable {
+		// миграции еще ни разу не запускались
+		return status, nil
+	}
+	if err != nil {
+		return status, fmt.Errorf("%s: failed to read schema version: %w", op, err)
+	}
+
+	return status, nil
+}
@@ -0,0 +1,69 @@
+package postgres
+
+import (
+	"RestApi_v1/internal/config/internal/storage"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/jackc/pgx/v4"
+)
+
+// Каналы, в которые пишут триггеры на songs
+const (
+	songsChannel      = "songs_changed"
+	songEventsChannel = "song_events"
+)
+
+// listen подписывается на канал channel и передает payload уведомлений в fn, пока не отменен ctx.
+// ready вызывается, когда подписка установлена: уведомления до этого момента потеряны.
+// Ошибка соединения возвращается, переподключаться должен вызывающий.
+func (s *Storage) listen(ctx context.Context, channel string, ready func(), fn func(payload string)) error {
+	const op = "storage.postgres.listen"
+
+	conn, err := s.db.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	// соединение с подпиской в пул не возвращаем
+	pgConn := conn.Hijack()
+	defer pgConn.Close(context.Background())
+
+	if _, err := pgConn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	ready()
+
+	for {
+		n, err := pgConn.WaitForNotification(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		fn(n.Payload)
+	}
+}
+
+// ListenSongChanges подписывается на изменения песен для сброса кеша
+func (s *Storage) ListenSongChanges(ctx context.Context, ready func(), fn func(storage.SongChange)) error {
+	return s.listen(ctx, songsChannel, ready, func(payload string) {
+		var change storage.SongChange
+		if err := json.Unmarshal([]byte(payload), &change); err != nil {
+			return
+		}
+		fn(change)
+	})
+}
+
+// ListenSongEvents будит fn при записи нового события в журнал song_events, передает его id
+func (s *Storage) ListenSongEvents(ctx context.Context, ready func(), fn func(id int64)) error {
+	return s.listen(ctx, songEventsChannel, ready, func(payload string) {
+		var id int64
+		if _, err := fmt.Sscan(payload, &id); err != nil {
+			return
+		}
+		fn(id)
+	})
+}
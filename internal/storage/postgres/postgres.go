@@ -6,105 +6,169 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"github.com/jackc/pgconn"
 	"github.com/jackc/pgx/v4"
-	"log/slog"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "RestApi_v1/storage/postgres"
+
+// коды ошибок Postgres: нарушение UNIQUE и обращение к несуществующей таблице
+const (
+	uniqueViolation = "23505"
+	undefinedTable  = "42P01"
 )
 
 type Storage struct {
-	db *pgx.Conn
+	db *pgxpool.Pool
 }
 
 // NewSongStorage создает новый экземпляр Storage
-func NewSongStorage(db *pgx.Conn) *Storage {
+func NewSongStorage(db *pgxpool.Pool) *Storage {
 	return &Storage{db: db}
 }
 
-// New создает и настраивает соединение с базой данных
-func New() (*Storage, error) {
-	const connStr = "host=localhost user=postgres password=123-123-123-123 dbname=postgres sslmode=disable"
-
-	db, err := pgx.Connect(context.Background(), connStr)
+// New создает пул соединений с базой данных и применяет миграции
+func New(ctx context.Context) (*Storage, error) {
+	s, err := Connect(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open database: %w", err)
+		return nil, err
 	}
 
-	// Создание таблицы
-	if err := createTable(db); err != nil {
+	// Создание таблиц и индексов
+	if err := s.Migrate(ctx); err != nil {
+		s.Close()
 		return nil, err
 	}
 
-	// Создание индекса
-	if err := createIndex(db); err != nil {
-		return nil, err
+	return s, nil
+}
+
+// Connect создает пул соединений без миграций, чтобы можно было посмотреть состояние схемы до их применения
+func Connect(ctx context.Context) (*Storage, error) {
+	const connStr = "host=localhost user=postgres password=123-123-123-123 dbname=postgres sslmode=disable"
+
+	db, err := pgxpool.Connect(ctx, connStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
 	return &Storage{db: db}, nil
 }
 
-func createTable(db *pgx.Conn) error {
-	stmt := `
-		CREATE TABLE IF NOT EXISTS songs (
-			id SERIAL PRIMARY KEY,
-			nameGroup VARCHAR(50),
-			song VARCHAR(100) UNIQUE,
-			text VARCHAR,
-			release_date DATE,
-			link VARCHAR(255)
-		);
-	`
+// Close закрывает все соединения пула
+func (s *Storage) Close() {
+	s.db.Close()
+}
 
-	_, err := db.Exec(context.Background(), stmt)
-	if err != nil {
-		return fmt.Errorf("failed to execute table creation statement: %w", err)
+// Ping проверяет доступность базы данных
+func (s *Storage) Ping(ctx context.Context) (err error) {
+	ctx, span := startSpan(ctx, "Ping", "SELECT 1")
+	defer func() { endSpan(span, err) }()
+
+	return s.db.Ping(ctx)
+}
+
+// PoolStats возвращает состояние пула соединений
+func (s *Storage) PoolStats() storage.PoolStats {
+	stat := s.db.Stat()
+
+	return storage.PoolStats{
+		Acquired: stat.AcquiredConns(),
+		Idle:     stat.IdleConns(),
+		Total:    stat.TotalConns(),
+		Max:      stat.MaxConns(),
 	}
-	return nil
 }
 
-func createIndex(db *pgx.Conn) error {
-	stmt := `CREATE INDEX IF NOT EXISTS idx_song ON songs(song);`
-	_, err := db.Exec(context.Background(), stmt)
-	if err != nil {
-		return fmt.Errorf("failed to execute index creation statement: %w", err)
+// startSpan открывает дочерний спан для запроса к базе, name - имя SQL-выражения
+func startSpan(ctx context.Context, name string, query string) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, "postgres."+name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBOperationName(name),
+			semconv.DBQueryText(query),
+		),
+	)
+}
+
+// endSpan закрывает спан, отмечая ошибку если она есть
+func endSpan(span trace.Span, err error) {
+	if err != nil && !errors.Is(err, storage.ErrSongNotFound) && !errors.Is(err, storage.ErrSongExist) && !errors.Is(err, storage.ErrAPIKeyNotFound) &&
+		!errors.Is(err, storage.ErrJobNotFound) && !errors.Is(err, storage.ErrJobLost) &&
+		!errors.Is(err, storage.ErrWebhookNotFound) && !errors.Is(err, storage.ErrDeliveryNotFound) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// isUniqueViolation песня с таким названием уже есть (колонка song UNIQUE)
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolation
+}
+
+// nullIfEmpty пустая строка в колонку DATE не вставляется, вместо нее пишем NULL
+func nullIfEmpty(s string) any {
+	if s == "" {
+		return nil
 	}
-	return nil
+	return s
 }
 
-// SaveSong сохраняет песню в базу данных
-func (s *Storage) SaveSong(SongToSave string, GroupToSave string, TextSongToSave string, DateToSave string, LinkToSave string) (int64, error) {
-	var id int64
+// SaveSong сохраняет песню в базу данных, в той же транзакции ставит вебхук song.created
+func (s *Storage) SaveSong(ctx context.Context, SongToSave string, GroupToSave string, TextSongToSave string, DateToSave string, LinkToSave string) (id int64, err error) {
 	query := `
 		INSERT INTO songs (song, nameGroup, text, release_date, link)
 		VALUES ($1, $2, $3, $4, $5)
 		RETURNING id;
 	`
 
-	err := s.db.QueryRow(context.Background(), query, SongToSave, GroupToSave, TextSongToSave, DateToSave, LinkToSave).Scan(&id)
+	ctx, span := startSpan(ctx, "SaveSong", query)
+	defer func() { endSpan(span, err) }()
+
+	err = s.db.BeginFunc(ctx, func(tx pgx.Tx) error {
+		if err := tx.QueryRow(ctx, query, SongToSave, GroupToSave, TextSongToSave, nullIfEmpty(DateToSave), LinkToSave).Scan(&id); err != nil {
+			return err
+		}
+		return enqueueSongWebhook(ctx, tx, models.WebhookSongCreated, id)
+	})
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return 0, storage.ErrSongNotFound
 		}
+		if isUniqueViolation(err) {
+			return 0, storage.ErrSongExist
+		}
 		return 0, fmt.Errorf("failed to save song: %w", err)
 	}
 	return id, nil
 }
 
-func (s *Storage) GetSongWithPagination(ctx context.Context, id int, page int, pageSize int) ([]models.Song, error) {
+func (s *Storage) GetSongWithPagination(ctx context.Context, id int, page int, pageSize int) (songs []models.Song, err error) {
 	offset := (page - 1) * pageSize
 
-	query := `SELECT id, song, nameGroup, text, release_date, link
+	query := `SELECT id, song, nameGroup, text, release_date, link, updated_at
 			  FROM songs WHERE id = $1 LIMIT $2 OFFSET $3`
 
+	ctx, span := startSpan(ctx, "GetSongWithPagination", query)
+	defer func() { endSpan(span, err) }()
+
 	rows, err := s.db.Query(ctx, query, id, pageSize, offset)
 	if err != nil {
 		return nil, err // Обработайте ошибку
 	}
 	defer rows.Close()
 
-	var songs []models.Song
-
 	for rows.Next() {
 		var song models.Song
-		err := rows.Scan(&song.ID, &song.Song, &song.Group, &song.Text, &song.ReleaseDate, &song.Link)
+		err := rows.Scan(&song.ID, &song.Song, &song.Group, &song.Text, &song.ReleaseDate, &song.Link, &song.UpdatedAt)
 		if err != nil {
 			return nil, err // Обработайте ошибку
 		}
@@ -119,24 +183,49 @@ func (s *Storage) GetSongWithPagination(ctx context.Context, id int, page int, p
 }
 
 // DeleteSong удаляет песню по имени TODO нужно сделать по айди
-func (s *Storage) DeleteSong(songToDelete string) (string, error) {
-	_, err := s.db.Exec(context.Background(), "DELETE FROM songs WHERE song = $1", songToDelete)
+// Вебхук song.deleted ставится в той же транзакции со снимком песни до удаления.
+func (s *Storage) DeleteSong(ctx context.Context, songToDelete string) (_ string, err error) {
+	query := "DELETE FROM songs WHERE id = $1"
+
+	ctx, span := startSpan(ctx, "DeleteSong", query)
+	defer func() { endSpan(span, err) }()
+
+	err = s.db.BeginFunc(ctx, func(tx pgx.Tx) error {
+		var id int64
+		err := tx.QueryRow(ctx, "SELECT id FROM songs WHERE song = $1 FOR UPDATE", songToDelete).Scan(&id)
+		if errors.Is(err, pgx.ErrNoRows) {
+			// удалять нечего
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := enqueueSongWebhook(ctx, tx, models.WebhookSongDeleted, id); err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, query, id)
+		return err
+	})
 	if err != nil {
 		return songToDelete, fmt.Errorf("failed to delete song: %w", err)
 	}
 	return songToDelete, nil
 }
 
-// UpdateSong обновляет информацию о песне
-func (s *Storage) UpdateSong(ID int, SongToSave string, GroupToSave string, TextSongToSave string, DateToSave string, LinkToSave string) (string, error) {
+// UpdateSong обновляет информацию о песне, в той же транзакции ставит вебхук song.updated
+func (s *Storage) UpdateSong(ctx context.Context, ID int, SongToSave string, GroupToSave string, TextSongToSave string, DateToSave string, LinkToSave string) (_ string, err error) {
 	// Проверка существования ID
+	existsQuery := "SELECT EXISTS(SELECT 1 FROM songs WHERE id=$1)"
+
+	existsCtx, existsSpan := startSpan(ctx, "SongExists", existsQuery)
 	var exists bool
-	err := s.db.QueryRow(context.Background(), "SELECT EXISTS(SELECT 1 FROM songs WHERE id=$1)", ID).Scan(&exists)
+	err = s.db.QueryRow(existsCtx, existsQuery, ID).Scan(&exists)
+	endSpan(existsSpan, err)
 	if err != nil {
 		return "", fmt.Errorf("failed to check if song exists: %w", err)
 	}
 	if !exists {
-		fmt.Errorf("Song not found in the database", slog.Int("ID", ID))
 		return "", storage.ErrSongNotFound // Возвращаем ошибку, если ID не найден
 	}
 
@@ -147,8 +236,19 @@ func (s *Storage) UpdateSong(ID int, SongToSave string, GroupToSave string, Text
 		WHERE id = $1;
 	`
 
-	_, err = s.db.Exec(context.Background(), query, ID, SongToSave, GroupToSave, TextSongToSave, DateToSave, LinkToSave)
+	ctx, span := startSpan(ctx, "UpdateSong", query)
+	defer func() { endSpan(span, err) }()
+
+	err = s.db.BeginFunc(ctx, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, query, ID, SongToSave, GroupToSave, TextSongToSave, nullIfEmpty(DateToSave), LinkToSave); err != nil {
+			return err
+		}
+		return enqueueSongWebhook(ctx, tx, models.WebhookSongUpdated, int64(ID))
+	})
 	if err != nil {
+		if isUniqueViolation(err) {
+			return "", storage.ErrSongExist
+		}
 		return "", fmt.Errorf("failed to update song: %w", err)
 	}
 	return SongToSave, nil
@@ -0,0 +1,48 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// TakeToken атомарно пополняет бакет key и пытается взять из него токен.
+// Используется как общее хранилище лимитов для нескольких реплик.
+func (s *Storage) TakeToken(ctx context.Context, key string, capacity float64, ratePerSecond float64) (allowed bool, tokens float64, err error) {
+	const op = "storage.postgres.TakeToken"
+
+	query := `
+		INSERT INTO rate_limits AS rl (key, tokens, allowed, updated_at)
+		VALUES ($1, $2::float8 - 1, true, now())
+		ON CONFLICT (key) DO UPDATE SET
+			allowed = LEAST($2::float8, rl.tokens + EXTRACT(EPOCH FROM now() - rl.updated_at) * $3::float8) >= 1,
+			tokens = LEAST($2::float8, rl.tokens + EXTRACT(EPOCH FROM now() - rl.updated_at) * $3::float8)
+				- CASE WHEN LEAST($2::float8, rl.tokens + EXTRACT(EPOCH FROM now() - rl.updated_at) * $3::float8) >= 1 THEN 1 ELSE 0 END,
+			updated_at = now()
+		RETURNING allowed, tokens;
+	`
+
+	ctx, span := startSpan(ctx, "TakeToken", query)
+	defer func() { endSpan(span, err) }()
+
+	if err = s.db.QueryRow(ctx, query, key, capacity, ratePerSecond).Scan(&allowed, &tokens); err != nil {
+		return false, 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return allowed, tokens, nil
+}
+
+// PurgeRateLimits удаляет бакеты, которые не обновлялись дольше idle
+func (s *Storage) PurgeRateLimits(ctx context.Context, idle time.Duration) (deleted int64, err error) {
+	const op = "storage.postgres.PurgeRateLimits"
+
+	query := `DELETE FROM rate_limits WHERE updated_at < now() - $1::float8 * interval '1 second'`
+
+	ctx, span := startSpan(ctx, "PurgeRateLimits", query)
+	defer func() { endSpan(span, err) }()
+
+	tag, err := s.db.Exec(ctx, query, idle.Seconds())
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,344 @@
+package postgres
+
+import (
+	"RestApi_v1/internal/config/internal/models"
+	"RestApi_v1/internal/config/internal/storage"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v4"
+	"time"
+)
+
+// enqueueSongWebhook пишет событие в outbox в транзакции изменения песни, снимок песни берется из songs
+func enqueueSongWebhook(ctx context.Context, tx pgx.Tx, eventType string, songID int64) error {
+	query := `INSERT INTO webhook_outbox (event_type, song_id, song)
+			  SELECT $1, id, to_jsonb(songs) FROM songs WHERE id = $2`
+
+	if _, err := tx.Exec(ctx, query, eventType, songID); err != nil {
+		return fmt.Errorf("failed to enqueue webhook: %w", err)
+	}
+	return nil
+}
+
+// enqueueSongWebhooks то же для пачки песен одним запросом
+func enqueueSongWebhooks(ctx context.Context, tx pgx.Tx, eventType string, songIDs []int64) error {
+	if len(songIDs) == 0 {
+		return nil
+	}
+
+	query := `INSERT INTO webhook_outbox (event_type, song_id, song)
+			  SELECT $1, id, to_jsonb(songs) FROM songs WHERE id = ANY($2) ORDER BY id`
+
+	if _, err := tx.Exec(ctx, query, eventType, songIDs); err != nil {
+		return fmt.Errorf("failed to enqueue webhooks: %w", err)
+	}
+	return nil
+}
+
+// CreateWebhook сохраняет подписку
+func (s *Storage) CreateWebhook(ctx context.Context, webhook storage.NewWebhook) (sub models.WebhookSubscription, err error) {
+	const op = "storage.postgres.CreateWebhook"
+
+	query := `INSERT INTO webhook_subscriptions (url, events, secret, created_by)
+			  VALUES ($1, $2, $3, $4)
+			  RETURNING id, url, events, secret, created_by, created_at`
+
+	ctx, span := startSpan(ctx, "CreateWebhook", query)
+	defer func() { endSpan(span, err) }()
+
+	err = s.db.QueryRow(ctx, query, webhook.URL, webhook.Events, webhook.Secret, webhook.CreatedBy).Scan(
+		&sub.ID, &sub.URL, &sub.Events, &sub.Secret, &sub.CreatedBy, &sub.CreatedAt,
+	)
+	if err != nil {
+		return sub, fmt.Errorf("%s: %w", op, err)
+	}
+	return sub, nil
+}
+
+// Webhooks возвращает все подписки по возрастанию id
+func (s *Storage) Webhooks(ctx context.Context) (subs []models.WebhookSubscription, err error) {
+	const op = "storage.postgres.Webhooks"
+
+	query := `SELECT id, url, events, secret, created_by, created_at FROM webhook_subscriptions ORDER BY id`
+
+	ctx, span := startSpan(ctx, "Webhooks", query)
+	defer func() { endSpan(span, err) }()
+
+	rows, err := s.db.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var sub models.WebhookSubscription
+		if err = rows.Scan(&sub.ID, &sub.URL, &sub.Events, &sub.Secret, &sub.CreatedBy, &sub.CreatedAt); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		subs = append(subs, sub)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return subs, nil
+}
+
+// DeleteWebhook удаляет подписку вместе с журналом ее доставок
+func (s *Storage) DeleteWebhook(ctx context.Context, id int64) (err error) {
+	const op = "storage.postgres.DeleteWebhook"
+
+	query := `DELETE FROM webhook_subscriptions WHERE id = $1`
+
+	ctx, span := startSpan(ctx, "DeleteWebhook", query)
+	defer func() { endSpan(span, err) }()
+
+	tag, err := s.db.Exec(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return storage.ErrWebhookNotFound
+	}
+	return nil
+}
+
+// WebhookDeliveries журнал доставок подписки, новые первыми. Пустой status - все статусы.
+// Для неизвестной подписки возвращает storage.ErrWebhookNotFound.
+func (s *Storage) WebhookDeliveries(ctx context.Context, subscriptionID int64, status string, limit int) (deliveries []models.WebhookDelivery, err error) {
+	const op = "storage.postgres.WebhookDeliveries"
+
+	query := `SELECT id, subscription_id, outbox_id, event_type, status, attempts, next_attempt_at,
+				last_status_code, last_error, last_attempt_at, created_at, delivered_at
+			  FROM webhook_deliveries
+			  WHERE subscription_id = $1 AND ($2 = '' OR status = $2)
+			  ORDER BY id DESC
+			  LIMIT $3`
+
+	ctx, span := startSpan(ctx, "WebhookDeliveries", query)
+	defer func() { endSpan(span, err) }()
+
+	var exists bool
+	err = s.db.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM webhook_subscriptions WHERE id = $1)", subscriptionID).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if !exists {
+		return nil, storage.ErrWebhookNotFound
+	}
+
+	rows, err := s.db.Query(ctx, query, subscriptionID, status, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var d models.WebhookDelivery
+		err = rows.Scan(&d.ID, &d.SubscriptionID, &d.OutboxID, &d.EventType, &d.Status, &d.Attempts, &d.NextAttemptAt,
+			&d.LastStatusCode, &d.LastError, &d.LastAttemptAt, &d.CreatedAt, &d.DeliveredAt)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		deliveries = append(deliveries, d)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return deliveries, nil
+}
+
+// RetryWebhookDelivery ставит доставку подписки на повтор с нуля, в том числе из dead
+func (s *Storage) RetryWebhookDelivery(ctx context.Context, subscriptionID int64, deliveryID int64) (err error) {
+	const op = "storage.postgres.RetryWebhookDelivery"
+
+	query := `
+		UPDATE webhook_deliveries SET
+			status = 'pending',
+			attempts = 0,
+			next_attempt_at = now(),
+			locked_until = NULL
+		WHERE id = $1 AND subscription_id = $2;
+	`
+
+	ctx, span := startSpan(ctx, "RetryWebhookDelivery", query)
+	defer func() { endSpan(span, err) }()
+
+	tag, err := s.db.Exec(ctx, query, deliveryID, subscriptionID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return storage.ErrDeliveryNotFound
+	}
+	return nil
+}
+
+// FanOutWebhooks разбирает до limit событий outbox: для каждой подписки на тип события создает доставку
+// с телом payload(event). События без подписчиков просто отмечаются разобранными.
+// Несколько экземпляров могут разбирать outbox одновременно, события не задваиваются.
+func (s *Storage) FanOutWebhooks(ctx context.Context, limit int, payload func(models.WebhookEvent) ([]byte, error)) (n int, err error) {
+	const op = "storage.postgres.FanOutWebhooks"
+
+	query := `SELECT id, event_type, song_id, song, created_at FROM webhook_outbox
+			  WHERE dispatched_at IS NULL
+			  ORDER BY id
+			  LIMIT $1
+			  FOR UPDATE SKIP LOCKED`
+
+	ctx, span := startSpan(ctx, "FanOutWebhooks", query)
+	defer func() { endSpan(span, err) }()
+
+	err = s.db.BeginFunc(ctx, func(tx pgx.Tx) error {
+		events, err := outboxEvents(ctx, tx, query, limit)
+		if err != nil {
+			return err
+		}
+		n = len(events)
+
+		for _, event := range events {
+			body, err := payload(event)
+			if err != nil {
+				return fmt.Errorf("event %d: %w", event.ID, err)
+			}
+
+			_, err = tx.Exec(ctx, `
+				INSERT INTO webhook_deliveries (subscription_id, outbox_id, event_type, payload)
+				SELECT id, $1, $2, $3 FROM webhook_subscriptions WHERE $2 = ANY(events)
+				ON CONFLICT (subscription_id, outbox_id) DO NOTHING`,
+				event.ID, event.Type, body,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to create deliveries: %w", err)
+			}
+
+			if _, err := tx.Exec(ctx, `UPDATE webhook_outbox SET dispatched_at = now() WHERE id = $1`, event.ID); err != nil {
+				return fmt.Errorf("failed to mark event dispatched: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return n, nil
+}
+
+func outboxEvents(ctx context.Context, tx pgx.Tx, query string, limit int) ([]models.WebhookEvent, error) {
+	rows, err := tx.Query(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []models.WebhookEvent
+	for rows.Next() {
+		var (
+			event    models.WebhookEvent
+			snapshot []byte
+		)
+		if err := rows.Scan(&event.ID, &event.Type, &event.SongID, &snapshot, &event.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		var row songSnapshot
+		if err := json.Unmarshal(snapshot, &row); err != nil {
+			return nil, fmt.Errorf("event %d: %w", event.ID, err)
+		}
+		event.Song = row.song()
+
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// ClaimWebhookDelivery забирает доставку, время которой пришло, и берет ее в аренду на lease.
+// Как и у задач, номер попытки служит токеном владельца. Пустая очередь - storage.ErrDeliveryNotFound.
+func (s *Storage) ClaimWebhookDelivery(ctx context.Context, lease time.Duration) (d models.WebhookDelivery, err error) {
+	const op = "storage.postgres.ClaimWebhookDelivery"
+
+	query := `
+		WITH claimed AS (
+			UPDATE webhook_deliveries SET
+				attempts = attempts + 1,
+				locked_until = now() + $1 * interval '1 second'
+			WHERE id = (
+				SELECT id FROM webhook_deliveries
+				WHERE status = 'pending' AND next_attempt_at <= now()
+				  AND (locked_until IS NULL OR locked_until < now())
+				ORDER BY next_attempt_at, id
+				FOR UPDATE SKIP LOCKED
+				LIMIT 1
+			)
+			RETURNING id, subscription_id, outbox_id, event_type, payload, status, attempts, created_at
+		)
+		SELECT c.id, c.subscription_id, c.outbox_id, c.event_type, c.payload, c.status, c.attempts, c.created_at, s.url, s.secret
+		FROM claimed c JOIN webhook_subscriptions s ON s.id = c.subscription_id;
+	`
+
+	ctx, span := startSpan(ctx, "ClaimWebhookDelivery", query)
+	defer func() { endSpan(span, err) }()
+
+	err = s.db.QueryRow(ctx, query, lease.Seconds()).Scan(
+		&d.ID, &d.SubscriptionID, &d.OutboxID, &d.EventType, &d.Payload, &d.Status, &d.Attempts, &d.CreatedAt, &d.URL, &d.Secret,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return d, storage.ErrDeliveryNotFound
+	}
+	if err != nil {
+		return d, fmt.Errorf("%s: %w", op, err)
+	}
+	return d, nil
+}
+
+// FinishWebhookAttempt записывает итог попытки и снимает аренду
+func (s *Storage) FinishWebhookAttempt(ctx context.Context, id int64, attempt int, result storage.DeliveryAttempt) (err error) {
+	const op = "storage.postgres.FinishWebhookAttempt"
+
+	query := `
+		UPDATE webhook_deliveries SET
+			status = CASE WHEN $3 THEN 'delivered' WHEN $6::timestamptz IS NULL THEN 'dead' ELSE 'pending' END,
+			next_attempt_at = coalesce($6::timestamptz, next_attempt_at),
+			delivered_at = CASE WHEN $3 THEN now() END,
+			last_status_code = $4,
+			last_error = $5,
+			last_attempt_at = now(),
+			locked_until = NULL
+		WHERE id = $1 AND attempts = $2 AND status = 'pending';
+	`
+
+	ctx, span := startSpan(ctx, "FinishWebhookAttempt", query)
+	defer func() { endSpan(span, err) }()
+
+	tag, err := s.db.Exec(ctx, query, id, attempt, result.Delivered, result.StatusCode, result.Error, result.RetryAt)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return storage.ErrDeliveryNotFound
+	}
+	return nil
+}
+
+// PurgeWebhooks удаляет разобранные события outbox и завершенные доставки старше before
+func (s *Storage) PurgeWebhooks(ctx context.Context, before time.Time) (deleted int64, err error) {
+	const op = "storage.postgres.PurgeWebhooks"
+
+	query := `
+		WITH outbox AS (
+			DELETE FROM webhook_outbox WHERE dispatched_at < $1 RETURNING 1
+		), deliveries AS (
+			DELETE FROM webhook_deliveries WHERE status <> 'pending' AND created_at < $1 RETURNING 1
+		)
+		SELECT (SELECT count(*) FROM outbox) + (SELECT count(*) FROM deliveries);
+	`
+
+	ctx, span := startSpan(ctx, "PurgeWebhooks", query)
+	defer func() { endSpan(span, err) }()
+
+	if err = s.db.QueryRow(ctx, query, before).Scan(&deleted); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return deleted, nil
+}
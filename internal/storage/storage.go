@@ -1,9 +1,147 @@
 package storage
 
-import "errors"
+import (
+	"errors"
+	"io"
+	"time"
+)
 
 var (
 	ErrSongNotFound  = errors.New("song not found")
 	ErrSongExist     = errors.New("song exist")
 	ErrGroupNotFound = errors.New("group not found")
+
+	ErrAPIKeyNotFound = errors.New("api key not found")
+
+	ErrJobNotFound = errors.New("job not found")
+	// ErrJobLost задачу забрал другой воркер, пока у этого истекла аренда
+	ErrJobLost = errors.New("job lease lost")
+
+	ErrWebhookNotFound = errors.New("webhook not found")
+	// ErrDeliveryNotFound доставки нет или ее аренду забрал другой воркер
+	ErrDeliveryNotFound = errors.New("webhook delivery not found")
 )
+
+// MigrationStatus версия схемы в базе и последняя известная приложению
+type MigrationStatus struct {
+	Current int `json:"current"`
+	Latest  int `json:"latest"`
+}
+
+// UpToDate сообщает, применены ли все миграции
+func (m MigrationStatus) UpToDate() bool {
+	return m.Current >= m.Latest
+}
+
+// PoolStats состояние пула соединений с базой
+type PoolStats struct {
+	Acquired int32 `json:"acquired"`
+	Idle     int32 `json:"idle"`
+	Total    int32 `json:"total"`
+	Max      int32 `json:"max"`
+}
+
+// Saturation доля занятых соединений от максимума пула
+func (p PoolStats) Saturation() float64 {
+	if p.Max == 0 {
+		return 0
+	}
+	return float64(p.Acquired) / float64(p.Max)
+}
+
+// SongFilter условия выборки песен, пустые поля не фильтруют
+type SongFilter struct {
+	Group string
+	// Song подстрока названия без учета регистра
+	Song string
+	// Query подстрока названия, группы или текста без учета регистра
+	Query        string
+	ReleasedFrom *time.Time
+	ReleasedTo   *time.Time
+	// AfterID только песни с id больше указанного, для продолжения выгрузки
+	AfterID int64
+}
+
+// ImportSong строка пакетной загрузки, Row - ее номер во входных данных
+type ImportSong struct {
+	Row   int
+	Song  string
+	Group string
+	Text  string
+	Date  string
+	Link  string
+}
+
+// ImportResult итог по строке: ID новой песни или Created=false, если такая уже есть
+type ImportResult struct {
+	Row     int
+	ID      int64
+	Created bool
+}
+
+// NewJob задача для постановки в очередь
+type NewJob struct {
+	Kind        string
+	Params      []byte // JSON
+	PayloadType string
+	// Payload входной файл, читается до конца при постановке в очередь, nil - без файла
+	Payload     io.Reader
+	MaxAttempts int
+	CreatedBy   string
+}
+
+// JobProgress промежуточное состояние задачи. Result дописывается в конец уже сохраненного результата.
+type JobProgress struct {
+	Processed  int
+	Total      int
+	Checkpoint []byte // JSON
+	Result     []byte
+	ResultType string
+}
+
+// SongDetails данные для дозаполнения песни, пустые поля не меняют песню
+type SongDetails struct {
+	Text string
+	Date string // 2006-01-02
+	Link string
+}
+
+// EnrichmentOutcome итог проверки песни во внешнем сервисе
+type EnrichmentOutcome struct {
+	Status string
+	// Filled заполненные поля через запятую: text,release_date,link
+	Filled string
+	Error  string
+}
+
+// IdempotencyRecord запрос с ключом идемпотентности и, когда он выполнен, его ответ
+type IdempotencyRecord struct {
+	RequestHash string
+	Completed   bool
+	Status      int
+	Header      map[string][]string
+	Body        []byte
+}
+
+// SongChange уведомление об изменении песни из канала songs_changed
+type SongChange struct {
+	Op   string `json:"op"` // INSERT, UPDATE или DELETE
+	ID   int64  `json:"id"`
+	Song string `json:"song"`
+}
+
+// NewWebhook подписка на события песен
+type NewWebhook struct {
+	URL       string
+	Events    []string
+	Secret    string
+	CreatedBy string
+}
+
+// DeliveryAttempt итог попытки доставки. Если Delivered false, RetryAt nil переводит доставку в dead.
+type DeliveryAttempt struct {
+	StatusCode int
+	Error      string
+	Delivered  bool
+	RetryAt    *time.Time
+}